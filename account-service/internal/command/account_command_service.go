@@ -4,119 +4,192 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 
 	"github.com/eaglebank/account-service/internal/repository"
 	"github.com/eaglebank/shared/cqrs"
 	"github.com/eaglebank/shared/events"
+	"github.com/eaglebank/shared/ledger"
+	"github.com/eaglebank/shared/middleware"
 	"github.com/eaglebank/shared/models"
+	"github.com/eaglebank/shared/money"
+	sharedredis "github.com/eaglebank/shared/redis"
 	"github.com/eaglebank/shared/utils"
 )
 
 // AccountCommandService writes account state and keeps the read model in sync.
+// Domain events are queued in the outbox within the same SQL transaction as
+// the write and published by the outbox relay. Every balance change is also
+// settled into the shared funds ledger that transaction-service reserves
+// against, releasing the hold placed by the originating debit.
 type AccountCommandService struct {
 	writeRepo *repository.AccountWriteRepository
 	readRepo  *repository.AccountReadRepository
-	publisher *events.Publisher
+	funds     *sharedredis.Funds
 }
 
 func NewAccountCommandService(
 	writeRepo *repository.AccountWriteRepository,
 	readRepo *repository.AccountReadRepository,
-	publisher *events.Publisher,
+	funds *sharedredis.Funds,
 ) *AccountCommandService {
 	return &AccountCommandService{
 		writeRepo: writeRepo,
 		readRepo:  readRepo,
-		publisher: publisher,
+		funds:     funds,
 	}
 }
 
-func (s *AccountCommandService) CreateAccount(cmd cqrs.CreateAccountCommand) (*models.Account, error) {
+// CreateAccount opens an account for a user who has verified their email
+// address, returning "user not verified" otherwise.
+func (s *AccountCommandService) CreateAccount(ctx context.Context, cmd cqrs.CreateAccountCommand) (*models.Account, error) {
+	verified, err := s.writeRepo.IsUserVerified(cmd.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !verified {
+		return nil, fmt.Errorf("user not verified")
+	}
 	account := &models.Account{
 		AccountNumber: utils.GenerateAccountNumber(),
 		UserID:        cmd.UserID,
 		SortCode:      "10-10-10",
 		Name:          cmd.Name,
 		AccountType:   cmd.AccountType,
-		Balance:       0.00,
+		Balance:       0,
 		Currency:      "GBP",
+		Status:        models.AccountStatusActive,
 		CreatedAt:     time.Now().UTC(),
 		UpdatedAt:     time.Now().UTC(),
 	}
-	if err := s.writeRepo.Create(account); err != nil {
-		return nil, err
-	}
-	ctx := context.Background()
-	s.readRepo.CacheAccountView(ctx, accountToView(account))
-	if err := s.publisher.Publish(ctx, events.AccountEventsStream, events.AccountCreated, events.AccountCreatedEvent{
+	created := events.NewOutboxEvent(ctx, events.AccountEventsStream, events.AccountCreated, events.AccountCreatedEvent{
 		AccountNumber: account.AccountNumber,
 		UserID:        account.UserID,
 		Name:          account.Name,
 		AccountType:   account.AccountType,
-	}); err != nil {
-		log.Printf("Failed to publish account.created event: %v", err)
+	})
+	if err := s.writeRepo.Create(account, created); err != nil {
+		return nil, err
+	}
+	s.readRepo.CacheAccountView(ctx, accountToView(account))
+	if err := s.funds.Settle(ctx, account.AccountNumber, account.Balance, ""); err != nil {
+		slog.ErrorContext(ctx, "Failed to seed funds", "account_number", account.AccountNumber, "err", err)
 	}
 	return account, nil
 }
 
-func (s *AccountCommandService) UpdateAccount(cmd cqrs.UpdateAccountCommand) (*models.AccountView, error) {
-	account, err := s.writeRepo.GetByAccountNumber(cmd.AccountNumber)
+func (s *AccountCommandService) UpdateAccount(ctx context.Context, cmd cqrs.UpdateAccountCommand) (*models.AccountView, error) {
+	account, err := s.writeRepo.GetByAccountNumber(ctx, cmd.AccountNumber)
 	if err != nil {
 		return nil, err
 	}
-	if account.UserID != cmd.RequestingUserID {
+	if !cmd.Principal.CanAccess(account.UserID, middleware.ScopeAccountsWrite) {
 		return nil, fmt.Errorf("forbidden")
 	}
 	account.Name = cmd.Name
 	account.AccountType = cmd.AccountType
 	account.UpdatedAt = time.Now().UTC()
-	if err := s.writeRepo.Update(account); err != nil {
+	updatedEvent := events.NewOutboxEvent(ctx, events.AccountEventsStream, events.AccountUpdated, events.AccountUpdatedEvent{
+		AccountNumber: account.AccountNumber,
+		UserID:        account.UserID,
+		Name:          account.Name,
+	})
+	if err := s.writeRepo.Update(account, updatedEvent); err != nil {
 		return nil, err
 	}
-	updated, err := s.writeRepo.GetByAccountNumber(cmd.AccountNumber)
+	updated, err := s.writeRepo.GetByAccountNumber(ctx, cmd.AccountNumber)
 	if err != nil {
 		return nil, err
 	}
 	view := accountToView(updated)
-	s.readRepo.CacheAccountView(context.Background(), view)
-	if err := s.publisher.Publish(context.Background(), events.AccountEventsStream, events.AccountUpdated, events.AccountUpdatedEvent{
-		AccountNumber: account.AccountNumber,
-		UserID:        account.UserID,
-		Name:          account.Name,
-	}); err != nil {
-		log.Printf("Failed to publish account.updated event: %v", err)
-	}
+	s.readRepo.CacheAccountView(ctx, view)
 	return view, nil
 }
 
-func (s *AccountCommandService) DeleteAccount(cmd cqrs.DeleteAccountCommand) error {
-	account, err := s.writeRepo.GetByAccountNumber(cmd.AccountNumber)
+func (s *AccountCommandService) DeleteAccount(ctx context.Context, cmd cqrs.DeleteAccountCommand) error {
+	account, err := s.writeRepo.GetByAccountNumber(ctx, cmd.AccountNumber)
 	if err != nil {
 		return err
 	}
-	if account.UserID != cmd.RequestingUserID {
+	if !cmd.Principal.CanAccess(account.UserID, middleware.ScopeAccountsWrite) {
 		return fmt.Errorf("forbidden")
 	}
-	if err := s.writeRepo.Delete(cmd.AccountNumber); err != nil {
-		return err
+	if account.Status == models.AccountStatusFrozen {
+		return fmt.Errorf("account frozen")
 	}
-	s.readRepo.InvalidateAccountView(context.Background(), cmd.AccountNumber)
-	if err := s.publisher.Publish(context.Background(), events.AccountEventsStream, events.AccountDeleted, events.AccountDeletedEvent{
+	deleted := events.NewOutboxEvent(ctx, events.AccountEventsStream, events.AccountDeleted, events.AccountDeletedEvent{
 		AccountNumber: account.AccountNumber,
 		UserID:        account.UserID,
-	}); err != nil {
-		log.Printf("Failed to publish account.deleted event: %v", err)
+	})
+	if err := s.writeRepo.Delete(cmd.AccountNumber, deleted); err != nil {
+		return err
+	}
+	s.readRepo.InvalidateAccountView(ctx, cmd.AccountNumber)
+	if err := s.funds.Forget(ctx, cmd.AccountNumber); err != nil {
+		slog.ErrorContext(ctx, "Failed to clear funds", "account_number", cmd.AccountNumber, "err", err)
 	}
 	return nil
 }
 
-// HandleTransactionEvent reacts to transaction.created events by updating the
-// account balance. Idempotent: duplicate delivery of the same transaction ID
-// is detected via Redis and skipped without modifying the balance.
+// FreezeAccount stops the account accepting transactions and transfers.
+// Transfers already in flight still settle. Returns "account already frozen"
+// if it was.
+func (s *AccountCommandService) FreezeAccount(ctx context.Context, cmd cqrs.FreezeAccountCommand) (*models.AccountView, error) {
+	return s.setStatus(ctx, cmd.AccountNumber, models.AccountStatusFrozen, events.AccountFrozen, cmd.ActorID, cmd.Reason)
+}
+
+// UnfreezeAccount lifts a freeze. Returns "account not frozen" if there was none.
+func (s *AccountCommandService) UnfreezeAccount(ctx context.Context, cmd cqrs.UnfreezeAccountCommand) (*models.AccountView, error) {
+	return s.setStatus(ctx, cmd.AccountNumber, models.AccountStatusActive, events.AccountUnfrozen, cmd.ActorID, cmd.Reason)
+}
+
+func (s *AccountCommandService) setStatus(ctx context.Context, accountNumber, status, eventType, actorID, reason string) (*models.AccountView, error) {
+	account, err := s.writeRepo.GetByAccountNumber(ctx, accountNumber)
+	if err != nil {
+		return nil, err
+	}
+	event := events.NewOutboxEvent(ctx, events.AccountEventsStream, eventType, events.AccountFreezeEvent{
+		AccountNumber: account.AccountNumber,
+		UserID:        account.UserID,
+		ActorID:       actorID,
+		Reason:        reason,
+	})
+	changed, err := s.writeRepo.SetStatus(accountNumber, status, event)
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		if status == models.AccountStatusFrozen {
+			return nil, fmt.Errorf("account already frozen")
+		}
+		return nil, fmt.Errorf("account not frozen")
+	}
+	updated, err := s.writeRepo.GetByAccountNumber(ctx, accountNumber)
+	if err != nil {
+		return nil, err
+	}
+	view := accountToView(updated)
+	s.readRepo.CacheAccountView(ctx, view)
+	slog.InfoContext(ctx, "Account status changed", "account_number", accountNumber, "status", status, "actor_id", actorID, "reason", reason)
+	return view, nil
+}
+
+// HandleTransactionEvent reacts to transaction.created events by posting the
+// transaction's journal entry to the ledger, from which the account balance is
+// derived. Idempotent: duplicate delivery of the same transaction ID
+// is detected via Redis as a fast path, and authoritatively by the
+// processed_transactions row written in the same SQL transaction as the balance.
+//
+// The outcome is reported as transaction.posted or transaction.rejected in
+// that same SQL transaction, and transfer legs also report it on
+// transfer.events. A transaction that can never be applied (insufficient
+// funds, closed account) is rejected rather than retried, so the client is
+// told and the transfer saga can compensate instead of leaving money
+// half-moved. The debit's hold is settled or released on every one of these
+// paths; it only stays while the event is being retried.
 func (s *AccountCommandService) HandleTransactionEvent(ctx context.Context, event events.Event) error {
-	log.Printf("Received transaction event: %s", event.Type)
+	slog.InfoContext(ctx, "Received transaction event")
 	if event.Type != events.TransactionCreated {
 		return nil
 	}
@@ -126,38 +199,140 @@ func (s *AccountCommandService) HandleTransactionEvent(ctx context.Context, even
 		return fmt.Errorf("failed to unmarshal transaction.created event: %w", err)
 	}
 	if s.readRepo.IsTransactionProcessed(ctx, data.TransactionID) {
-		log.Printf("Transaction %s already processed, skipping duplicate event", data.TransactionID)
+		slog.InfoContext(ctx, "Transaction already processed, skipping duplicate event", "transaction_id", data.TransactionID)
+		s.releaseHold(ctx, data)
 		return nil
 	}
-	account, err := s.writeRepo.GetByAccountNumber(data.AccountNumber)
+	account, err := s.writeRepo.GetByAccountNumber(ctx, data.AccountNumber)
 	if err != nil {
+		if err.Error() == "account not found" {
+			return s.rejectTransaction(ctx, data, "account not found")
+		}
 		return fmt.Errorf("failed to get account for balance update: %w", err)
 	}
-	var newBalance float64
-	if data.Type == "deposit" {
-		newBalance = account.Balance + data.Amount
-	} else {
-		newBalance = account.Balance - data.Amount
+	entry, err := ledger.ForTransaction(data.TransactionID, data.Type, data.AccountNumber, data.Currency, data.Amount, event.Timestamp)
+	if err != nil {
+		return s.rejectTransaction(ctx, data, err.Error())
+	}
+	change := money.New(entry.Change(data.AccountNumber), data.Currency)
+	updated, err := money.New(account.Balance, account.Currency).Add(change)
+	if err != nil {
+		return s.rejectTransaction(ctx, data, err.Error())
+	}
+	if updated.Amount.IsNegative() {
+		return s.rejectTransaction(ctx, data, "insufficient funds")
+	}
+	newBalance := updated.Amount
+	outbox := []events.OutboxEvent{
+		events.NewOutboxEvent(ctx, events.AccountEventsStream, events.BalanceUpdated, events.BalanceUpdatedEvent{
+			AccountNumber: data.AccountNumber,
+			NewBalance:    newBalance,
+			Change:        change.Amount,
+		}),
+		events.NewOutboxEvent(ctx, events.AccountEventsStream, events.TransactionPosted, transactionOutcome(data, "")),
 	}
-	if err := s.writeRepo.UpdateBalance(data.AccountNumber, newBalance); err != nil {
+	if data.TransferID != "" {
+		outbox = append(outbox, events.NewOutboxEvent(ctx, events.TransferEventsStream, events.TransferLegApplied, transferLeg(data, "")))
+	}
+	applied, err := s.writeRepo.PostJournalEntry(ctx, entry, data.AccountNumber, newBalance, outbox...)
+	if err != nil {
 		return fmt.Errorf("failed to update balance: %w", err)
 	}
+	if !applied {
+		slog.InfoContext(ctx, "Transaction already applied, skipping duplicate event", "transaction_id", data.TransactionID)
+		s.readRepo.MarkTransactionProcessed(ctx, data.TransactionID)
+		s.releaseHold(ctx, data)
+		return nil
+	}
 	// Record the transaction ID before updating the cache, so that any
 	// redelivery after this point is detected and skipped.
 	s.readRepo.MarkTransactionProcessed(ctx, data.TransactionID)
+	slog.InfoContext(ctx, "Balance updated", "account_number", data.AccountNumber, "transaction_id", data.TransactionID)
 	account.Balance = newBalance
 	s.readRepo.CacheAccountView(ctx, accountToView(account))
-	if err := s.publisher.Publish(ctx, events.AccountEventsStream, events.BalanceUpdated, events.BalanceUpdatedEvent{
-		AccountNumber: data.AccountNumber,
-		NewBalance:    newBalance,
-		Change:        data.Amount,
-	}); err != nil {
-		log.Printf("Failed to publish balance.updated event: %v", err)
+	if err := s.funds.Settle(ctx, data.AccountNumber, newBalance, data.TransactionID); err != nil {
+		slog.ErrorContext(ctx, "Failed to settle funds", "transaction_id", data.TransactionID, "err", err)
+	}
+	return nil
+}
+
+// HandleUserEvent is the user.events subscriber handler. It records
+// verified users so CreateAccount can admit them, and forgets them when they
+// change their email address until they verify the new one.
+func (s *AccountCommandService) HandleUserEvent(ctx context.Context, event events.Event) error {
+	switch event.Type {
+	case events.UserVerified:
+		dataBytes, _ := json.Marshal(event.Data)
+		var data events.UserVerifiedEvent
+		if err := json.Unmarshal(dataBytes, &data); err != nil {
+			return fmt.Errorf("failed to unmarshal user.verified event: %w", err)
+		}
+		if err := s.writeRepo.MarkUserVerified(data.UserID, event.Timestamp); err != nil {
+			return err
+		}
+		slog.InfoContext(ctx, "User verified; accounts may now be opened", "user_id", data.UserID)
+	case events.UserEmailChanged:
+		dataBytes, _ := json.Marshal(event.Data)
+		var data events.UserEmailChangedEvent
+		if err := json.Unmarshal(dataBytes, &data); err != nil {
+			return fmt.Errorf("failed to unmarshal user.email_changed event: %w", err)
+		}
+		if err := s.writeRepo.MarkUserUnverified(data.UserID); err != nil {
+			return err
+		}
+		slog.InfoContext(ctx, "User changed email; accounts blocked until verified", "user_id", data.UserID)
 	}
-	log.Printf("Balance updated for account %s: %.2f -> %.2f", data.AccountNumber, account.Balance, newBalance)
 	return nil
 }
 
+// releaseHold drops any hold transaction-service placed for the transaction.
+// Failures are just logged: the transaction is settled either way, and the
+// hold can be released by hand.
+func (s *AccountCommandService) releaseHold(ctx context.Context, data events.TransactionCreatedEvent) {
+	if err := s.funds.Release(ctx, data.AccountNumber, data.TransactionID); err != nil {
+		slog.ErrorContext(ctx, "Failed to release hold", "transaction_id", data.TransactionID, "err", err)
+	}
+}
+
+// rejectTransaction marks a transaction processed without moving money and
+// reports it as rejected, so transaction-service can record the outcome and,
+// for a transfer leg, fail or compensate the transfer.
+func (s *AccountCommandService) rejectTransaction(ctx context.Context, data events.TransactionCreatedEvent, reason string) error {
+	outbox := []events.OutboxEvent{
+		events.NewOutboxEvent(ctx, events.AccountEventsStream, events.TransactionRejected, transactionOutcome(data, reason)),
+	}
+	if data.TransferID != "" {
+		outbox = append(outbox, events.NewOutboxEvent(ctx, events.TransferEventsStream, events.TransferLegFailed, transferLeg(data, reason)))
+	}
+	if _, err := s.writeRepo.RejectTransaction(ctx, data.TransactionID, data.AccountNumber, outbox...); err != nil {
+		return fmt.Errorf("failed to reject transaction: %w", err)
+	}
+	s.readRepo.MarkTransactionProcessed(ctx, data.TransactionID)
+	s.releaseHold(ctx, data)
+	slog.WarnContext(ctx, "Rejected transaction", "type", data.Type, "transaction_id", data.TransactionID, "transfer_id", data.TransferID, "reason", reason)
+	return nil
+}
+
+func transactionOutcome(data events.TransactionCreatedEvent, reason string) events.TransactionOutcomeEvent {
+	return events.TransactionOutcomeEvent{
+		TransactionID: data.TransactionID,
+		AccountNumber: data.AccountNumber,
+		Type:          data.Type,
+		TransferID:    data.TransferID,
+		Reason:        reason,
+	}
+}
+
+func transferLeg(data events.TransactionCreatedEvent, reason string) events.TransferLegEvent {
+	return events.TransferLegEvent{
+		TransferID:    data.TransferID,
+		TransactionID: data.TransactionID,
+		AccountNumber: data.AccountNumber,
+		Type:          data.Type,
+		Reason:        reason,
+	}
+}
+
 // accountToView converts the PostgreSQL write model to the Redis read view model.
 func accountToView(a *models.Account) *models.AccountView {
 	return &models.AccountView{
@@ -168,6 +343,7 @@ func accountToView(a *models.Account) *models.AccountView {
 		AccountType:   a.AccountType,
 		Balance:       a.Balance,
 		Currency:      a.Currency,
+		Status:        a.Status,
 		CreatedAt:     a.CreatedAt,
 		UpdatedAt:     a.UpdatedAt,
 	}
@@ -1,8 +1,11 @@
 package handler
 
 import (
+	"context"
 	"net/http"
+	"time"
 
+	"github.com/eaglebank/shared/admin"
 	"github.com/eaglebank/shared/cqrs"
 	"github.com/eaglebank/shared/middleware"
 	"github.com/eaglebank/shared/models"
@@ -11,15 +14,18 @@ import (
 
 // AccountCommander defines the write-side operations used by AccountHandler.
 type AccountCommander interface {
-	CreateAccount(cqrs.CreateAccountCommand) (*models.Account, error)
-	UpdateAccount(cqrs.UpdateAccountCommand) (*models.AccountView, error)
-	DeleteAccount(cqrs.DeleteAccountCommand) error
+	CreateAccount(context.Context, cqrs.CreateAccountCommand) (*models.Account, error)
+	UpdateAccount(context.Context, cqrs.UpdateAccountCommand) (*models.AccountView, error)
+	DeleteAccount(context.Context, cqrs.DeleteAccountCommand) error
+	FreezeAccount(context.Context, cqrs.FreezeAccountCommand) (*models.AccountView, error)
+	UnfreezeAccount(context.Context, cqrs.UnfreezeAccountCommand) (*models.AccountView, error)
 }
 
 // AccountQuerier defines the read-side operations used by AccountHandler.
 type AccountQuerier interface {
-	GetAccount(cqrs.GetAccountQuery) (*models.AccountView, error)
-	ListAccounts(cqrs.ListAccountsQuery) ([]models.AccountView, error)
+	GetAccount(context.Context, cqrs.GetAccountQuery) (*models.AccountView, error)
+	ListAccounts(context.Context, cqrs.ListAccountsQuery) ([]models.AccountView, error)
+	GetBalance(context.Context, cqrs.GetBalanceQuery) (*models.BalanceView, error)
 }
 
 // AccountHandler handles account-related HTTP requests.
@@ -38,6 +44,12 @@ type UpdateAccountRequest struct {
 	AccountType string `json:"accountType" validate:"omitempty,oneof=personal"`
 }
 
+// AccountStatusRequest gives the reason staff record for freezing or
+// unfreezing an account.
+type AccountStatusRequest struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
 type ListAccountsResponse struct {
 	Accounts []any `json:"accounts"`
 }
@@ -59,13 +71,17 @@ func (h *AccountHandler) CreateAccount(c *gin.Context) {
 		return
 	}
 
-	account, err := h.commands.CreateAccount(cqrs.CreateAccountCommand{
+	account, err := h.commands.CreateAccount(c.Request.Context(), cqrs.CreateAccountCommand{
 		UserID:      userID,
 		Name:        req.Name,
 		AccountType: req.AccountType,
 	})
 	if err != nil {
-		middleware.RespondWithError(c, http.StatusInternalServerError, "Failed to create account")
+		if err.Error() == "user not verified" {
+			middleware.RespondWithError(c, http.StatusForbidden, "Verify your email address before opening an account")
+		} else {
+			middleware.RespondWithError(c, http.StatusInternalServerError, "Failed to create account")
+		}
 		return
 	}
 
@@ -74,8 +90,16 @@ func (h *AccountHandler) CreateAccount(c *gin.Context) {
 
 func (h *AccountHandler) ListAccounts(c *gin.Context) {
 	userID, _ := middleware.GetUserID(c)
+	h.listAccounts(c, userID)
+}
 
-	views, err := h.queries.ListAccounts(cqrs.ListAccountsQuery{UserID: userID})
+// ListUserAccounts is the back-office listing of any user's accounts.
+func (h *AccountHandler) ListUserAccounts(c *gin.Context) {
+	h.listAccounts(c, c.Param("userId"))
+}
+
+func (h *AccountHandler) listAccounts(c *gin.Context, userID string) {
+	views, err := h.queries.ListAccounts(c.Request.Context(), cqrs.ListAccountsQuery{UserID: userID})
 	if err != nil {
 		middleware.RespondWithError(c, http.StatusInternalServerError, "Failed to list accounts")
 		return
@@ -90,11 +114,10 @@ func (h *AccountHandler) ListAccounts(c *gin.Context) {
 
 func (h *AccountHandler) GetAccount(c *gin.Context) {
 	accountNumber := c.Param("accountNumber")
-	userID, _ := middleware.GetUserID(c)
 
-	view, err := h.queries.GetAccount(cqrs.GetAccountQuery{
-		AccountNumber:    accountNumber,
-		RequestingUserID: userID,
+	view, err := h.queries.GetAccount(c.Request.Context(), cqrs.GetAccountQuery{
+		AccountNumber: accountNumber,
+		Principal:     middleware.GetPrincipal(c),
 	})
 	if err != nil {
 		if err.Error() == "forbidden" {
@@ -108,9 +131,43 @@ func (h *AccountHandler) GetAccount(c *gin.Context) {
 	c.JSON(http.StatusOK, view)
 }
 
+// GetBalance returns the ledger balance now, or as at the RFC 3339 timestamp
+// given in the "at" query parameter.
+func (h *AccountHandler) GetBalance(c *gin.Context) {
+	accountNumber := c.Param("accountNumber")
+
+	asAt := time.Now().UTC()
+	if at := c.Query("at"); at != "" {
+		parsed, err := time.Parse(time.RFC3339, at)
+		if err != nil {
+			middleware.RespondWithError(c, http.StatusBadRequest, "at must be an RFC 3339 timestamp")
+			return
+		}
+		asAt = parsed.UTC()
+	}
+
+	balance, err := h.queries.GetBalance(c.Request.Context(), cqrs.GetBalanceQuery{
+		AccountNumber: accountNumber,
+		Principal:     middleware.GetPrincipal(c),
+		AsAt:          asAt,
+	})
+	if err != nil {
+		switch err.Error() {
+		case "forbidden":
+			middleware.RespondWithError(c, http.StatusForbidden, "You can only access your own accounts")
+		case "account not found":
+			middleware.RespondWithError(c, http.StatusNotFound, "Account not found")
+		default:
+			middleware.RespondWithError(c, http.StatusInternalServerError, "Failed to get balance")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, balance)
+}
+
 func (h *AccountHandler) UpdateAccount(c *gin.Context) {
 	accountNumber := c.Param("accountNumber")
-	userID, _ := middleware.GetUserID(c)
 
 	var req UpdateAccountRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -122,11 +179,11 @@ func (h *AccountHandler) UpdateAccount(c *gin.Context) {
 		return
 	}
 
-	view, err := h.commands.UpdateAccount(cqrs.UpdateAccountCommand{
-		AccountNumber:    accountNumber,
-		RequestingUserID: userID,
-		Name:             req.Name,
-		AccountType:      req.AccountType,
+	view, err := h.commands.UpdateAccount(c.Request.Context(), cqrs.UpdateAccountCommand{
+		AccountNumber: accountNumber,
+		Principal:     middleware.GetPrincipal(c),
+		Name:          req.Name,
+		AccountType:   req.AccountType,
 	})
 	if err != nil {
 		if err.Error() == "account not found" {
@@ -146,11 +203,10 @@ func (h *AccountHandler) UpdateAccount(c *gin.Context) {
 
 func (h *AccountHandler) DeleteAccount(c *gin.Context) {
 	accountNumber := c.Param("accountNumber")
-	userID, _ := middleware.GetUserID(c)
 
-	err := h.commands.DeleteAccount(cqrs.DeleteAccountCommand{
-		AccountNumber:    accountNumber,
-		RequestingUserID: userID,
+	err := h.commands.DeleteAccount(c.Request.Context(), cqrs.DeleteAccountCommand{
+		AccountNumber: accountNumber,
+		Principal:     middleware.GetPrincipal(c),
 	})
 	if err != nil {
 		if err.Error() == "account not found" {
@@ -161,9 +217,74 @@ func (h *AccountHandler) DeleteAccount(c *gin.Context) {
 			middleware.RespondWithError(c, http.StatusForbidden, "You can only delete your own accounts")
 			return
 		}
+		if err.Error() == "account frozen" {
+			middleware.RespondWithError(c, http.StatusConflict, "A frozen account cannot be closed")
+			return
+		}
 		middleware.RespondWithError(c, http.StatusInternalServerError, "Failed to delete account")
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
+
+// FreezeAccount is the back-office action that stops an account accepting
+// transactions and transfers.
+func (h *AccountHandler) FreezeAccount(c *gin.Context) {
+	req, ok := bindAccountStatusRequest(c)
+	if !ok {
+		return
+	}
+	view, err := h.commands.FreezeAccount(c.Request.Context(), cqrs.FreezeAccountCommand{
+		AccountNumber: c.Param("accountNumber"),
+		ActorID:       middleware.GetPrincipal(c).Subject(),
+		Reason:        req.Reason,
+	})
+	respondAccountStatus(c, view, err)
+}
+
+// UnfreezeAccount is the back-office action that lifts a freeze.
+func (h *AccountHandler) UnfreezeAccount(c *gin.Context) {
+	req, ok := bindAccountStatusRequest(c)
+	if !ok {
+		return
+	}
+	view, err := h.commands.UnfreezeAccount(c.Request.Context(), cqrs.UnfreezeAccountCommand{
+		AccountNumber: c.Param("accountNumber"),
+		ActorID:       middleware.GetPrincipal(c).Subject(),
+		Reason:        req.Reason,
+	})
+	respondAccountStatus(c, view, err)
+}
+
+func bindAccountStatusRequest(c *gin.Context) (*AccountStatusRequest, bool) {
+	var req AccountStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.RespondWithError(c, http.StatusBadRequest, "Invalid request body")
+		return nil, false
+	}
+	if validationErrors := middleware.ValidateRequest(req); validationErrors != nil {
+		middleware.RespondWithValidationError(c, validationErrors)
+		return nil, false
+	}
+	admin.AuditDetail(c, "reason", req.Reason)
+	return &req, true
+}
+
+func respondAccountStatus(c *gin.Context, view *models.AccountView, err error) {
+	if err != nil {
+		switch err.Error() {
+		case "account not found":
+			middleware.RespondWithError(c, http.StatusNotFound, "Account not found")
+		case "account already frozen":
+			middleware.RespondWithError(c, http.StatusConflict, "Account is already frozen")
+		case "account not frozen":
+			middleware.RespondWithError(c, http.StatusConflict, "Account is not frozen")
+		default:
+			middleware.RespondWithError(c, http.StatusInternalServerError, "Failed to change account status")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, view)
+}
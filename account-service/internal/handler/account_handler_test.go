@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/eaglebank/shared/cqrs"
 	"github.com/eaglebank/shared/models"
+	"github.com/eaglebank/shared/money"
 	"github.com/gin-gonic/gin"
 )
 
@@ -20,44 +22,61 @@ type mockAccountCommander struct {
 	createFn func(cqrs.CreateAccountCommand) (*models.Account, error)
 	updateFn func(cqrs.UpdateAccountCommand) (*models.AccountView, error)
 	deleteFn func(cqrs.DeleteAccountCommand) error
+	freezeFn func(cqrs.FreezeAccountCommand) (*models.AccountView, error)
 }
 
-func (m *mockAccountCommander) CreateAccount(cmd cqrs.CreateAccountCommand) (*models.Account, error) {
+func (m *mockAccountCommander) CreateAccount(_ context.Context, cmd cqrs.CreateAccountCommand) (*models.Account, error) {
 	if m.createFn != nil {
 		return m.createFn(cmd)
 	}
 	return nil, fmt.Errorf("not configured")
 }
-func (m *mockAccountCommander) UpdateAccount(cmd cqrs.UpdateAccountCommand) (*models.AccountView, error) {
+func (m *mockAccountCommander) UpdateAccount(_ context.Context, cmd cqrs.UpdateAccountCommand) (*models.AccountView, error) {
 	if m.updateFn != nil {
 		return m.updateFn(cmd)
 	}
 	return nil, fmt.Errorf("not configured")
 }
-func (m *mockAccountCommander) DeleteAccount(cmd cqrs.DeleteAccountCommand) error {
+func (m *mockAccountCommander) DeleteAccount(_ context.Context, cmd cqrs.DeleteAccountCommand) error {
 	if m.deleteFn != nil {
 		return m.deleteFn(cmd)
 	}
 	return fmt.Errorf("not configured")
 }
+func (m *mockAccountCommander) FreezeAccount(_ context.Context, cmd cqrs.FreezeAccountCommand) (*models.AccountView, error) {
+	if m.freezeFn != nil {
+		return m.freezeFn(cmd)
+	}
+	return nil, fmt.Errorf("not configured")
+}
+func (m *mockAccountCommander) UnfreezeAccount(_ context.Context, cmd cqrs.UnfreezeAccountCommand) (*models.AccountView, error) {
+	return nil, fmt.Errorf("not configured")
+}
 
 type mockAccountQuerier struct {
-	getFn  func(cqrs.GetAccountQuery) (*models.AccountView, error)
-	listFn func(cqrs.ListAccountsQuery) ([]models.AccountView, error)
+	getFn     func(cqrs.GetAccountQuery) (*models.AccountView, error)
+	listFn    func(cqrs.ListAccountsQuery) ([]models.AccountView, error)
+	balanceFn func(cqrs.GetBalanceQuery) (*models.BalanceView, error)
 }
 
-func (m *mockAccountQuerier) GetAccount(q cqrs.GetAccountQuery) (*models.AccountView, error) {
+func (m *mockAccountQuerier) GetAccount(_ context.Context, q cqrs.GetAccountQuery) (*models.AccountView, error) {
 	if m.getFn != nil {
 		return m.getFn(q)
 	}
 	return nil, fmt.Errorf("not configured")
 }
-func (m *mockAccountQuerier) ListAccounts(q cqrs.ListAccountsQuery) ([]models.AccountView, error) {
+func (m *mockAccountQuerier) ListAccounts(_ context.Context, q cqrs.ListAccountsQuery) ([]models.AccountView, error) {
 	if m.listFn != nil {
 		return m.listFn(q)
 	}
 	return nil, fmt.Errorf("not configured")
 }
+func (m *mockAccountQuerier) GetBalance(_ context.Context, q cqrs.GetBalanceQuery) (*models.BalanceView, error) {
+	if m.balanceFn != nil {
+		return m.balanceFn(q)
+	}
+	return nil, fmt.Errorf("not configured")
+}
 
 // ---- helpers ----
 
@@ -77,8 +96,10 @@ func newAccountTestRouter(cmds AccountCommander, qrys AccountQuerier, authUserID
 	v1.POST("", h.CreateAccount)
 	v1.GET("", h.ListAccounts)
 	v1.GET("/:accountNumber", h.GetAccount)
+	v1.GET("/:accountNumber/balance", h.GetBalance)
 	v1.PATCH("/:accountNumber", h.UpdateAccount)
 	v1.DELETE("/:accountNumber", h.DeleteAccount)
+	r.POST("/admin/v1/accounts/:accountNumber/freeze", h.FreezeAccount)
 	return r
 }
 
@@ -99,14 +120,14 @@ func acctDoRequest(router *gin.Engine, method, url string, body interface{}) *ht
 var aTestAccount = &models.Account{
 	AccountNumber: "12345678", UserID: "usr-001", SortCode: "10-10-10",
 	Name: "My Account", AccountType: "personal",
-	Balance: 100.00, Currency: "GBP",
+	Balance: money.MustParse("100.00"), Currency: "GBP",
 	CreatedAt: time.Now(), UpdatedAt: time.Now(),
 }
 
 var aTestAccountView = &models.AccountView{
 	AccountNumber: "12345678", UserID: "usr-001", SortCode: "10-10-10",
 	Name: "My Account", AccountType: "personal",
-	Balance: 100.00, Currency: "GBP",
+	Balance: money.MustParse("100.00"), Currency: "GBP",
 	CreatedAt: time.Now(), UpdatedAt: time.Now(),
 }
 
@@ -133,6 +154,12 @@ func TestCreateAccount(t *testing.T) {
 			createFn: func(cmd cqrs.CreateAccountCommand) (*models.Account, error) { return aTestAccount, nil },
 			expectedStatus: http.StatusCreated,
 		},
+		{
+			name:           "forbidden - email not verified",
+			body:           aValidCreateBody(),
+			createFn:       func(cmd cqrs.CreateAccountCommand) (*models.Account, error) { return nil, fmt.Errorf("user not verified") },
+			expectedStatus: http.StatusForbidden,
+		},
 		{
 			name:           "bad request - missing required fields",
 			body:           map[string]interface{}{},
@@ -272,6 +299,12 @@ func TestDeleteAccount(t *testing.T) {
 			deleteFn:       func(cmd cqrs.DeleteAccountCommand) error { return fmt.Errorf("account not found") },
 			expectedStatus: http.StatusNotFound,
 		},
+		{
+			name:           "conflict - account is frozen",
+			accountNum:     "12345678",
+			deleteFn:       func(cmd cqrs.DeleteAccountCommand) error { return fmt.Errorf("account frozen") },
+			expectedStatus: http.StatusConflict,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -284,3 +317,111 @@ func TestDeleteAccount(t *testing.T) {
 		})
 	}
 }
+
+func TestGetBalance(t *testing.T) {
+	asAt := time.Date(2024, 1, 31, 23, 59, 59, 0, time.UTC)
+	tests := []struct {
+		name           string
+		url            string
+		balanceFn      func(cqrs.GetBalanceQuery) (*models.BalanceView, error)
+		expectedStatus int
+	}{
+		{
+			name: "success - current balance of own account",
+			url:  "/v1/accounts/12345678/balance",
+			balanceFn: func(q cqrs.GetBalanceQuery) (*models.BalanceView, error) {
+				return &models.BalanceView{AccountNumber: q.AccountNumber, Balance: money.MustParse("100.00"), Currency: "GBP", AsAt: q.AsAt}, nil
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "success - balance as at a past timestamp",
+			url:  "/v1/accounts/12345678/balance?at=2024-01-31T23:59:59Z",
+			balanceFn: func(q cqrs.GetBalanceQuery) (*models.BalanceView, error) {
+				if !q.AsAt.Equal(asAt) {
+					return nil, fmt.Errorf("unexpected timestamp %s", q.AsAt)
+				}
+				return &models.BalanceView{AccountNumber: q.AccountNumber, Currency: "GBP", AsAt: q.AsAt}, nil
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "bad request - malformed timestamp",
+			url:            "/v1/accounts/12345678/balance?at=yesterday",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "forbidden - balance of another user's account",
+			url:            "/v1/accounts/99999999/balance",
+			balanceFn:      func(q cqrs.GetBalanceQuery) (*models.BalanceView, error) { return nil, fmt.Errorf("forbidden") },
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "not found - account does not exist",
+			url:            "/v1/accounts/00000000/balance",
+			balanceFn:      func(q cqrs.GetBalanceQuery) (*models.BalanceView, error) { return nil, fmt.Errorf("account not found") },
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newAccountTestRouter(&mockAccountCommander{}, &mockAccountQuerier{balanceFn: tt.balanceFn}, "usr-001")
+			w := acctDoRequest(router, http.MethodGet, tt.url, nil)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("[%s] expected %d got %d; body: %s", tt.name, tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestFreezeAccount(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           interface{}
+		freezeFn       func(cqrs.FreezeAccountCommand) (*models.AccountView, error)
+		expectedStatus int
+	}{
+		{
+			name: "success - frozen by the acting staff member",
+			body: map[string]interface{}{"reason": "suspected fraud"},
+			freezeFn: func(cmd cqrs.FreezeAccountCommand) (*models.AccountView, error) {
+				if cmd.ActorID != "usr-staff" || cmd.Reason != "suspected fraud" {
+					return nil, fmt.Errorf("unexpected command %+v", cmd)
+				}
+				return aTestAccountView, nil
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "bad request - reason is required",
+			body:           map[string]interface{}{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "conflict - already frozen",
+			body: map[string]interface{}{"reason": "suspected fraud"},
+			freezeFn: func(cmd cqrs.FreezeAccountCommand) (*models.AccountView, error) {
+				return nil, fmt.Errorf("account already frozen")
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name: "not found - account does not exist",
+			body: map[string]interface{}{"reason": "suspected fraud"},
+			freezeFn: func(cmd cqrs.FreezeAccountCommand) (*models.AccountView, error) {
+				return nil, fmt.Errorf("account not found")
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmds := &mockAccountCommander{freezeFn: tt.freezeFn}
+			router := newAccountTestRouter(cmds, &mockAccountQuerier{}, "usr-staff")
+			w := acctDoRequest(router, http.MethodPost, "/admin/v1/accounts/12345678/freeze", tt.body)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("[%s] expected %d got %d; body: %s", tt.name, tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
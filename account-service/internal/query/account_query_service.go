@@ -5,6 +5,7 @@ import (
 
 	"github.com/eaglebank/account-service/internal/repository"
 	"github.com/eaglebank/shared/cqrs"
+	"github.com/eaglebank/shared/middleware"
 	"github.com/eaglebank/shared/models"
 )
 
@@ -16,28 +17,48 @@ func NewAccountQueryService(readRepo *repository.AccountReadRepository) *Account
 	return &AccountQueryService{readRepo: readRepo}
 }
 
-// GetAccount fetches a single account view and enforces ownership.
-func (s *AccountQueryService) GetAccount(q cqrs.GetAccountQuery) (*models.AccountView, error) {
-	ctx := context.Background()
+// GetAccount fetches a single account view. Callers other than the owner
+// need middleware.ScopeAccountsRead.
+func (s *AccountQueryService) GetAccount(ctx context.Context, q cqrs.GetAccountQuery) (*models.AccountView, error) {
 	view, err := s.readRepo.GetByAccountNumber(ctx, q.AccountNumber)
 	if err != nil {
 		return nil, err
 	}
 
 	// Ownership check: the AccountView carries UserID (json:"-") for this purpose.
-	if view.UserID != q.RequestingUserID {
+	if !q.Principal.CanAccess(view.UserID, middleware.ScopeAccountsRead) {
 		return nil, &forbiddenError{}
 	}
 
 	return view, nil
 }
 
-func (s *AccountQueryService) ListAccounts(q cqrs.ListAccountsQuery) ([]models.AccountView, error) {
-	ctx := context.Background()
+func (s *AccountQueryService) ListAccounts(ctx context.Context, q cqrs.ListAccountsQuery) ([]models.AccountView, error) {
 	return s.readRepo.ListByUserID(ctx, q.UserID)
 }
 
-// forbiddenError signals that the requesting user does not own the resource.
+// GetBalance derives the account balance from the ledger as at q.AsAt.
+func (s *AccountQueryService) GetBalance(ctx context.Context, q cqrs.GetBalanceQuery) (*models.BalanceView, error) {
+	view, err := s.readRepo.GetByAccountNumber(ctx, q.AccountNumber)
+	if err != nil {
+		return nil, err
+	}
+	if !q.Principal.CanAccess(view.UserID, middleware.ScopeAccountsRead) {
+		return nil, &forbiddenError{}
+	}
+	balance, err := s.readRepo.BalanceAt(ctx, q.AccountNumber, q.AsAt)
+	if err != nil {
+		return nil, err
+	}
+	return &models.BalanceView{
+		AccountNumber: q.AccountNumber,
+		Balance:       balance,
+		Currency:      view.Currency,
+		AsAt:          q.AsAt,
+	}, nil
+}
+
+// forbiddenError signals that the caller may not access the resource.
 type forbiddenError struct{}
 
 func (e *forbiddenError) Error() string { return "forbidden" }
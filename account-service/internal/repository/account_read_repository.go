@@ -4,10 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 
 	"github.com/eaglebank/shared/models"
+	"github.com/eaglebank/shared/money"
 	sharedredis "github.com/eaglebank/shared/redis"
 	goredis "github.com/redis/go-redis/v9"
 )
@@ -18,15 +19,16 @@ const accountViewKeyPrefix = "account:view:"
 // Unlike models.AccountView, it includes UserID so that downstream services
 // (e.g. transaction-service) can perform ownership checks from the cache.
 type accountCacheEntry struct {
-	AccountNumber string    `json:"accountNumber"`
-	UserID        string    `json:"userId"`
-	SortCode      string    `json:"sortCode"`
-	Name          string    `json:"name"`
-	AccountType   string    `json:"accountType"`
-	Balance       float64   `json:"balance"`
-	Currency      string    `json:"currency"`
-	CreatedAt     time.Time `json:"createdTimestamp"`
-	UpdatedAt     time.Time `json:"updatedTimestamp"`
+	AccountNumber string       `json:"accountNumber"`
+	UserID        string       `json:"userId"`
+	SortCode      string       `json:"sortCode"`
+	Name          string       `json:"name"`
+	AccountType   string       `json:"accountType"`
+	Balance       money.Amount `json:"balance"`
+	Currency      string       `json:"currency"`
+	Status        string       `json:"status"`
+	CreatedAt     time.Time    `json:"createdTimestamp"`
+	UpdatedAt     time.Time    `json:"updatedTimestamp"`
 }
 
 // AccountReadRepository handles all read operations for accounts.
@@ -42,7 +44,7 @@ func NewAccountReadRepository(db *sql.DB, redisClient *goredis.Client) *AccountR
 	return &AccountReadRepository{
 		db:    db,
 		redis: redisClient,
-		cache: sharedredis.NewViewCache[accountCacheEntry](redisClient, 0),
+		cache: sharedredis.NewViewCache[accountCacheEntry](redisClient, "account", 0),
 	}
 }
 
@@ -56,6 +58,7 @@ func cacheEntryToView(e *accountCacheEntry) *models.AccountView {
 		AccountType:   e.AccountType,
 		Balance:       e.Balance,
 		Currency:      e.Currency,
+		Status:        e.Status,
 		CreatedAt:     e.CreatedAt,
 		UpdatedAt:     e.UpdatedAt,
 	}
@@ -71,14 +74,14 @@ func (r *AccountReadRepository) GetByAccountNumber(ctx context.Context, accountN
 
 	// Fallback: PostgreSQL — include user_id so the service can enforce ownership.
 	query := `
-		SELECT account_number, user_id, sort_code, name, account_type, balance, currency, created_at, updated_at
+		SELECT account_number, user_id, sort_code, name, account_type, balance, currency, status, created_at, updated_at
 		FROM accounts
 		WHERE account_number = $1 AND deleted_at IS NULL
 	`
 	var view models.AccountView
 	pgErr := r.db.QueryRow(query, accountNumber).Scan(
 		&view.AccountNumber, &view.UserID, &view.SortCode, &view.Name,
-		&view.AccountType, &view.Balance, &view.Currency,
+		&view.AccountType, &view.Balance, &view.Currency, &view.Status,
 		&view.CreatedAt, &view.UpdatedAt,
 	)
 	if pgErr == sql.ErrNoRows {
@@ -96,7 +99,7 @@ func (r *AccountReadRepository) GetByAccountNumber(ctx context.Context, accountN
 // ListByUserID returns all AccountViews for the given user from PostgreSQL.
 func (r *AccountReadRepository) ListByUserID(ctx context.Context, userID string) ([]models.AccountView, error) {
 	query := `
-		SELECT account_number, user_id, sort_code, name, account_type, balance, currency, created_at, updated_at
+		SELECT account_number, user_id, sort_code, name, account_type, balance, currency, status, created_at, updated_at
 		FROM accounts
 		WHERE user_id = $1 AND deleted_at IS NULL
 		ORDER BY created_at DESC
@@ -112,7 +115,7 @@ func (r *AccountReadRepository) ListByUserID(ctx context.Context, userID string)
 		var view models.AccountView
 		if err := rows.Scan(
 			&view.AccountNumber, &view.UserID, &view.SortCode, &view.Name,
-			&view.AccountType, &view.Balance, &view.Currency,
+			&view.AccountType, &view.Balance, &view.Currency, &view.Status,
 			&view.CreatedAt, &view.UpdatedAt,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan account: %w", err)
@@ -134,6 +137,7 @@ func (r *AccountReadRepository) CacheAccountView(ctx context.Context, view *mode
 		AccountType:   view.AccountType,
 		Balance:       view.Balance,
 		Currency:      view.Currency,
+		Status:        view.Status,
 		CreatedAt:     view.CreatedAt,
 		UpdatedAt:     view.UpdatedAt,
 	}
@@ -161,6 +165,22 @@ func (r *AccountReadRepository) IsTransactionProcessed(ctx context.Context, tran
 func (r *AccountReadRepository) MarkTransactionProcessed(ctx context.Context, transactionID string) {
 	key := processedTxnKeyPrefix + transactionID
 	if err := r.redis.Set(ctx, key, "1", 72*time.Hour).Err(); err != nil {
-		log.Printf("Failed to mark transaction %s as processed: %v", transactionID, err)
+		slog.ErrorContext(ctx, "Failed to mark transaction as processed", "transaction_id", transactionID, "err", err)
 	}
 }
+
+// BalanceAt derives an account's balance from the ledger postings whose
+// journal entries took effect at or before at. Always read from PostgreSQL.
+func (r *AccountReadRepository) BalanceAt(ctx context.Context, accountNumber string, at time.Time) (money.Amount, error) {
+	query := `
+		SELECT COALESCE(SUM(p.amount), 0)
+		FROM postings p
+		JOIN journal_entries j ON j.id = p.entry_id
+		WHERE p.account = $1 AND j.effective_at <= $2
+	`
+	var balance money.Amount
+	if err := r.db.QueryRowContext(ctx, query, accountNumber, at).Scan(&balance); err != nil {
+		return 0, fmt.Errorf("failed to derive balance: %w", err)
+	}
+	return balance, nil
+}
@@ -1,10 +1,15 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
+	"github.com/eaglebank/shared/events"
+	"github.com/eaglebank/shared/ledger"
 	"github.com/eaglebank/shared/models"
+	"github.com/eaglebank/shared/money"
 )
 
 // AccountWriteRepository handles all state-mutating operations for accounts.
@@ -17,33 +22,40 @@ func NewAccountWriteRepository(db *sql.DB) *AccountWriteRepository {
 	return &AccountWriteRepository{db: db}
 }
 
-func (r *AccountWriteRepository) Create(account *models.Account) error {
+// Create inserts the account and queues its outbox events in one SQL transaction.
+func (r *AccountWriteRepository) Create(account *models.Account, outbox ...events.OutboxEvent) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
-		INSERT INTO accounts (account_number, user_id, sort_code, name, account_type, balance, currency, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO accounts (account_number, user_id, sort_code, name, account_type, balance, currency, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 	`
-	_, err := r.db.Exec(query,
+	_, err = tx.Exec(query,
 		account.AccountNumber, account.UserID, account.SortCode, account.Name,
-		account.AccountType, account.Balance, account.Currency,
+		account.AccountType, account.Balance, account.Currency, account.Status,
 		account.CreatedAt, account.UpdatedAt,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create account: %w", err)
 	}
-	return nil
+	return commitWithOutbox(tx, outbox)
 }
 
 // GetByAccountNumber fetches the full write model including UserID for ownership checks.
-func (r *AccountWriteRepository) GetByAccountNumber(accountNumber string) (*models.Account, error) {
+func (r *AccountWriteRepository) GetByAccountNumber(ctx context.Context, accountNumber string) (*models.Account, error) {
 	query := `
-		SELECT account_number, user_id, sort_code, name, account_type, balance, currency, created_at, updated_at
+		SELECT account_number, user_id, sort_code, name, account_type, balance, currency, status, created_at, updated_at
 		FROM accounts
 		WHERE account_number = $1 AND deleted_at IS NULL
 	`
 	var account models.Account
-	err := r.db.QueryRow(query, accountNumber).Scan(
+	err := r.db.QueryRowContext(ctx, query, accountNumber).Scan(
 		&account.AccountNumber, &account.UserID, &account.SortCode, &account.Name,
-		&account.AccountType, &account.Balance, &account.Currency,
+		&account.AccountType, &account.Balance, &account.Currency, &account.Status,
 		&account.CreatedAt, &account.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
@@ -55,60 +67,184 @@ func (r *AccountWriteRepository) GetByAccountNumber(accountNumber string) (*mode
 	return &account, nil
 }
 
-func (r *AccountWriteRepository) Update(account *models.Account) error {
+func (r *AccountWriteRepository) Update(account *models.Account, outbox ...events.OutboxEvent) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
 		UPDATE accounts
 		SET name = $2, account_type = $3, updated_at = $4
 		WHERE account_number = $1 AND deleted_at IS NULL
 	`
-	result, err := r.db.Exec(query, account.AccountNumber, account.Name, account.AccountType, account.UpdatedAt)
+	result, err := tx.Exec(query, account.AccountNumber, account.Name, account.AccountType, account.UpdatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to update account: %w", err)
 	}
-	rows, err := result.RowsAffected()
+	if err := requireRowAffected(result); err != nil {
+		return err
+	}
+	return commitWithOutbox(tx, outbox)
+}
+
+// SetStatus moves the account to status and queues the outbox events in the
+// same SQL transaction. It reports false, writing nothing, if the account
+// already had that status.
+func (r *AccountWriteRepository) SetStatus(accountNumber, status string, outbox ...events.OutboxEvent) (bool, error) {
+	tx, err := r.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to check rows affected: %w", err)
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	if rows == 0 {
-		return fmt.Errorf("account not found")
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE accounts SET status = $2, updated_at = NOW()
+		WHERE account_number = $1 AND deleted_at IS NULL AND status <> $2`,
+		accountNumber, status,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to update account status: %w", err)
 	}
-	return nil
+	if n, err := result.RowsAffected(); err != nil {
+		return false, fmt.Errorf("failed to check rows affected: %w", err)
+	} else if n == 0 {
+		return false, nil
+	}
+	if err := commitWithOutbox(tx, outbox); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
-func (r *AccountWriteRepository) UpdateBalance(accountNumber string, newBalance float64) error {
+// PostJournalEntry records the ledger entry for transactionID and derives the
+// account balance from its postings, queueing the outbox events (typically
+// balance.updated) in the same SQL transaction. The account row is locked for
+// the duration, and newBalance is the balance the caller expects to result:
+// if the derived balance differs, the caller worked from a stale read and
+// "balance conflict" is returned so the event can be retried.
+//
+// The transaction ID is recorded in processed_transactions as part of that
+// commit, so a redelivered event returns applied=false and posts nothing.
+func (r *AccountWriteRepository) PostJournalEntry(ctx context.Context, entry *ledger.JournalEntry, accountNumber string, newBalance money.Amount, outbox ...events.OutboxEvent) (bool, error) {
+	if err := entry.Validate(); err != nil {
+		return false, err
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	marked, err := tx.ExecContext(ctx, `
+		INSERT INTO processed_transactions (transaction_id, account_number)
+		VALUES ($1, $2)
+		ON CONFLICT (transaction_id) DO NOTHING
+	`, entry.TransactionID, accountNumber)
+	if err != nil {
+		return false, fmt.Errorf("failed to record processed transaction: %w", err)
+	}
+	if n, err := marked.RowsAffected(); err != nil {
+		return false, fmt.Errorf("failed to check rows affected: %w", err)
+	} else if n == 0 {
+		return false, nil
+	}
+
+	var current money.Amount
+	err = tx.QueryRowContext(ctx, `SELECT balance FROM accounts WHERE account_number = $1 AND deleted_at IS NULL FOR UPDATE`, accountNumber).Scan(&current)
+	if err == sql.ErrNoRows {
+		return false, fmt.Errorf("account not found")
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to lock account: %w", err)
+	}
+
+	if err := insertJournalEntry(ctx, tx, entry); err != nil {
+		return false, err
+	}
+
 	query := `
 		UPDATE accounts
-		SET balance = $2, updated_at = NOW()
+		SET balance = (SELECT COALESCE(SUM(amount), 0) FROM postings WHERE account = $1), updated_at = NOW()
 		WHERE account_number = $1 AND deleted_at IS NULL
+		RETURNING balance
 	`
-	result, err := r.db.Exec(query, accountNumber, newBalance)
-	if err != nil {
-		return fmt.Errorf("failed to update balance: %w", err)
+	var derived money.Amount
+	if err := tx.QueryRowContext(ctx, query, accountNumber).Scan(&derived); err != nil {
+		return false, fmt.Errorf("failed to update balance: %w", err)
 	}
-	rows, err := result.RowsAffected()
+	if derived != newBalance {
+		return false, fmt.Errorf("balance conflict")
+	}
+	if err := commitWithOutbox(tx, outbox); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func insertJournalEntry(ctx context.Context, tx *sql.Tx, entry *ledger.JournalEntry) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO journal_entries (id, transaction_id, type, currency, effective_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, entry.ID, entry.TransactionID, entry.Type, entry.Currency, entry.EffectiveAt)
 	if err != nil {
-		return fmt.Errorf("failed to check rows affected: %w", err)
+		return fmt.Errorf("failed to create journal entry: %w", err)
 	}
-	if rows == 0 {
-		return fmt.Errorf("account not found")
+	for _, p := range entry.Postings {
+		_, err := tx.ExecContext(ctx, `INSERT INTO postings (entry_id, account, amount) VALUES ($1, $2, $3)`, entry.ID, p.Account, p.Amount)
+		if err != nil {
+			return fmt.Errorf("failed to create posting: %w", err)
+		}
 	}
 	return nil
 }
 
-func (r *AccountWriteRepository) Delete(accountNumber string) error {
-	query := `UPDATE accounts SET deleted_at = NOW() WHERE account_number = $1 AND deleted_at IS NULL`
-	result, err := r.db.Exec(query, accountNumber)
+// RejectTransaction records transactionID as processed without touching any
+// balance and queues the outbox events (typically transaction.rejected). Like
+// PostJournalEntry it returns applied=false for a redelivered event.
+func (r *AccountWriteRepository) RejectTransaction(ctx context.Context, transactionID, accountNumber string, outbox ...events.OutboxEvent) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to delete account: %w", err)
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	rows, err := result.RowsAffected()
+	defer tx.Rollback()
+
+	marked, err := tx.ExecContext(ctx, `
+		INSERT INTO processed_transactions (transaction_id, account_number)
+		VALUES ($1, $2)
+		ON CONFLICT (transaction_id) DO NOTHING
+	`, transactionID, accountNumber)
 	if err != nil {
-		return fmt.Errorf("failed to check rows affected: %w", err)
+		return false, fmt.Errorf("failed to record processed transaction: %w", err)
 	}
-	if rows == 0 {
-		return fmt.Errorf("account not found")
+	if n, err := marked.RowsAffected(); err != nil {
+		return false, fmt.Errorf("failed to check rows affected: %w", err)
+	} else if n == 0 {
+		return false, nil
 	}
-	return nil
+	if err := commitWithOutbox(tx, outbox); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *AccountWriteRepository) Delete(accountNumber string, outbox ...events.OutboxEvent) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE accounts SET deleted_at = NOW() WHERE account_number = $1 AND deleted_at IS NULL`
+	result, err := tx.Exec(query, accountNumber)
+	if err != nil {
+		return fmt.Errorf("failed to delete account: %w", err)
+	}
+	if err := requireRowAffected(result); err != nil {
+		return err
+	}
+	return commitWithOutbox(tx, outbox)
 }
 
 func (r *AccountWriteRepository) CountByUserID(userID string) (int, error) {
@@ -120,3 +256,60 @@ func (r *AccountWriteRepository) CountByUserID(userID string) (int, error) {
 	}
 	return count, nil
 }
+
+// MarkUserVerified records that userID has verified their email address.
+// Recording the same user again has no effect.
+func (r *AccountWriteRepository) MarkUserVerified(userID string, verifiedAt time.Time) error {
+	_, err := r.db.Exec(`
+		INSERT INTO verified_users (user_id, verified_at) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO NOTHING`,
+		userID, verifiedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record verified user: %w", err)
+	}
+	return nil
+}
+
+// MarkUserUnverified forgets that userID verified their email address, after
+// they change it. Accounts already open are unaffected.
+func (r *AccountWriteRepository) MarkUserUnverified(userID string) error {
+	if _, err := r.db.Exec(`DELETE FROM verified_users WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to clear verified user: %w", err)
+	}
+	return nil
+}
+
+// IsUserVerified reports whether a user.verified event has been seen for
+// userID.
+func (r *AccountWriteRepository) IsUserVerified(userID string) (bool, error) {
+	var verified bool
+	err := r.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM verified_users WHERE user_id = $1)`, userID).Scan(&verified)
+	if err != nil {
+		return false, fmt.Errorf("failed to check user verification: %w", err)
+	}
+	return verified, nil
+}
+
+// requireRowAffected maps an UPDATE that matched nothing to "account not found".
+func requireRowAffected(result sql.Result) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("account not found")
+	}
+	return nil
+}
+
+// commitWithOutbox queues the outbox events on tx and commits it.
+func commitWithOutbox(tx *sql.Tx, outbox []events.OutboxEvent) error {
+	if err := events.WriteOutbox(tx, outbox...); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
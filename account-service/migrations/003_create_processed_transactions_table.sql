@@ -0,0 +1,10 @@
+CREATE TABLE
+IF NOT EXISTS processed_transactions
+(
+    transaction_id VARCHAR
+(50) PRIMARY KEY,
+    account_number VARCHAR
+(8) NOT NULL,
+    processed_at TIMESTAMP NOT NULL DEFAULT NOW
+()
+);
@@ -0,0 +1,78 @@
+CREATE TABLE
+IF NOT EXISTS journal_entries
+(
+    id VARCHAR
+(50) PRIMARY KEY,
+    transaction_id VARCHAR
+(50) NOT NULL UNIQUE,
+    type VARCHAR
+(20) NOT NULL,
+    currency VARCHAR
+(3) NOT NULL,
+    effective_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW
+()
+);
+
+CREATE TABLE
+IF NOT EXISTS postings
+(
+    id BIGSERIAL PRIMARY KEY,
+    entry_id VARCHAR
+(50) NOT NULL REFERENCES journal_entries
+(id),
+    account VARCHAR
+(50) NOT NULL,
+    amount DECIMAL
+(10,2) NOT NULL,
+    CONSTRAINT posting_amount_nonzero CHECK
+(amount <> 0)
+);
+
+CREATE INDEX idx_postings_entry ON postings(entry_id);
+CREATE INDEX idx_postings_account ON postings(account);
+CREATE INDEX idx_journal_entries_effective_at ON journal_entries(effective_at);
+
+-- Every journal entry must balance. Checked at commit so all postings of the
+-- entry are visible.
+CREATE OR REPLACE FUNCTION check_journal_entry_balanced() RETURNS TRIGGER AS $$
+BEGIN
+    IF (SELECT COALESCE(SUM(amount), 0) FROM postings WHERE entry_id = NEW.entry_id) <> 0 THEN
+        RAISE EXCEPTION 'journal entry % does not balance', NEW.entry_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER postings_balanced
+AFTER INSERT OR UPDATE ON postings
+DEFERRABLE INITIALLY DEFERRED
+FOR EACH ROW EXECUTE FUNCTION check_journal_entry_balanced();
+
+-- Accounts opened before the ledger existed carry a balance with no postings
+-- behind it. Give each one an opening entry against internal:opening_equity,
+-- effective when the balance last changed, so the balance derived from its
+-- postings matches the stored one.
+INSERT INTO journal_entries
+    (id, transaction_id, type, currency, effective_at)
+SELECT 'jnl_opening_' || account_number, 'opening_' || account_number, 'opening_balance', currency, updated_at
+FROM accounts
+WHERE balance <> 0
+ON CONFLICT
+(transaction_id) DO NOTHING;
+
+INSERT INTO postings
+    (entry_id, account, amount)
+SELECT j.id, a.account_number, a.balance
+FROM accounts a
+    JOIN journal_entries j ON j.transaction_id = 'opening_' || a.account_number
+WHERE NOT EXISTS (SELECT 1
+FROM postings p
+WHERE p.entry_id = j.id)
+UNION ALL
+SELECT j.id, 'internal:opening_equity', -a.balance
+FROM accounts a
+    JOIN journal_entries j ON j.transaction_id = 'opening_' || a.account_number
+WHERE NOT EXISTS (SELECT 1
+FROM postings p
+WHERE p.entry_id = j.id);
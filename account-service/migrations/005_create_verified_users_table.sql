@@ -0,0 +1,9 @@
+-- Users who have verified their email address, projected from user.verified
+-- events. Only verified users may open accounts.
+CREATE TABLE
+IF NOT EXISTS verified_users
+(
+    user_id VARCHAR
+(50) PRIMARY KEY,
+    verified_at TIMESTAMP NOT NULL
+);
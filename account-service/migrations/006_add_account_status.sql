@@ -0,0 +1,5 @@
+-- status is 'frozen' while staff have frozen the account; a frozen account
+-- accepts no transactions or transfers and cannot be closed.
+ALTER TABLE accounts ADD COLUMN
+IF NOT EXISTS status VARCHAR
+(20) NOT NULL DEFAULT 'active';
@@ -1,14 +1,21 @@
 package main
 
 import (
-	"bytes"
-	"io"
+	"context"
 	"log"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/eaglebank/api-gateway/internal/proxy"
+	"github.com/eaglebank/shared/correlation"
+	"github.com/eaglebank/shared/health"
+	"github.com/eaglebank/shared/lifecycle"
+	"github.com/eaglebank/shared/logging"
 	"github.com/eaglebank/shared/middleware"
+	redisClient "github.com/eaglebank/shared/redis"
+	"github.com/eaglebank/shared/tracing"
 	"github.com/gin-gonic/gin"
 )
 
@@ -20,109 +27,215 @@ var (
 )
 
 func main() {
-	middleware.MustInitJWTSecret()
+	if err := logging.Setup("api-gateway"); err != nil {
+		log.Fatalf("Failed to set up logging: %v", err)
+	}
 
-	router := gin.Default()
-	router.Use(middleware.LoggingMiddleware())
+	middleware.MustInitJWKS()
+
+	shutdownTracing, err := tracing.Setup("api-gateway")
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	// Redis connection for the access token denylist and rate limits
+	redisAddr := getEnv("REDIS_ADDR", "localhost:6379")
+	redis, err := redisClient.NewClient(redisAddr, "", 0)
+	if err != nil {
+		log.Fatalf("Failed to connect to Redis: %v", err)
+	}
+
+	// Reject access tokens revoked by auth-service before they expire
+	middleware.UseTokenDenylist(middleware.NewRedisTokenDenylist(redis.Client))
 
-	// Health check
+	// Token-bucket rate limits, by client address or by the authenticated
+	// caller, loaded from RATE_LIMITS_FILE
+	rules, err := middleware.LoadRateLimitRules(getEnv("RATE_LIMITS_FILE", "config/ratelimits.json"))
+	if err != nil {
+		log.Fatalf("Failed to load rate limits: %v", err)
+	}
+	limiter, err := middleware.NewRateLimiter(middleware.NewRedisRateLimitStore(redis.Client), rules)
+	if err != nil {
+		log.Fatalf("Invalid rate limits: %v", err)
+	}
+	loginLimit := limiter.Limit("login")
+	transactionsLimit := limiter.Limit("transactions")
+	accountCreationLimit := limiter.Limit("account-creation")
+
+	router := gin.New()
+	router.Use(gin.Recovery())
+	// Only trust X-Forwarded-For from the proxies in TRUSTED_PROXIES (comma
+	// separated); otherwise the client address is the connection's. Services
+	// throttle logins by the address the gateway forwards.
+	var trustedProxies []string
+	if v := getEnv("TRUSTED_PROXIES", ""); v != "" {
+		trustedProxies = strings.Split(v, ",")
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+	router.Use(middleware.RequestIDMiddleware())
+	router.Use(middleware.LoggingMiddleware())
+	router.Use(middleware.TracingMiddleware())
+	router.Use(middleware.MetricsMiddleware())
+
+	// Upstreams share one connection pool. Each has its own timeout, set with
+	// <NAME>_SERVICE_TIMEOUT, and its own circuit breaker. Statement exports
+	// can take a while, so transaction-service gets longer by default.
+	transport := proxy.NewTransport()
+	authService := newUpstream("auth", authServiceURL, transport, 10*time.Second)
+	userService := newUpstream("user", userServiceURL, transport, 10*time.Second)
+	accountService := newUpstream("account", accountServiceURL, transport, 10*time.Second)
+	transactionService := newUpstream("transaction", transactionServiceURL, transport, 30*time.Second)
+	upstreams := []*proxy.Upstream{authService, userService, accountService, transactionService}
+
+	// Prometheus metrics
+	router.GET("/metrics", middleware.MetricsHandler())
+
+	// Health check, with the circuit breaker of each upstream
 	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "ok", "service": "api-gateway"})
+		status := "ok"
+		breakers := make(map[string]proxy.BreakerStats, len(upstreams))
+		for _, u := range upstreams {
+			stats := u.Stats()
+			if stats.State != proxy.StateClosed {
+				status = "degraded"
+			}
+			breakers[u.Name()] = stats
+		}
+		c.JSON(200, gin.H{"status": status, "service": "api-gateway", "upstreams": breakers})
 	})
 
+	// Liveness and readiness. The gateway is ready when Redis and every
+	// upstream are; upstream results are cached briefly so frequent probes
+	// do not fan out to every service.
+	checks := health.NewRegistry()
+	checks.AddReadiness("redis", health.Redis(redis.Client))
+	for _, u := range upstreams {
+		checks.AddReadiness(u.Name(), health.Cached(u, 5*time.Second))
+	}
+	checks.Register(router)
+
 	// Auth routes (no authentication required)
-	router.POST("/v1/auth/login", proxyTo(authServiceURL))
-	router.POST("/v1/auth/refresh", proxyTo(authServiceURL))
+	router.POST("/v1/auth/login", loginLimit, proxyTo(authService))
+	router.POST("/v1/auth/mfa/verify", loginLimit, proxyTo(authService))
+	router.POST("/v1/auth/refresh", proxyTo(authService))
+	router.POST("/v1/auth/logout", middleware.AuthMiddleware(), proxyTo(authService))
+	router.POST("/v1/auth/password-reset", loginLimit, proxyTo(authService))
+	router.POST("/v1/auth/password-reset/confirm", proxyTo(authService))
+	router.POST("/v1/auth/token", loginLimit, proxyTo(authService)) // API clients authenticate with their secret
+	router.GET("/.well-known/jwks.json", proxyTo(authService))
 
 	// User routes
-	router.POST("/v1/users", proxyTo(userServiceURL)) // No auth for registration
-	router.GET("/v1/users/:userId", middleware.AuthMiddleware(), proxyTo(userServiceURL))
-	router.PATCH("/v1/users/:userId", middleware.AuthMiddleware(), proxyTo(userServiceURL))
-	router.DELETE("/v1/users/:userId", middleware.AuthMiddleware(), proxyTo(userServiceURL))
+	router.POST("/v1/users", proxyTo(userService))             // No auth for registration
+	router.GET("/v1/users/verify-email", proxyTo(userService)) // The signed link is the credential
+	router.GET("/v1/users/:userId", middleware.AuthMiddleware(), proxyTo(userService))
+	router.PATCH("/v1/users/:userId", middleware.AuthMiddleware(), proxyTo(userService))
+	router.DELETE("/v1/users/:userId", middleware.AuthMiddleware(), proxyTo(userService))
+	router.PUT("/v1/users/:userId/password", middleware.AuthMiddleware(), proxyTo(userService))
+	router.POST("/v1/users/:userId/verification", middleware.AuthMiddleware(), proxyTo(userService))
+	router.POST("/v1/users/:userId/mfa", middleware.AuthMiddleware(), proxyTo(userService))
+	router.POST("/v1/users/:userId/mfa/confirm", middleware.AuthMiddleware(), proxyTo(userService))
 
 	// Account routes
-	router.POST("/v1/accounts", middleware.AuthMiddleware(), proxyTo(accountServiceURL))
-	router.GET("/v1/accounts", middleware.AuthMiddleware(), proxyTo(accountServiceURL))
-	router.GET("/v1/accounts/:accountNumber", middleware.AuthMiddleware(), proxyTo(accountServiceURL))
-	router.PATCH("/v1/accounts/:accountNumber", middleware.AuthMiddleware(), proxyTo(accountServiceURL))
-	router.DELETE("/v1/accounts/:accountNumber", middleware.AuthMiddleware(), proxyTo(accountServiceURL))
+	router.POST("/v1/accounts", middleware.AuthMiddleware(), accountCreationLimit, proxyTo(accountService))
+	router.GET("/v1/accounts", middleware.AuthMiddleware(), proxyTo(accountService))
+	router.GET("/v1/accounts/:accountNumber", middleware.AuthMiddleware(), proxyTo(accountService))
+	router.GET("/v1/accounts/:accountNumber/balance", middleware.AuthMiddleware(), proxyTo(accountService))
+	router.PATCH("/v1/accounts/:accountNumber", middleware.AuthMiddleware(), proxyTo(accountService))
+	router.DELETE("/v1/accounts/:accountNumber", middleware.AuthMiddleware(), proxyTo(accountService))
 
 	// Transaction routes
-	router.POST("/v1/accounts/:accountNumber/transactions", middleware.AuthMiddleware(), proxyTo(transactionServiceURL))
-	router.GET("/v1/accounts/:accountNumber/transactions", middleware.AuthMiddleware(), proxyTo(transactionServiceURL))
-	router.GET("/v1/accounts/:accountNumber/transactions/:transactionId", middleware.AuthMiddleware(), proxyTo(transactionServiceURL))
+	router.POST("/v1/accounts/:accountNumber/transactions", middleware.AuthMiddleware(), transactionsLimit, proxyTo(transactionService))
+	router.GET("/v1/accounts/:accountNumber/transactions", middleware.AuthMiddleware(), transactionsLimit, proxyTo(transactionService))
+	router.GET("/v1/accounts/:accountNumber/transactions/:transactionId", middleware.AuthMiddleware(), transactionsLimit, proxyTo(transactionService))
+	router.POST("/v1/accounts/:accountNumber/transfers", middleware.AuthMiddleware(), transactionsLimit, proxyTo(transactionService))
+	router.GET("/v1/accounts/:accountNumber/transfers/:transferId", middleware.AuthMiddleware(), transactionsLimit, proxyTo(transactionService))
+	router.GET("/v1/accounts/:accountNumber/statements", middleware.AuthMiddleware(), transactionsLimit, proxyTo(transactionService))
+
+	// Back-office routes for staff and API clients. Each is checked against
+	// the caller's scopes here and again, with an audit record, by the
+	// service.
+	staff := router.Group("/admin/v1", middleware.ScopedAuthMiddleware())
+	{
+		usersRead := middleware.RequireScope(middleware.ScopeUsersRead)
+		accountsRead := middleware.RequireScope(middleware.ScopeAccountsRead)
+		accountsFreeze := middleware.RequireScope(middleware.ScopeAccountsFreeze)
+		transactionsRead := middleware.RequireScope(middleware.ScopeTransactionsRead)
+		transactionsAdjust := middleware.RequireScope(middleware.ScopeTransactionsAdjust)
+		clientsManage := middleware.RequireScope(middleware.ScopeClientsManage)
+
+		staff.GET("/users", usersRead, proxyTo(userService))
+		staff.GET("/users/:userId", usersRead, proxyTo(userService))
+		staff.GET("/users/:userId/accounts", accountsRead, proxyTo(accountService))
+		staff.GET("/accounts/:accountNumber", accountsRead, proxyTo(accountService))
+		staff.GET("/accounts/:accountNumber/balance", accountsRead, proxyTo(accountService))
+		staff.POST("/accounts/:accountNumber/freeze", accountsFreeze, proxyTo(accountService))
+		staff.POST("/accounts/:accountNumber/unfreeze", accountsFreeze, proxyTo(accountService))
+		staff.GET("/accounts/:accountNumber/transactions", transactionsRead, proxyTo(transactionService))
+		staff.GET("/accounts/:accountNumber/transactions/:transactionId", transactionsRead, proxyTo(transactionService))
+		staff.GET("/accounts/:accountNumber/transfers/:transferId", transactionsRead, proxyTo(transactionService))
+		staff.GET("/accounts/:accountNumber/statements", transactionsRead, proxyTo(transactionService))
+		staff.POST("/accounts/:accountNumber/adjustments", transactionsAdjust, proxyTo(transactionService))
+		staff.POST("/clients", clientsManage, proxyTo(authService))
+		staff.GET("/clients", clientsManage, proxyTo(authService))
+		staff.DELETE("/clients/:clientId", clientsManage, proxyTo(authService))
+	}
 
+	// Serve until SIGINT or SIGTERM, giving proxied requests as long to
+	// finish as the slowest upstream's default timeout
 	port := getEnv("PORT", "8080")
+	app := lifecycle.New(lifecycle.Config{Addr: ":" + port, Handler: router, DrainTimeout: 30 * time.Second})
+	app.OnClose("Redis", redis.Close)
+
 	log.Printf("API Gateway starting on port %s", port)
-	if err := router.Run(":" + port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	if err := app.Run(); err != nil {
+		log.Fatalf("API Gateway stopped: %v", err)
 	}
 }
 
-func proxyTo(serviceURL string) gin.HandlerFunc {
+// proxyTo streams the request to upstream. The upstream sees the caller's
+// address as X-Forwarded-For and, once AuthMiddleware has run, who they are;
+// identity headers sent by the caller are dropped.
+func proxyTo(upstream *proxy.Upstream) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Build target URL
-		targetURL := serviceURL + c.Request.URL.Path
-		if c.Request.URL.RawQuery != "" {
-			targetURL += "?" + c.Request.URL.RawQuery
-		}
+		req := c.Request.Clone(proxy.WithClientIP(c.Request.Context(), c.ClientIP()))
+		req.Header.Del("X-User-ID")
+		req.Header.Del("X-User-Email")
+		req.Header.Del("X-Client-ID")
+		req.Header.Set(correlation.Header, middleware.GetRequestID(c))
 
-		// Read request body
-		var bodyBytes []byte
-		if c.Request.Body != nil {
-			bodyBytes, _ = io.ReadAll(c.Request.Body)
-			c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
-		}
-
-		// Create new request
-		req, err := http.NewRequest(c.Request.Method, targetURL, bytes.NewBuffer(bodyBytes))
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to create request"})
-			return
-		}
-
-		// Copy headers
-		for key, values := range c.Request.Header {
-			for _, value := range values {
-				req.Header.Add(key, value)
-			}
-		}
-
-		// Forward user context from JWT middleware if authenticated
+		// Forward user or client context from JWT middleware if authenticated
 		if userID, exists := c.Get("userId"); exists {
 			req.Header.Set("X-User-ID", userID.(string))
 		}
 		if email, exists := c.Get("email"); exists {
 			req.Header.Set("X-User-Email", email.(string))
 		}
-
-		// Make request
-		client := &http.Client{}
-		resp, err := client.Do(req)
-		if err != nil {
-			log.Printf("Error proxying request: %v", err)
-			c.JSON(http.StatusBadGateway, gin.H{"message": "Service unavailable"})
-			return
+		if clientID, exists := c.Get("clientId"); exists {
+			req.Header.Set("X-Client-ID", clientID.(string))
 		}
-		defer resp.Body.Close()
 
-		// Read response
-		respBody, err := io.ReadAll(resp.Body)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to read response"})
-			return
-		}
+		upstream.ServeHTTP(c.Writer, req)
+	}
+}
 
-		// Copy response headers
-		for key, values := range resp.Header {
-			for _, value := range values {
-				c.Header(key, value)
-			}
+func newUpstream(name, url string, transport *http.Transport, defaultTimeout time.Duration) *proxy.Upstream {
+	config := proxy.DefaultConfig()
+	config.Timeout = defaultTimeout
+	if v := getEnv(strings.ToUpper(name)+"_SERVICE_TIMEOUT", ""); v != "" {
+		timeout, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("Invalid %s_SERVICE_TIMEOUT: %v", strings.ToUpper(name), err)
 		}
-
-		// Forward response
-		c.Data(resp.StatusCode, resp.Header.Get("Content-Type"), respBody)
+		config.Timeout = timeout
+	}
+	upstream, err := proxy.New(name, url, transport, config)
+	if err != nil {
+		log.Fatalf("Invalid %s service URL: %v", name, err)
 	}
+	return upstream
 }
 
 func getEnv(key, fallback string) string {
@@ -0,0 +1,118 @@
+package proxy
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Breaker states.
+const (
+	StateClosed   = "closed"
+	StateOpen     = "open"
+	StateHalfOpen = "half_open"
+)
+
+// OpenError is returned instead of calling an upstream whose breaker is open.
+type OpenError struct {
+	Upstream   string
+	RetryAfter time.Duration
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("circuit open for %s", e.Upstream)
+}
+
+// BreakerStats is a snapshot of a breaker, as reported on /health.
+type BreakerStats struct {
+	State    string     `json:"state"`
+	Failures int        `json:"consecutiveFailures"`
+	OpenedAt *time.Time `json:"openedTimestamp,omitempty"`
+}
+
+// Breaker is a consecutive-failure circuit breaker. After threshold failures
+// in a row it opens and refuses every call for openFor. It then lets a single
+// probe through (half-open): success closes it, failure opens it again.
+type Breaker struct {
+	name      string
+	threshold int
+	openFor   time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func NewBreaker(name string, threshold int, openFor time.Duration) *Breaker {
+	return &Breaker{name: name, threshold: threshold, openFor: openFor, now: time.Now, state: StateClosed}
+}
+
+// Allow reports whether a call may proceed, returning an *OpenError if not.
+// Every allowed call must be followed by Success, Failure or Cancel.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		wait := b.openedAt.Add(b.openFor).Sub(b.now())
+		if wait > 0 {
+			return &OpenError{Upstream: b.name, RetryAfter: wait}
+		}
+		b.state = StateHalfOpen
+		b.probing = true
+		return nil
+	case StateHalfOpen:
+		if b.probing {
+			return &OpenError{Upstream: b.name, RetryAfter: time.Second}
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// Success records a call the upstream answered properly.
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = StateClosed
+	b.failures = 0
+	b.probing = false
+}
+
+// Failure records a call the upstream failed, opening the breaker if it was
+// the probe or the threshold is reached.
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.probing = false
+	if b.state == StateHalfOpen || b.failures >= b.threshold {
+		b.state = StateOpen
+		b.openedAt = b.now()
+	}
+}
+
+// Cancel records a call abandoned by the client, which says nothing about
+// the upstream.
+func (b *Breaker) Cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *Breaker) Stats() BreakerStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stats := BreakerStats{State: b.state, Failures: b.failures}
+	if b.state != StateClosed {
+		openedAt := b.openedAt
+		stats.OpenedAt = &openedAt
+	}
+	return stats
+}
@@ -0,0 +1,59 @@
+package proxy
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	b := NewBreaker("account", 3, 30*time.Second)
+	b.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if err := b.Allow(); err != nil {
+			t.Fatalf("expected closed breaker to allow, got %v", err)
+		}
+		b.Failure()
+	}
+	if err := b.Allow(); err != nil {
+		t.Fatalf("expected breaker below threshold to allow, got %v", err)
+	}
+	b.Success()
+	if got := b.Stats(); got.State != StateClosed || got.Failures != 0 {
+		t.Fatalf("expected success to reset failures, got %+v", got)
+	}
+
+	for i := 0; i < 3; i++ {
+		b.Allow()
+		b.Failure()
+	}
+	var open *OpenError
+	if err := b.Allow(); !errors.As(err, &open) || open.RetryAfter != 30*time.Second {
+		t.Fatalf("expected open breaker to refuse for 30s, got %v", err)
+	}
+
+	now = now.Add(30 * time.Second)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("expected a probe after the open period, got %v", err)
+	}
+	if err := b.Allow(); !errors.As(err, &open) {
+		t.Fatalf("expected only one probe while half-open, got %v", err)
+	}
+	b.Failure()
+	if got := b.Stats(); got.State != StateOpen {
+		t.Fatalf("expected failed probe to reopen, got %+v", got)
+	}
+
+	now = now.Add(30 * time.Second)
+	b.Allow()
+	b.Cancel()
+	if err := b.Allow(); err != nil {
+		t.Fatalf("expected a cancelled probe to free the slot, got %v", err)
+	}
+	b.Success()
+	if got := b.Stats(); got.State != StateClosed || got.OpenedAt != nil {
+		t.Fatalf("expected successful probe to close, got %+v", got)
+	}
+}
@@ -0,0 +1,251 @@
+// Package proxy forwards gateway requests to the backend services. Requests
+// and responses are streamed rather than buffered, every upstream shares one
+// pooled transport, and each upstream has its own timeout, retry policy and
+// circuit breaker.
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/eaglebank/shared/correlation"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// Config tunes one upstream.
+type Config struct {
+	// Timeout bounds a whole proxied request: every attempt and streaming
+	// the response back to the client.
+	Timeout time.Duration
+	// MaxRetries is how many times a failed idempotent request without a
+	// body is tried again, waiting RetryBackoff, then twice that, and so on,
+	// with jitter.
+	MaxRetries   int
+	RetryBackoff time.Duration
+	// FailureThreshold consecutive failures open the breaker for OpenFor.
+	FailureThreshold int
+	OpenFor          time.Duration
+}
+
+// DefaultConfig returns the settings used unless a service overrides them.
+func DefaultConfig() Config {
+	return Config{
+		Timeout:          10 * time.Second,
+		MaxRetries:       2,
+		RetryBackoff:     100 * time.Millisecond,
+		FailureThreshold: 5,
+		OpenFor:          30 * time.Second,
+	}
+}
+
+// NewTransport returns the connection pool shared by every upstream.
+func NewTransport() *http.Transport {
+	return &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout:   5 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   32,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ExpectContinueTimeout: time.Second,
+		ForceAttemptHTTP2:     true,
+	}
+}
+
+// Upstream is a backend service the gateway proxies to.
+type Upstream struct {
+	name    string
+	target  *url.URL
+	config  Config
+	breaker *Breaker
+	proxy   *httputil.ReverseProxy
+	// probe sends readiness checks, outside the breaker and retries.
+	probe *http.Client
+}
+
+// New creates the upstream called name at rawURL, sending requests through
+// transport. Each attempt is a client span carrying the trace context on to
+// the upstream.
+func New(name, rawURL string, transport http.RoundTripper, config Config) (*Upstream, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	u := &Upstream{
+		name:    name,
+		target:  target,
+		config:  config,
+		breaker: NewBreaker(name, config.FailureThreshold, config.OpenFor),
+		probe:   &http.Client{Transport: transport},
+	}
+	u.proxy = &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(target)
+			if ip, ok := r.In.Context().Value(clientIPKey{}).(string); ok {
+				r.Out.Header.Set("X-Forwarded-For", ip)
+			}
+		},
+		// The gateway has already set the request ID on the response; the
+		// upstream's echo of it would repeat the header.
+		ModifyResponse: func(resp *http.Response) error {
+			resp.Header.Del(correlation.Header)
+			return nil
+		},
+		Transport: &retryTransport{
+			next: otelhttp.NewTransport(transport, otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+				return name + " " + r.Method
+			})),
+			upstream: u,
+		},
+		ErrorHandler: u.handleError,
+	}
+	return u, nil
+}
+
+func (u *Upstream) Name() string { return u.name }
+
+// Stats returns the state of the upstream's circuit breaker.
+func (u *Upstream) Stats() BreakerStats { return u.breaker.Stats() }
+
+type clientIPKey struct{}
+
+// WithClientIP records the caller's address, which the upstream receives as
+// X-Forwarded-For in place of any chain the caller sent.
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// ServeHTTP proxies r to the upstream within its timeout. Failures are
+// answered with 502, a timeout with 504, and an open breaker with 503 and
+// Retry-After.
+func (u *Upstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), u.config.Timeout)
+	defer cancel()
+	defer func() {
+		if err := recover(); err != nil {
+			if err != http.ErrAbortHandler {
+				panic(err)
+			}
+			// The upstream failed mid-response; drop the connection so the
+			// client cannot mistake the truncated body for a complete one.
+			slog.WarnContext(r.Context(), "Upstream response truncated", "upstream", u.name)
+			abortConnection(w)
+		}
+	}()
+	u.proxy.ServeHTTP(w, r.WithContext(ctx))
+}
+
+func abortConnection(w http.ResponseWriter) {
+	if hj, ok := w.(http.Hijacker); ok {
+		if conn, _, err := hj.Hijack(); err == nil {
+			conn.Close()
+		}
+	}
+}
+
+func (u *Upstream) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	var open *OpenError
+	switch {
+	case errors.As(err, &open):
+		seconds := int64(math.Ceil(open.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+		writeError(w, http.StatusServiceUnavailable, "Service temporarily unavailable")
+	case errors.Is(r.Context().Err(), context.Canceled):
+		// The client went away; there is no one to answer.
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(r.Context().Err(), context.DeadlineExceeded):
+		slog.WarnContext(r.Context(), "Timed out proxying request", "upstream", u.name)
+		writeError(w, http.StatusGatewayTimeout, "Service timed out")
+	default:
+		slog.ErrorContext(r.Context(), "Error proxying request", "upstream", u.name, "err", err)
+		writeError(w, http.StatusBadGateway, "Service unavailable")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+// retryTransport makes each attempt through the upstream's breaker and
+// retries idempotent requests that have no body, since only those can be
+// replayed safely. A failure is a transport error, a timeout, or a 502, 503
+// or 504 from the upstream.
+type retryTransport struct {
+	next     http.RoundTripper
+	upstream *Upstream
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	breaker := t.upstream.breaker
+	config := t.upstream.config
+	retryable := idempotent(req.Method) && (req.Body == nil || req.Body == http.NoBody)
+
+	for attempt := 0; ; attempt++ {
+		if err := breaker.Allow(); err != nil {
+			return nil, err
+		}
+		resp, err := t.next.RoundTrip(req)
+		if err == nil && !unavailable(resp.StatusCode) {
+			breaker.Success()
+			return resp, nil
+		}
+		if errors.Is(req.Context().Err(), context.Canceled) {
+			breaker.Cancel()
+			return resp, err
+		}
+		breaker.Failure()
+		if !retryable || attempt >= config.MaxRetries || req.Context().Err() != nil {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+		if err := sleep(req.Context(), backoff(config.RetryBackoff, attempt)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func unavailable(status int) bool {
+	return status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+// backoff is base doubled per attempt, plus up to half again as jitter.
+func backoff(base time.Duration, attempt int) time.Duration {
+	d := base << attempt
+	return d + time.Duration(rand.Int63n(int64(d)/2+1))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
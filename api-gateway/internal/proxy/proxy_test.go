@@ -0,0 +1,164 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eaglebank/shared/health"
+)
+
+func testConfig() Config {
+	return Config{
+		Timeout:          time.Second,
+		MaxRetries:       2,
+		RetryBackoff:     time.Millisecond,
+		FailureThreshold: 3,
+		OpenFor:          time.Minute,
+	}
+}
+
+// newTestUpstream proxies to a server answering with statuses in turn, then
+// 200 once they run out. It returns the upstream and a count of the calls
+// the server received.
+func newTestUpstream(t *testing.T, config Config, statuses ...int) (*Upstream, *int32) {
+	t.Helper()
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		if r.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		if int(n) <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		w.Header().Set("X-Seen-Forwarded-For", r.Header.Get("X-Forwarded-For"))
+		w.Write([]byte(r.Method + " " + r.URL.RequestURI()))
+	}))
+	t.Cleanup(server.Close)
+	upstream, err := New("test", server.URL, NewTransport(), config)
+	if err != nil {
+		t.Fatalf("failed to create upstream: %v", err)
+	}
+	return upstream, &calls
+}
+
+func TestUpstreamServeHTTP(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		statuses       []int
+		timeout        time.Duration
+		expectedStatus int
+		expectedCalls  int32
+	}{
+		{name: "success - forwarded with path and query", method: http.MethodGet, path: "/v1/accounts?limit=5", expectedStatus: http.StatusOK, expectedCalls: 1},
+		{name: "retried - GET recovers after a 503", method: http.MethodGet, path: "/v1/accounts", statuses: []int{503}, expectedStatus: http.StatusOK, expectedCalls: 2},
+		{name: "retried - GET gives up after two retries", method: http.MethodGet, path: "/v1/accounts", statuses: []int{503, 502, 504}, expectedStatus: http.StatusGatewayTimeout, expectedCalls: 3},
+		{name: "not retried - POST", method: http.MethodPost, path: "/v1/accounts", body: `{"name":"x"}`, statuses: []int{503}, expectedStatus: http.StatusServiceUnavailable, expectedCalls: 1},
+		{name: "not retried - client error", method: http.MethodGet, path: "/v1/accounts", statuses: []int{404}, expectedStatus: http.StatusNotFound, expectedCalls: 1},
+		{name: "timeout - answered with 504", method: http.MethodGet, path: "/slow", timeout: 50 * time.Millisecond, expectedStatus: http.StatusGatewayTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig()
+			if tt.timeout != 0 {
+				config.Timeout = tt.timeout
+				config.MaxRetries = 0
+			}
+			upstream, calls := newTestUpstream(t, config, tt.statuses...)
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("X-Forwarded-For", "6.6.6.6")
+			req = req.WithContext(WithClientIP(req.Context(), "203.0.113.7"))
+			w := httptest.NewRecorder()
+			upstream.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("[%s] expected %d got %d; body: %s", tt.name, tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedCalls != 0 && atomic.LoadInt32(calls) != tt.expectedCalls {
+				t.Errorf("[%s] expected %d upstream calls got %d", tt.name, tt.expectedCalls, atomic.LoadInt32(calls))
+			}
+			if w.Code == http.StatusOK {
+				if got := w.Body.String(); got != tt.method+" "+tt.path {
+					t.Errorf("[%s] upstream saw %q", tt.name, got)
+				}
+				if got := w.Header().Get("X-Seen-Forwarded-For"); got != "203.0.113.7" {
+					t.Errorf("[%s] expected the client IP as X-Forwarded-For, got %q", tt.name, got)
+				}
+			}
+		})
+	}
+}
+
+func TestUpstreamCircuitBreaker(t *testing.T) {
+	config := testConfig()
+	config.MaxRetries = 0
+	upstream, calls := newTestUpstream(t, config, 503, 503, 503)
+
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		upstream.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/accounts", nil))
+		if w.Code != http.StatusServiceUnavailable {
+			t.Fatalf("expected the upstream's 503, got %d", w.Code)
+		}
+	}
+	if got := upstream.Stats(); got.State != StateOpen || got.Failures != 3 {
+		t.Fatalf("expected the breaker to open after 3 failures, got %+v", got)
+	}
+
+	w := httptest.NewRecorder()
+	upstream.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/accounts", nil))
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "60" {
+		t.Errorf("expected a fast 503 with Retry-After 60, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	if got := atomic.LoadInt32(calls); got != 3 {
+		t.Errorf("expected the open breaker to spare the upstream, got %d calls", got)
+	}
+}
+
+func TestUpstreamCheck(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         int
+		body           string
+		expectedStatus string
+		expectedChecks int
+	}{
+		{name: "ready", status: http.StatusOK, body: `{"status":"ok","checks":{"postgres":{"status":"ok"},"redis":{"status":"ok"}}}`, expectedStatus: health.StatusOK, expectedChecks: 2},
+		{name: "not ready - components reported", status: http.StatusServiceUnavailable, body: `{"status":"down","checks":{"postgres":{"status":"down","error":"connection refused"}}}`, expectedStatus: health.StatusDown, expectedChecks: 1},
+		{name: "error status without a report", status: http.StatusBadGateway, body: "bad gateway", expectedStatus: health.StatusDown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/readyz" {
+					t.Errorf("[%s] expected a probe of /readyz, got %s", tt.name, r.URL.Path)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+			upstream, err := New("test", server.URL, NewTransport(), testConfig())
+			if err != nil {
+				t.Fatalf("failed to create upstream: %v", err)
+			}
+
+			result := upstream.Check(context.Background())
+			if result.Status != tt.expectedStatus || len(result.Checks) != tt.expectedChecks {
+				t.Errorf("[%s] expected %s with %d checks, got %+v", tt.name, tt.expectedStatus, tt.expectedChecks, result)
+			}
+			if upstream.Stats().State != StateClosed {
+				t.Errorf("[%s] expected probes to leave the breaker closed", tt.name)
+			}
+		})
+	}
+}
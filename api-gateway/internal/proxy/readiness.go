@@ -0,0 +1,35 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/eaglebank/shared/health"
+)
+
+// Check asks the upstream's /readyz whether it is ready, reporting each of
+// its components under Checks. Probes bypass the breaker and retries, so
+// they see the service as it is and do not count towards tripping it.
+func (u *Upstream) Check(ctx context.Context) health.Result {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.target.JoinPath("/readyz").String(), nil)
+	if err != nil {
+		return health.Result{Status: health.StatusDown, Error: err.Error()}
+	}
+	resp, err := u.probe.Do(req)
+	if err != nil {
+		return health.Result{Status: health.StatusDown, Error: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	var result health.Result
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil {
+		return health.Result{Status: health.StatusDown, Error: fmt.Sprintf("unreadable readiness response (status %d)", resp.StatusCode)}
+	}
+	if resp.StatusCode != http.StatusOK {
+		result.Status = health.StatusDown
+	}
+	return result
+}
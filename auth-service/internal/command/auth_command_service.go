@@ -0,0 +1,526 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/eaglebank/auth-service/internal/repository"
+	"github.com/eaglebank/shared/cqrs"
+	"github.com/eaglebank/shared/events"
+	"github.com/eaglebank/shared/jwks"
+	"github.com/eaglebank/shared/middleware"
+	"github.com/eaglebank/shared/models"
+	"github.com/eaglebank/shared/notify"
+	"github.com/eaglebank/shared/secretbox"
+	"github.com/eaglebank/shared/totp"
+	"github.com/eaglebank/shared/utils"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// accessTokenTTL is kept short because a leaked access token can only be
+	// stopped early through the denylist.
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// Failed logins are counted per email address and per client IP over
+// loginFailureWindow. Past a free allowance each further failure doubles the
+// wait before the next attempt, up to maxLoginDelay. An IP is allowed more
+// failures than an email because many users may share it. An account that
+// reaches lockoutThreshold failures is locked for lockoutDuration.
+const (
+	loginFailureWindow = 15 * time.Minute
+	emailFreeFailures  = 3
+	ipFreeFailures     = 20
+	maxLoginDelay      = 30 * time.Second
+	lockoutThreshold   = 10
+	lockoutDuration    = 30 * time.Minute
+)
+
+const (
+	passwordResetTTL = 30 * time.Minute
+	// passwordResetInterval is the minimum time between reset emails to one
+	// address.
+	passwordResetInterval = time.Minute
+)
+
+// ThrottledError is returned by Login when the attempt is refused without
+// checking the password: "too many login attempts" or "account locked".
+type ThrottledError struct {
+	Reason string
+	Wait   time.Duration
+}
+
+func (e *ThrottledError) Error() string { return e.Reason }
+
+// RetryAfter is how long the caller should wait before trying again.
+func (e *ThrottledError) RetryAfter() time.Duration { return e.Wait }
+
+// loginDelay is the wait imposed after the given number of failures.
+func loginDelay(failures, free int64) time.Duration {
+	over := failures - free
+	if over <= 0 {
+		return 0
+	}
+	if over > 5 {
+		return maxLoginDelay
+	}
+	if d := time.Second << (over - 1); d < maxLoginDelay {
+		return d
+	}
+	return maxLoginDelay
+}
+
+// Claims is the JWT payload, matching middleware.Claims. Roles are the
+// user's staff roles and Scope the space-separated scopes they grant. Tokens
+// issued to an API client carry its ClientID and scopes instead of a user.
+type Claims struct {
+	UserID   string   `json:"userId,omitempty"`
+	ClientID string   `json:"clientId,omitempty"`
+	Email    string   `json:"email,omitempty"`
+	Roles    []string `json:"roles,omitempty"`
+	Scope    string   `json:"scope,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// AuthCommandService issues and revokes tokens. Access tokens are JWTs
+// identified by a jti and signed with the active key of keys; refresh tokens
+// are opaque, stored hashed, and rotated on every use. Presenting a refresh
+// token that was already rotated means it was copied, so the whole family it
+// belongs to is revoked.
+//
+// Users with MFA enabled get a short-lived challenge from Login instead of
+// tokens, and exchange it for tokens through VerifyMFA.
+type AuthCommandService struct {
+	userRepo      *repository.UserRepository
+	refreshRepo   *repository.RefreshTokenRepository
+	challengeRepo *repository.MFAChallengeRepository
+	throttle      *repository.LoginThrottleRepository
+	resetRepo     *repository.PasswordResetRepository
+	denylist      middleware.TokenDenylist
+	keys          *jwks.KeySet
+	mfaBox        *secretbox.Box
+	notifier      notify.Notifier
+	// resetURL is the page that accepts a reset token in its token parameter.
+	resetURL string
+}
+
+func NewAuthCommandService(
+	userRepo *repository.UserRepository,
+	refreshRepo *repository.RefreshTokenRepository,
+	challengeRepo *repository.MFAChallengeRepository,
+	throttle *repository.LoginThrottleRepository,
+	resetRepo *repository.PasswordResetRepository,
+	denylist middleware.TokenDenylist,
+	keys *jwks.KeySet,
+	mfaBox *secretbox.Box,
+	notifier notify.Notifier,
+	resetURL string,
+) *AuthCommandService {
+	return &AuthCommandService{
+		userRepo:      userRepo,
+		refreshRepo:   refreshRepo,
+		challengeRepo: challengeRepo,
+		throttle:      throttle,
+		resetRepo:     resetRepo,
+		denylist:      denylist,
+		keys:          keys,
+		mfaBox:        mfaBox,
+		notifier:      notifier,
+		resetURL:      resetURL,
+	}
+}
+
+func (s *AuthCommandService) Login(ctx context.Context, cmd cqrs.LoginCommand) (*models.LoginResult, error) {
+	scopes := loginScopes(cmd.Email, cmd.IP)
+	if err := s.checkThrottle(ctx, scopes); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByEmail(cmd.Email)
+	if err != nil {
+		if err.Error() != "user not found" {
+			return nil, err
+		}
+		s.loginFailed(ctx, nil, cmd.IP, scopes)
+		return nil, fmt.Errorf("invalid credentials")
+	}
+	if user.LockedUntil != nil && user.LockedUntil.After(time.Now()) {
+		return nil, &ThrottledError{Reason: "account locked", Wait: time.Until(*user.LockedUntil)}
+	}
+	if !utils.CheckPassword(cmd.Password, user.PasswordHash) {
+		s.loginFailed(ctx, user, cmd.IP, scopes)
+		return nil, fmt.Errorf("invalid credentials")
+	}
+
+	mfa, err := s.userRepo.GetMFA(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	// With MFA the password alone is not a successful login, so failures
+	// keep counting until VerifyMFA accepts a code.
+	if mfa != nil {
+		token, err := utils.GenerateToken()
+		if err != nil {
+			return nil, err
+		}
+		if err := s.challengeRepo.Create(ctx, utils.HashToken(token), user.ID); err != nil {
+			return nil, err
+		}
+		return &models.LoginResult{MFAToken: token, MFAExpiresIn: s.challengeRepo.TTL()}, nil
+	}
+
+	s.loginSucceeded(ctx, user, scopes)
+	pair, err := s.startSession(user)
+	if err != nil {
+		return nil, err
+	}
+	return &models.LoginResult{Tokens: pair}, nil
+}
+
+// loginScopes are the throttle scopes for a login attempt: the email, then
+// the IP if known.
+func loginScopes(email, ip string) []string {
+	scopes := []string{"email:" + strings.ToLower(email)}
+	if ip != "" {
+		scopes = append(scopes, "ip:"+ip)
+	}
+	return scopes
+}
+
+// checkThrottle refuses an attempt while any of scopes is being delayed.
+func (s *AuthCommandService) checkThrottle(ctx context.Context, scopes []string) error {
+	wait, err := s.throttle.Wait(ctx, scopes...)
+	if err != nil {
+		return err
+	}
+	if wait > 0 {
+		return &ThrottledError{Reason: "too many login attempts", Wait: wait}
+	}
+	return nil
+}
+
+// loginSucceeded clears the failures counted against the email once the
+// user has fully authenticated.
+func (s *AuthCommandService) loginSucceeded(ctx context.Context, user *models.User, scopes []string) {
+	if err := s.throttle.Reset(ctx, scopes[0]); err != nil {
+		slog.ErrorContext(ctx, "Failed to reset login failures", "user_id", user.ID, "err", err)
+	}
+}
+
+// loginFailed counts a failed login against each scope (email first, then
+// IP), delays the next attempt, and locks user once its email reaches the
+// lockout threshold. Errors are logged rather than returned so the caller
+// still sees "invalid credentials".
+func (s *AuthCommandService) loginFailed(ctx context.Context, user *models.User, ip string, scopes []string) {
+	for i, scope := range scopes {
+		free := int64(emailFreeFailures)
+		if i > 0 {
+			free = ipFreeFailures
+		}
+		failures, err := s.throttle.Fail(ctx, scope, loginFailureWindow)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to record login failure", "err", err)
+			return
+		}
+		if d := loginDelay(failures, free); d > 0 {
+			if err := s.throttle.Delay(ctx, scope, d); err != nil {
+				slog.ErrorContext(ctx, "Failed to delay login", "err", err)
+			}
+		}
+		if i == 0 && user != nil && failures >= lockoutThreshold {
+			s.lock(ctx, user, failures, ip, scope)
+		}
+	}
+}
+
+// lock locks user after too many failures and starts its count afresh, so
+// the lock is not reapplied the moment it lifts.
+func (s *AuthCommandService) lock(ctx context.Context, user *models.User, failures int64, ip, scope string) {
+	locked, err := s.userRepo.Lock(ctx, events.UserLockedEvent{
+		UserID:      user.ID,
+		Email:       user.Email,
+		Failures:    failures,
+		IP:          ip,
+		LockedUntil: time.Now().UTC().Add(lockoutDuration),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to lock user", "user_id", user.ID, "err", err)
+		return
+	}
+	if locked {
+		slog.WarnContext(ctx, "Locked user after repeated failed logins", "user_id", user.ID, "failures", failures)
+		if err := s.throttle.Reset(ctx, scope); err != nil {
+			slog.ErrorContext(ctx, "Failed to reset login failures", "user_id", user.ID, "err", err)
+		}
+	}
+}
+
+// UnlockExpired lifts locks that have run out, recording each unlock.
+func (s *AuthCommandService) UnlockExpired() (int, error) {
+	return s.userRepo.UnlockExpired()
+}
+
+// VerifyMFA answers a login challenge with a TOTP code or an unused recovery
+// code. Each wrong code counts against the challenge and, like a wrong
+// password, against the user's login failures, so new challenges cannot be
+// used to keep guessing past the lockout.
+func (s *AuthCommandService) VerifyMFA(ctx context.Context, cmd cqrs.VerifyMFACommand) (*models.TokenPair, error) {
+	challenge := utils.HashToken(cmd.MFAToken)
+	userID, err := s.challengeRepo.Get(ctx, challenge)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mfa token")
+	}
+	scopes := loginScopes(user.Email, cmd.IP)
+	if err := s.checkThrottle(ctx, scopes); err != nil {
+		return nil, err
+	}
+	if user.LockedUntil != nil && user.LockedUntil.After(time.Now()) {
+		return nil, &ThrottledError{Reason: "account locked", Wait: time.Until(*user.LockedUntil)}
+	}
+
+	ok, err := s.checkMFACode(userID, cmd.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if err := s.challengeRepo.Fail(ctx, challenge); err != nil {
+			slog.ErrorContext(ctx, "Failed to record MFA attempt", "user_id", userID, "err", err)
+		}
+		s.loginFailed(ctx, user, cmd.IP, scopes)
+		return nil, fmt.Errorf("invalid mfa code")
+	}
+
+	if err := s.challengeRepo.Delete(ctx, challenge); err != nil {
+		slog.ErrorContext(ctx, "Failed to delete MFA challenge", "user_id", userID, "err", err)
+	}
+	s.loginSucceeded(ctx, user, scopes)
+	return s.startSession(user)
+}
+
+// checkMFACode accepts a TOTP code that has not been used before or an
+// unused recovery code, spending it.
+func (s *AuthCommandService) checkMFACode(userID, code string) (bool, error) {
+	mfa, err := s.userRepo.GetMFA(userID)
+	if err != nil {
+		return false, err
+	}
+	if mfa == nil {
+		return false, fmt.Errorf("invalid mfa token")
+	}
+
+	if len(code) == totp.Digits {
+		secret, err := s.mfaBox.Open(mfa.SecretCiphertext, userID)
+		if err != nil {
+			return false, fmt.Errorf("failed to decrypt mfa secret: %w", err)
+		}
+		step, ok := totp.Validate(secret, code, time.Now(), mfa.LastUsedStep)
+		if !ok {
+			return false, nil
+		}
+		return s.userRepo.UseTOTPStep(userID, step)
+	}
+	return s.userRepo.UseRecoveryCode(userID, utils.HashToken(totp.NormalizeRecoveryCode(code)))
+}
+
+// startSession issues tokens in a new refresh token family.
+func (s *AuthCommandService) startSession(user *models.User) (*models.TokenPair, error) {
+	pair, record, err := s.issue(user)
+	if err != nil {
+		return nil, err
+	}
+	record.UserID = user.ID
+	record.FamilyID = utils.GenerateID("rtf")
+	if err := s.refreshRepo.Create(record); err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// RefreshToken rotates the presented refresh token and returns a new pair.
+func (s *AuthCommandService) RefreshToken(ctx context.Context, cmd cqrs.RefreshTokenCommand) (*models.TokenPair, error) {
+	current, err := s.refreshRepo.GetByHash(utils.HashToken(cmd.RefreshToken))
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.GetByID(current.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid refresh token")
+	}
+
+	pair, next, err := s.issue(user)
+	if err != nil {
+		return nil, err
+	}
+	current, err = s.refreshRepo.Rotate(current.TokenHash, next)
+	if err != nil {
+		if err.Error() == "refresh token reuse detected" {
+			slog.WarnContext(ctx, "Refresh token reuse detected; revoking its family", "user_id", current.UserID, "family_id", current.FamilyID)
+			if revokeErr := s.revokeFamily(ctx, current.FamilyID); revokeErr != nil {
+				slog.ErrorContext(ctx, "Failed to revoke refresh token family", "family_id", current.FamilyID, "err", revokeErr)
+			}
+		}
+		return nil, err
+	}
+	return pair, nil
+}
+
+// Logout revokes the refresh token's family and the caller's access token.
+// A refresh token that is unknown or belongs to another user is ignored, so
+// logout cannot be used to probe for valid tokens.
+func (s *AuthCommandService) Logout(ctx context.Context, cmd cqrs.LogoutCommand) error {
+	if err := s.denylist.Revoke(ctx, cmd.AccessTokenID, cmd.AccessTokenExpiresAt); err != nil {
+		return err
+	}
+	current, err := s.refreshRepo.GetByHash(utils.HashToken(cmd.RefreshToken))
+	if err != nil {
+		if err.Error() == "invalid refresh token" {
+			return nil
+		}
+		return err
+	}
+	if current.UserID != cmd.UserID {
+		return nil
+	}
+	return s.revokeFamily(ctx, current.FamilyID)
+}
+
+// RequestPasswordReset emails a single-use reset link to the user with the
+// given email. It succeeds without sending anything if there is no such user
+// or a link was sent within passwordResetInterval, so callers cannot tell
+// which addresses are registered.
+func (s *AuthCommandService) RequestPasswordReset(ctx context.Context, cmd cqrs.RequestPasswordResetCommand) error {
+	scope := "reset:" + strings.ToLower(cmd.Email)
+	wait, err := s.throttle.Wait(ctx, scope)
+	if err != nil {
+		return err
+	}
+	if wait > 0 {
+		return nil
+	}
+	user, err := s.userRepo.GetByEmail(cmd.Email)
+	if err != nil {
+		if err.Error() == "user not found" {
+			return nil
+		}
+		return err
+	}
+	if err := s.throttle.Delay(ctx, scope, passwordResetInterval); err != nil {
+		return err
+	}
+
+	token, err := utils.GenerateToken()
+	if err != nil {
+		return err
+	}
+	err = s.resetRepo.Create(&repository.PasswordResetToken{
+		ID:        utils.GenerateID("prt"),
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().UTC().Add(passwordResetTTL),
+	})
+	if err != nil {
+		return err
+	}
+	return s.notifier.Send(ctx, notify.Message{
+		To:      user.Email,
+		Subject: "Reset your Eagle Bank password",
+		Body: fmt.Sprintf("Use this link within %d minutes to choose a new password:\n\n%s?token=%s\n\n"+
+			"If you did not ask to reset your password, you can ignore this email.\n",
+			int(passwordResetTTL.Minutes()), s.resetURL, token),
+	})
+}
+
+// ConfirmPasswordReset sets a new password with a reset token. It lifts any
+// login lock and signs out every session, since whoever held them may be the
+// reason for the reset.
+func (s *AuthCommandService) ConfirmPasswordReset(ctx context.Context, cmd cqrs.ConfirmPasswordResetCommand) error {
+	passwordHash, err := utils.HashPassword(cmd.NewPassword)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	userID, revoked, err := s.resetRepo.Reset(ctx, utils.HashToken(cmd.Token), passwordHash)
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "Password reset; refresh tokens revoked", "user_id", userID, "revoked", len(revoked))
+	return s.denyAccessTokens(ctx, revoked)
+}
+
+// revokeFamily revokes every refresh token in the family and denies the
+// access tokens issued with them that have not yet expired.
+func (s *AuthCommandService) revokeFamily(ctx context.Context, familyID string) error {
+	tokens, err := s.refreshRepo.RevokeFamily(familyID)
+	if err != nil {
+		return err
+	}
+	return s.denyAccessTokens(ctx, tokens)
+}
+
+// denyAccessTokens denies the access tokens issued with the given refresh
+// tokens.
+func (s *AuthCommandService) denyAccessTokens(ctx context.Context, tokens []repository.RefreshToken) error {
+	for _, t := range tokens {
+		if err := s.denylist.Revoke(ctx, t.AccessTokenID, t.AccessExpiresAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// issue mints an access token carrying the user's current roles and a
+// refresh token, returning the pair for the client and the refresh token
+// record to store. The caller sets the record's user and family.
+func (s *AuthCommandService) issue(user *models.User) (*models.TokenPair, *repository.RefreshToken, error) {
+	roles, err := s.userRepo.GetRoles(user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now().UTC()
+	jti := utils.GenerateID("jti")
+	accessExpiresAt := now.Add(accessTokenTTL)
+	accessToken, err := s.generateToken(user, roles, jti, now, accessExpiresAt)
+	if err != nil {
+		return nil, nil, err
+	}
+	refreshToken, err := utils.GenerateToken()
+	if err != nil {
+		return nil, nil, err
+	}
+	record := &repository.RefreshToken{
+		ID:              utils.GenerateID("rtk"),
+		TokenHash:       utils.HashToken(refreshToken),
+		AccessTokenID:   jti,
+		AccessExpiresAt: accessExpiresAt,
+		ExpiresAt:       now.Add(refreshTokenTTL),
+	}
+	pair := &models.TokenPair{AccessToken: accessToken, RefreshToken: refreshToken, ExpiresIn: accessTokenTTL}
+	return pair, record, nil
+}
+
+func (s *AuthCommandService) generateToken(user *models.User, roles []string, jti string, issuedAt, expiresAt time.Time) (string, error) {
+	claims := Claims{
+		UserID: user.ID,
+		Email:  user.Email,
+		Roles:  roles,
+		Scope:  strings.Join(middleware.ScopesForRoles(roles), " "),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+		},
+	}
+	signed, err := s.keys.Sign(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return signed, nil
+}
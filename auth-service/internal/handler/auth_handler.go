@@ -1,22 +1,33 @@
 package handler
 
 import (
+	"context"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/eaglebank/shared/cqrs"
 	"github.com/eaglebank/shared/middleware"
+	"github.com/eaglebank/shared/models"
 	"github.com/gin-gonic/gin"
 )
 
-// AuthQuerier defines the read-side operations used by AuthHandler.
-type AuthQuerier interface {
-	Login(cqrs.LoginCommand) (string, error)
-	RefreshToken(cqrs.RefreshTokenCommand) (string, error)
+// AuthCommander defines the write-side operations used by AuthHandler.
+type AuthCommander interface {
+	Login(context.Context, cqrs.LoginCommand) (*models.LoginResult, error)
+	VerifyMFA(context.Context, cqrs.VerifyMFACommand) (*models.TokenPair, error)
+	RefreshToken(context.Context, cqrs.RefreshTokenCommand) (*models.TokenPair, error)
+	Logout(context.Context, cqrs.LogoutCommand) error
+	RequestPasswordReset(context.Context, cqrs.RequestPasswordResetCommand) error
+	ConfirmPasswordReset(context.Context, cqrs.ConfirmPasswordResetCommand) error
 }
 
-// AuthHandler handles login and token refresh. No command service needed.
+// AuthHandler handles login, MFA verification, token refresh, logout and
+// password reset.
 type AuthHandler struct {
-	queries AuthQuerier
+	commands AuthCommander
 }
 
 type LoginRequest struct {
@@ -24,16 +35,57 @@ type LoginRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
+type VerifyMFARequest struct {
+	MFAToken string `json:"mfaToken" validate:"required"`
+	Code     string `json:"code" validate:"required,max=32"`
+}
+
 type RefreshTokenRequest struct {
-	Token string `json:"token" validate:"required"`
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+type PasswordResetRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ConfirmPasswordResetRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"newPassword" validate:"required,min=8"`
 }
 
+// AuthResponse carries a token pair. Token is the access token sent as the
+// Bearer credential; ExpiresIn is its lifetime in seconds.
 type AuthResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	TokenType    string `json:"tokenType"`
+	ExpiresIn    int64  `json:"expiresIn"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+// MFAChallengeResponse is returned by login instead of tokens when the user
+// has MFA enabled. MFAToken is answered at /v1/auth/mfa/verify within
+// ExpiresIn seconds.
+type MFAChallengeResponse struct {
+	Status    string `json:"status"`
+	MFAToken  string `json:"mfaToken"`
+	ExpiresIn int64  `json:"expiresIn"`
 }
 
-func NewAuthHandler(queries AuthQuerier) *AuthHandler {
-	return &AuthHandler{queries: queries}
+func NewAuthHandler(commands AuthCommander) *AuthHandler {
+	return &AuthHandler{commands: commands}
+}
+
+func newAuthResponse(pair *models.TokenPair) AuthResponse {
+	return AuthResponse{
+		Token:        pair.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(pair.ExpiresIn.Seconds()),
+		RefreshToken: pair.RefreshToken,
+	}
 }
 
 func (h *AuthHandler) Login(c *gin.Context) {
@@ -47,16 +99,85 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	token, err := h.queries.Login(cqrs.LoginCommand{
+	result, err := h.commands.Login(c.Request.Context(), cqrs.LoginCommand{
 		Email:    req.Email,
 		Password: req.Password,
+		IP:       c.ClientIP(),
+	})
+	if err != nil {
+		switch err.Error() {
+		case "invalid credentials":
+			middleware.RespondWithError(c, http.StatusUnauthorized, "Invalid credentials")
+		case "too many login attempts":
+			setRetryAfter(c, err)
+			middleware.RespondWithError(c, http.StatusTooManyRequests, "Too many failed login attempts; please wait before trying again")
+		case "account locked":
+			setRetryAfter(c, err)
+			middleware.RespondWithError(c, http.StatusLocked, "Account is temporarily locked after too many failed login attempts; wait or reset your password")
+		default:
+			middleware.RespondWithError(c, http.StatusInternalServerError, "Failed to log in")
+		}
+		return
+	}
+
+	if result.Tokens == nil {
+		c.JSON(http.StatusOK, MFAChallengeResponse{
+			Status:    "mfa_required",
+			MFAToken:  result.MFAToken,
+			ExpiresIn: int64(result.MFAExpiresIn.Seconds()),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, newAuthResponse(result.Tokens))
+}
+
+// setRetryAfter sets the Retry-After header, in whole seconds, from an error
+// that says how long to wait.
+func setRetryAfter(c *gin.Context, err error) {
+	var throttled interface{ RetryAfter() time.Duration }
+	if errors.As(err, &throttled) {
+		seconds := int64(math.Ceil(throttled.RetryAfter().Seconds()))
+		c.Header("Retry-After", strconv.FormatInt(seconds, 10))
+	}
+}
+
+// VerifyMFA completes a login that returned an MFA challenge, accepting a
+// TOTP code or a recovery code.
+func (h *AuthHandler) VerifyMFA(c *gin.Context) {
+	var req VerifyMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.RespondWithError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if validationErrors := middleware.ValidateRequest(req); validationErrors != nil {
+		middleware.RespondWithValidationError(c, validationErrors)
+		return
+	}
+
+	pair, err := h.commands.VerifyMFA(c.Request.Context(), cqrs.VerifyMFACommand{
+		MFAToken: req.MFAToken,
+		Code:     req.Code,
+		IP:       c.ClientIP(),
 	})
 	if err != nil {
-		middleware.RespondWithError(c, http.StatusUnauthorized, "Invalid credentials")
+		switch err.Error() {
+		case "invalid mfa token":
+			middleware.RespondWithError(c, http.StatusUnauthorized, "MFA challenge is invalid or has expired; please log in again")
+		case "invalid mfa code":
+			middleware.RespondWithError(c, http.StatusUnauthorized, "Invalid MFA code")
+		case "too many login attempts":
+			setRetryAfter(c, err)
+			middleware.RespondWithError(c, http.StatusTooManyRequests, "Too many failed login attempts; please wait before trying again")
+		case "account locked":
+			setRetryAfter(c, err)
+			middleware.RespondWithError(c, http.StatusLocked, "Account is temporarily locked after too many failed login attempts; wait or reset your password")
+		default:
+			middleware.RespondWithError(c, http.StatusInternalServerError, "Failed to verify MFA code")
+		}
 		return
 	}
 
-	c.JSON(http.StatusOK, AuthResponse{Token: token})
+	c.JSON(http.StatusOK, newAuthResponse(pair))
 }
 
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
@@ -70,13 +191,98 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	token, err := h.queries.RefreshToken(cqrs.RefreshTokenCommand{
-		Token: req.Token,
+	pair, err := h.commands.RefreshToken(c.Request.Context(), cqrs.RefreshTokenCommand{
+		RefreshToken: req.RefreshToken,
+	})
+	if err != nil {
+		switch err.Error() {
+		case "invalid refresh token", "refresh token expired":
+			middleware.RespondWithError(c, http.StatusUnauthorized, "Invalid token")
+		case "refresh token reuse detected":
+			middleware.RespondWithError(c, http.StatusUnauthorized, "Refresh token has already been used; please log in again")
+		default:
+			middleware.RespondWithError(c, http.StatusInternalServerError, "Failed to refresh token")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, newAuthResponse(pair))
+}
+
+// Logout revokes the given refresh token, every token rotated from the same
+// login, and the access token used to call it.
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req LogoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.RespondWithError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if validationErrors := middleware.ValidateRequest(req); validationErrors != nil {
+		middleware.RespondWithValidationError(c, validationErrors)
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+	jti, expiresAt, _ := middleware.GetTokenID(c)
+
+	err := h.commands.Logout(c.Request.Context(), cqrs.LogoutCommand{
+		UserID:               userID,
+		RefreshToken:         req.RefreshToken,
+		AccessTokenID:        jti,
+		AccessTokenExpiresAt: expiresAt,
+	})
+	if err != nil {
+		middleware.RespondWithError(c, http.StatusInternalServerError, "Failed to log out")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RequestPasswordReset emails a reset link if the address is registered. It
+// answers 202 either way so it cannot be used to discover accounts.
+func (h *AuthHandler) RequestPasswordReset(c *gin.Context) {
+	var req PasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.RespondWithError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if validationErrors := middleware.ValidateRequest(req); validationErrors != nil {
+		middleware.RespondWithValidationError(c, validationErrors)
+		return
+	}
+
+	if err := h.commands.RequestPasswordReset(c.Request.Context(), cqrs.RequestPasswordResetCommand{Email: req.Email}); err != nil {
+		middleware.RespondWithError(c, http.StatusInternalServerError, "Failed to request password reset")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the address is registered, a reset link has been sent"})
+}
+
+// ConfirmPasswordReset sets a new password with a token from a reset link.
+func (h *AuthHandler) ConfirmPasswordReset(c *gin.Context) {
+	var req ConfirmPasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.RespondWithError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if validationErrors := middleware.ValidateRequest(req); validationErrors != nil {
+		middleware.RespondWithValidationError(c, validationErrors)
+		return
+	}
+
+	err := h.commands.ConfirmPasswordReset(c.Request.Context(), cqrs.ConfirmPasswordResetCommand{
+		Token:       req.Token,
+		NewPassword: req.NewPassword,
 	})
 	if err != nil {
-		middleware.RespondWithError(c, http.StatusUnauthorized, "Invalid token")
+		if err.Error() == "invalid reset token" {
+			middleware.RespondWithError(c, http.StatusBadRequest, "Reset link is invalid or has expired")
+		} else {
+			middleware.RespondWithError(c, http.StatusInternalServerError, "Failed to reset password")
+		}
 		return
 	}
 
-	c.JSON(http.StatusOK, AuthResponse{Token: token})
+	c.Status(http.StatusNoContent)
 }
@@ -1,49 +1,95 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/eaglebank/shared/cqrs"
+	"github.com/eaglebank/shared/models"
 	"github.com/gin-gonic/gin"
 )
 
 // ---- mock implementation ----
 
-type mockAuthQuerier struct {
-	loginFn   func(cqrs.LoginCommand) (string, error)
-	refreshFn func(cqrs.RefreshTokenCommand) (string, error)
+type mockAuthCommander struct {
+	loginFn     func(cqrs.LoginCommand) (*models.LoginResult, error)
+	verifyMFAFn func(cqrs.VerifyMFACommand) (*models.TokenPair, error)
+	refreshFn   func(cqrs.RefreshTokenCommand) (*models.TokenPair, error)
+	logoutFn    func(cqrs.LogoutCommand) error
+	resetFn     func(cqrs.RequestPasswordResetCommand) error
+	confirmFn   func(cqrs.ConfirmPasswordResetCommand) error
 }
 
-func (m *mockAuthQuerier) Login(cmd cqrs.LoginCommand) (string, error) {
+func (m *mockAuthCommander) Login(_ context.Context, cmd cqrs.LoginCommand) (*models.LoginResult, error) {
 	if m.loginFn != nil {
 		return m.loginFn(cmd)
 	}
-	return "", fmt.Errorf("not configured")
+	return nil, fmt.Errorf("not configured")
 }
-func (m *mockAuthQuerier) RefreshToken(cmd cqrs.RefreshTokenCommand) (string, error) {
+func (m *mockAuthCommander) VerifyMFA(_ context.Context, cmd cqrs.VerifyMFACommand) (*models.TokenPair, error) {
+	if m.verifyMFAFn != nil {
+		return m.verifyMFAFn(cmd)
+	}
+	return nil, fmt.Errorf("not configured")
+}
+func (m *mockAuthCommander) RefreshToken(_ context.Context, cmd cqrs.RefreshTokenCommand) (*models.TokenPair, error) {
 	if m.refreshFn != nil {
 		return m.refreshFn(cmd)
 	}
-	return "", fmt.Errorf("not configured")
+	return nil, fmt.Errorf("not configured")
+}
+func (m *mockAuthCommander) Logout(_ context.Context, cmd cqrs.LogoutCommand) error {
+	if m.logoutFn != nil {
+		return m.logoutFn(cmd)
+	}
+	return fmt.Errorf("not configured")
+}
+func (m *mockAuthCommander) RequestPasswordReset(_ context.Context, cmd cqrs.RequestPasswordResetCommand) error {
+	if m.resetFn != nil {
+		return m.resetFn(cmd)
+	}
+	return fmt.Errorf("not configured")
+}
+func (m *mockAuthCommander) ConfirmPasswordReset(_ context.Context, cmd cqrs.ConfirmPasswordResetCommand) error {
+	if m.confirmFn != nil {
+		return m.confirmFn(cmd)
+	}
+	return fmt.Errorf("not configured")
 }
 
 // ---- helper ----
 
-func newAuthTestRouter(qrys AuthQuerier) *gin.Engine {
+func fakeAuthToken(userID, jti string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("userId", userID)
+		c.Set("tokenId", jti)
+		c.Set("tokenExpiresAt", time.Now().Add(time.Minute))
+		c.Next()
+	}
+}
+
+func newAuthTestRouter(cmds AuthCommander) *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
-	h := NewAuthHandler(qrys)
+	h := NewAuthHandler(cmds)
 	v1 := r.Group("/v1/auth")
 	v1.POST("/login", h.Login)
+	v1.POST("/mfa/verify", h.VerifyMFA)
 	v1.POST("/refresh", h.RefreshToken)
+	v1.POST("/logout", fakeAuthToken("usr-001", "jti-001"), h.Logout)
+	v1.POST("/password-reset", h.RequestPasswordReset)
+	v1.POST("/password-reset/confirm", h.ConfirmPasswordReset)
 	return r
 }
 
+var testTokenPair = &models.TokenPair{AccessToken: "mock.jwt.token", RefreshToken: "mock-refresh-token", ExpiresIn: 15 * time.Minute}
+
 func authDoRequest(router *gin.Engine, method, url string, body interface{}) *httptest.ResponseRecorder {
 	req, _ := http.NewRequest(method, url, nil)
 	if body != nil {
@@ -62,19 +108,19 @@ func TestLogin(t *testing.T) {
 	tests := []struct {
 		name           string
 		body            interface{}
-		loginFn        func(cqrs.LoginCommand) (string, error)
+		loginFn        func(cqrs.LoginCommand) (*models.LoginResult, error)
 		expectedStatus int
 	}{
 		{
 			name: "success - valid credentials return JWT",
 			body: map[string]string{"email": "alice@example.com", "password": "securepass123"},
-			loginFn: func(cmd cqrs.LoginCommand) (string, error) { return "mock.jwt.token", nil },
+			loginFn: func(cmd cqrs.LoginCommand) (*models.LoginResult, error) { return &models.LoginResult{Tokens: testTokenPair}, nil },
 			expectedStatus: http.StatusOK,
 		},
 		{
 			name: "unauthorised - invalid credentials",
 			body: map[string]string{"email": "alice@example.com", "password": "wrongpass"},
-			loginFn: func(cmd cqrs.LoginCommand) (string, error) { return "", fmt.Errorf("invalid credentials") },
+			loginFn: func(cmd cqrs.LoginCommand) (*models.LoginResult, error) { return nil, fmt.Errorf("invalid credentials") },
 			expectedStatus: http.StatusUnauthorized,
 		},
 		{
@@ -98,7 +144,7 @@ func TestLogin(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			router := newAuthTestRouter(&mockAuthQuerier{loginFn: tt.loginFn})
+			router := newAuthTestRouter(&mockAuthCommander{loginFn: tt.loginFn})
 			w := authDoRequest(router, http.MethodPost, "/v1/auth/login", tt.body)
 			if w.Code != tt.expectedStatus {
 				t.Errorf("[%s] expected %d got %d; body: %s", tt.name, tt.expectedStatus, w.Code, w.Body.String())
@@ -107,25 +153,87 @@ func TestLogin(t *testing.T) {
 	}
 }
 
+type throttledError struct {
+	reason string
+	wait   time.Duration
+}
+
+func (e *throttledError) Error() string             { return e.reason }
+func (e *throttledError) RetryAfter() time.Duration { return e.wait }
+
+func TestLoginThrottled(t *testing.T) {
+	tests := []struct {
+		name               string
+		err                error
+		expectedStatus     int
+		expectedRetryAfter string
+	}{
+		{
+			name:               "too many requests - delayed after repeated failures",
+			err:                &throttledError{reason: "too many login attempts", wait: 1500 * time.Millisecond},
+			expectedStatus:     http.StatusTooManyRequests,
+			expectedRetryAfter: "2",
+		},
+		{
+			name:               "locked - account locked after too many failures",
+			err:                &throttledError{reason: "account locked", wait: 30 * time.Minute},
+			expectedStatus:     http.StatusLocked,
+			expectedRetryAfter: "1800",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newAuthTestRouter(&mockAuthCommander{loginFn: func(cmd cqrs.LoginCommand) (*models.LoginResult, error) {
+				return nil, tt.err
+			}})
+			w := authDoRequest(router, http.MethodPost, "/v1/auth/login", map[string]string{"email": "alice@example.com", "password": "wrongpass"})
+			if w.Code != tt.expectedStatus {
+				t.Errorf("[%s] expected %d got %d; body: %s", tt.name, tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if got := w.Header().Get("Retry-After"); got != tt.expectedRetryAfter {
+				t.Errorf("[%s] expected Retry-After %q got %q", tt.name, tt.expectedRetryAfter, got)
+			}
+		})
+	}
+}
+
 func TestRefreshToken(t *testing.T) {
 	tests := []struct {
 		name           string
 		body            interface{}
-		refreshFn      func(cqrs.RefreshTokenCommand) (string, error)
+		refreshFn      func(cqrs.RefreshTokenCommand) (*models.TokenPair, error)
 		expectedStatus int
 	}{
 		{
-			name: "success - valid token returns new JWT",
-			body: map[string]string{"token": "valid.jwt.token"},
-			refreshFn: func(cmd cqrs.RefreshTokenCommand) (string, error) { return "new.jwt.token", nil },
+			name: "success - valid refresh token returns a new pair",
+			body: map[string]string{"refreshToken": "valid-refresh-token"},
+			refreshFn: func(cmd cqrs.RefreshTokenCommand) (*models.TokenPair, error) { return testTokenPair, nil },
 			expectedStatus: http.StatusOK,
 		},
 		{
 			name: "unauthorised - invalid token",
-			body: map[string]string{"token": "invalid.jwt.token"},
-			refreshFn: func(cmd cqrs.RefreshTokenCommand) (string, error) { return "", fmt.Errorf("invalid token") },
+			body: map[string]string{"refreshToken": "invalid-refresh-token"},
+			refreshFn: func(cmd cqrs.RefreshTokenCommand) (*models.TokenPair, error) { return nil, fmt.Errorf("invalid refresh token") },
 			expectedStatus: http.StatusUnauthorized,
 		},
+		{
+			name: "unauthorised - expired refresh token",
+			body: map[string]string{"refreshToken": "expired-refresh-token"},
+			refreshFn: func(cmd cqrs.RefreshTokenCommand) (*models.TokenPair, error) { return nil, fmt.Errorf("refresh token expired") },
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "unauthorised - reused refresh token",
+			body: map[string]string{"refreshToken": "rotated-refresh-token"},
+			refreshFn: func(cmd cqrs.RefreshTokenCommand) (*models.TokenPair, error) { return nil, fmt.Errorf("refresh token reuse detected") },
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "bad request - access token sent in the old field",
+			body: map[string]string{"token": "valid.jwt.token"},
+			refreshFn: nil,
+			expectedStatus: http.StatusBadRequest,
+		},
 		{
 			name:      "bad request - missing token field",
 			body:      map[string]string{},
@@ -135,7 +243,7 @@ func TestRefreshToken(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			router := newAuthTestRouter(&mockAuthQuerier{refreshFn: tt.refreshFn})
+			router := newAuthTestRouter(&mockAuthCommander{refreshFn: tt.refreshFn})
 			w := authDoRequest(router, http.MethodPost, "/v1/auth/refresh", tt.body)
 			if w.Code != tt.expectedStatus {
 				t.Errorf("[%s] expected %d got %d; body: %s", tt.name, tt.expectedStatus, w.Code, w.Body.String())
@@ -143,3 +251,224 @@ func TestRefreshToken(t *testing.T) {
 		})
 	}
 }
+
+func TestLoginResponse(t *testing.T) {
+	router := newAuthTestRouter(&mockAuthCommander{loginFn: func(cmd cqrs.LoginCommand) (*models.LoginResult, error) {
+		return &models.LoginResult{Tokens: testTokenPair}, nil
+	}})
+	w := authDoRequest(router, http.MethodPost, "/v1/auth/login", map[string]string{"email": "alice@example.com", "password": "securepass123"})
+	var resp AuthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	want := AuthResponse{Token: "mock.jwt.token", TokenType: "Bearer", ExpiresIn: 900, RefreshToken: "mock-refresh-token"}
+	if resp != want {
+		t.Errorf("expected %+v got %+v", want, resp)
+	}
+}
+
+func TestLoginMFAChallenge(t *testing.T) {
+	router := newAuthTestRouter(&mockAuthCommander{loginFn: func(cmd cqrs.LoginCommand) (*models.LoginResult, error) {
+		return &models.LoginResult{MFAToken: "mfa-challenge", MFAExpiresIn: 5 * time.Minute}, nil
+	}})
+	w := authDoRequest(router, http.MethodPost, "/v1/auth/login", map[string]string{"email": "alice@example.com", "password": "securepass123"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d; body: %s", w.Code, w.Body.String())
+	}
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp["status"] != "mfa_required" || resp["mfaToken"] != "mfa-challenge" || resp["expiresIn"] != float64(300) {
+		t.Errorf("expected an MFA challenge, got %v", resp)
+	}
+	if _, ok := resp["token"]; ok {
+		t.Errorf("expected no access token before MFA, got %v", resp)
+	}
+}
+
+func TestVerifyMFA(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           interface{}
+		verifyMFAFn    func(cqrs.VerifyMFACommand) (*models.TokenPair, error)
+		expectedStatus int
+	}{
+		{
+			name: "success - returns a token pair",
+			body: map[string]string{"mfaToken": "mfa-challenge", "code": "123456"},
+			verifyMFAFn: func(cmd cqrs.VerifyMFACommand) (*models.TokenPair, error) {
+				if cmd.MFAToken != "mfa-challenge" || cmd.Code != "123456" {
+					return nil, fmt.Errorf("unexpected command %+v", cmd)
+				}
+				return testTokenPair, nil
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unauthorized - wrong code",
+			body:           map[string]string{"mfaToken": "mfa-challenge", "code": "000000"},
+			verifyMFAFn:    func(cmd cqrs.VerifyMFACommand) (*models.TokenPair, error) { return nil, fmt.Errorf("invalid mfa code") },
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "unauthorized - expired or exhausted challenge",
+			body:           map[string]string{"mfaToken": "mfa-challenge", "code": "123456"},
+			verifyMFAFn:    func(cmd cqrs.VerifyMFACommand) (*models.TokenPair, error) { return nil, fmt.Errorf("invalid mfa token") },
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "too many requests - delayed after repeated wrong codes",
+			body: map[string]string{"mfaToken": "mfa-challenge", "code": "000000"},
+			verifyMFAFn: func(cmd cqrs.VerifyMFACommand) (*models.TokenPair, error) {
+				return nil, &throttledError{reason: "too many login attempts", wait: time.Second}
+			},
+			expectedStatus: http.StatusTooManyRequests,
+		},
+		{
+			name: "locked - account locked after too many wrong codes",
+			body: map[string]string{"mfaToken": "mfa-challenge", "code": "000000"},
+			verifyMFAFn: func(cmd cqrs.VerifyMFACommand) (*models.TokenPair, error) {
+				return nil, &throttledError{reason: "account locked", wait: 30 * time.Minute}
+			},
+			expectedStatus: http.StatusLocked,
+		},
+		{
+			name:           "bad request - missing code",
+			body:           map[string]string{"mfaToken": "mfa-challenge"},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newAuthTestRouter(&mockAuthCommander{verifyMFAFn: tt.verifyMFAFn})
+			w := authDoRequest(router, http.MethodPost, "/v1/auth/mfa/verify", tt.body)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("[%s] expected %d got %d; body: %s", tt.name, tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestLogout(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           interface{}
+		logoutFn       func(cqrs.LogoutCommand) error
+		expectedStatus int
+	}{
+		{
+			name: "success - revokes the refresh token and the caller's access token",
+			body: map[string]string{"refreshToken": "valid-refresh-token"},
+			logoutFn: func(cmd cqrs.LogoutCommand) error {
+				if cmd.UserID != "usr-001" || cmd.AccessTokenID != "jti-001" || cmd.RefreshToken != "valid-refresh-token" {
+					return fmt.Errorf("unexpected command %+v", cmd)
+				}
+				return nil
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "bad request - missing refresh token",
+			body:           map[string]string{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "server error - revocation failed",
+			body:           map[string]string{"refreshToken": "valid-refresh-token"},
+			logoutFn:       func(cmd cqrs.LogoutCommand) error { return fmt.Errorf("failed to revoke token") },
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newAuthTestRouter(&mockAuthCommander{logoutFn: tt.logoutFn})
+			w := authDoRequest(router, http.MethodPost, "/v1/auth/logout", tt.body)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("[%s] expected %d got %d; body: %s", tt.name, tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequestPasswordReset(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           interface{}
+		resetFn        func(cqrs.RequestPasswordResetCommand) error
+		expectedStatus int
+	}{
+		{
+			name: "accepted - reset requested",
+			body: map[string]string{"email": "alice@example.com"},
+			resetFn: func(cmd cqrs.RequestPasswordResetCommand) error {
+				if cmd.Email != "alice@example.com" {
+					return fmt.Errorf("unexpected command %+v", cmd)
+				}
+				return nil
+			},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:           "bad request - invalid email",
+			body:           map[string]string{"email": "not-an-email"},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "server error - notifier failed",
+			body:           map[string]string{"email": "alice@example.com"},
+			resetFn:        func(cmd cqrs.RequestPasswordResetCommand) error { return fmt.Errorf("failed to send message") },
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newAuthTestRouter(&mockAuthCommander{resetFn: tt.resetFn})
+			w := authDoRequest(router, http.MethodPost, "/v1/auth/password-reset", tt.body)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("[%s] expected %d got %d; body: %s", tt.name, tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestConfirmPasswordReset(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           interface{}
+		confirmFn      func(cqrs.ConfirmPasswordResetCommand) error
+		expectedStatus int
+	}{
+		{
+			name: "success - password reset",
+			body: map[string]string{"token": "reset-token", "newPassword": "newsecret123"},
+			confirmFn: func(cmd cqrs.ConfirmPasswordResetCommand) error {
+				if cmd.Token != "reset-token" || cmd.NewPassword != "newsecret123" {
+					return fmt.Errorf("unexpected command %+v", cmd)
+				}
+				return nil
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "bad request - used or expired token",
+			body:           map[string]string{"token": "reset-token", "newPassword": "newsecret123"},
+			confirmFn:      func(cmd cqrs.ConfirmPasswordResetCommand) error { return fmt.Errorf("invalid reset token") },
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "bad request - password too short",
+			body:           map[string]string{"token": "reset-token", "newPassword": "short"},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newAuthTestRouter(&mockAuthCommander{confirmFn: tt.confirmFn})
+			w := authDoRequest(router, http.MethodPost, "/v1/auth/password-reset/confirm", tt.body)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("[%s] expected %d got %d; body: %s", tt.name, tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/eaglebank/shared/admin"
+	"github.com/eaglebank/shared/cqrs"
+	"github.com/eaglebank/shared/middleware"
+	"github.com/eaglebank/shared/models"
+	"github.com/gin-gonic/gin"
+)
+
+// ClientCommander defines the write-side operations used by ClientHandler.
+type ClientCommander interface {
+	IssueClientToken(context.Context, cqrs.ClientCredentialsCommand) (*models.AccessToken, error)
+	CreateClient(context.Context, cqrs.CreateClientCommand) (*models.APIClientCredentials, error)
+	RevokeClient(context.Context, cqrs.RevokeClientCommand) error
+}
+
+// ClientQuerier defines the read-side operations used by ClientHandler.
+type ClientQuerier interface {
+	ListClients() ([]*models.APIClient, error)
+}
+
+// ClientHandler serves the OAuth 2.0 token endpoint for API clients and the
+// back-office routes that manage them.
+type ClientHandler struct {
+	commands ClientCommander
+	queries  ClientQuerier
+}
+
+type CreateClientRequest struct {
+	Name   string   `json:"name" validate:"required,max=100"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,required"`
+}
+
+// TokenResponse is the RFC 6749 access token response. Client tokens come
+// without a refresh token.
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// TokenErrorResponse is the RFC 6749 error response of the token endpoint.
+type TokenErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// ClientCredentialsResponse is returned when a client is registered. The
+// secret is shown only this once.
+type ClientCredentialsResponse struct {
+	*models.APIClient
+	ClientSecret string `json:"clientSecret"`
+}
+
+type ListClientsResponse struct {
+	Clients []*models.APIClient `json:"clients"`
+}
+
+func NewClientHandler(commands ClientCommander, queries ClientQuerier) *ClientHandler {
+	return &ClientHandler{commands: commands, queries: queries}
+}
+
+// Token issues an access token to an API client using the client_credentials
+// grant. The request is form-encoded; the client authenticates with HTTP
+// Basic or with client_id and client_secret form fields, and may narrow the
+// token with a space-separated scope.
+func (h *ClientHandler) Token(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	switch c.PostForm("grant_type") {
+	case "client_credentials":
+	case "":
+		tokenError(c, http.StatusBadRequest, "invalid_request", "grant_type is required")
+		return
+	default:
+		tokenError(c, http.StatusBadRequest, "unsupported_grant_type", "Only client_credentials is supported")
+		return
+	}
+
+	clientID, clientSecret, ok := clientCredentials(c)
+	if !ok {
+		tokenError(c, http.StatusBadRequest, "invalid_request", "Client credentials must be sent once, with HTTP Basic or in the form")
+		return
+	}
+	if clientID == "" || clientSecret == "" {
+		invalidClient(c)
+		return
+	}
+
+	token, err := h.commands.IssueClientToken(c.Request.Context(), cqrs.ClientCredentialsCommand{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scopes:       strings.Fields(c.PostForm("scope")),
+	})
+	if err != nil {
+		switch err.Error() {
+		case "invalid client":
+			invalidClient(c)
+		case "invalid scope":
+			tokenError(c, http.StatusBadRequest, "invalid_scope", "The requested scope exceeds the scopes granted to the client")
+		default:
+			tokenError(c, http.StatusInternalServerError, "server_error", "Failed to issue token")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, TokenResponse{
+		AccessToken: token.AccessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(token.ExpiresIn.Seconds()),
+		Scope:       token.Scope,
+	})
+}
+
+// clientCredentials reads the client ID and secret from the Authorization
+// header, whose parts are form-encoded, or from the form body. It reports
+// false if the client used both methods or sent a malformed header.
+func clientCredentials(c *gin.Context) (string, string, bool) {
+	formID, formSecret := c.PostForm("client_id"), c.PostForm("client_secret")
+	if c.GetHeader("Authorization") == "" {
+		return formID, formSecret, true
+	}
+	if formID != "" || formSecret != "" {
+		return "", "", false
+	}
+	user, pass, ok := c.Request.BasicAuth()
+	if !ok {
+		return "", "", false
+	}
+	id, err := url.QueryUnescape(user)
+	if err != nil {
+		return "", "", false
+	}
+	secret, err := url.QueryUnescape(pass)
+	if err != nil {
+		return "", "", false
+	}
+	return id, secret, true
+}
+
+func invalidClient(c *gin.Context) {
+	c.Header("WWW-Authenticate", `Basic realm="eagle"`)
+	tokenError(c, http.StatusUnauthorized, "invalid_client", "Client authentication failed")
+}
+
+func tokenError(c *gin.Context, status int, code, description string) {
+	c.JSON(status, TokenErrorResponse{Error: code, ErrorDescription: description})
+}
+
+// CreateClient registers an API client and returns its secret.
+func (h *ClientHandler) CreateClient(c *gin.Context) {
+	var req CreateClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.RespondWithError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if validationErrors := middleware.ValidateRequest(req); validationErrors != nil {
+		middleware.RespondWithValidationError(c, validationErrors)
+		return
+	}
+	admin.AuditDetail(c, "name", req.Name)
+	admin.AuditDetail(c, "scopes", strings.Join(req.Scopes, " "))
+
+	creds, err := h.commands.CreateClient(c.Request.Context(), cqrs.CreateClientCommand{
+		Name:    req.Name,
+		Scopes:  req.Scopes,
+		ActorID: middleware.GetPrincipal(c).Subject(),
+	})
+	if err != nil {
+		if err.Error() == "invalid scope" {
+			middleware.RespondWithError(c, http.StatusBadRequest, "Unknown scope")
+		} else {
+			middleware.RespondWithError(c, http.StatusInternalServerError, "Failed to create client")
+		}
+		return
+	}
+	admin.AuditDetail(c, "clientId", creds.Client.ID)
+
+	c.JSON(http.StatusCreated, ClientCredentialsResponse{APIClient: creds.Client, ClientSecret: creds.Secret})
+}
+
+// ListClients returns every API client, without their secrets.
+func (h *ClientHandler) ListClients(c *gin.Context) {
+	clients, err := h.queries.ListClients()
+	if err != nil {
+		middleware.RespondWithError(c, http.StatusInternalServerError, "Failed to list clients")
+		return
+	}
+	c.JSON(http.StatusOK, ListClientsResponse{Clients: clients})
+}
+
+// RevokeClient stops a client obtaining new tokens.
+func (h *ClientHandler) RevokeClient(c *gin.Context) {
+	err := h.commands.RevokeClient(c.Request.Context(), cqrs.RevokeClientCommand{ClientID: c.Param("clientId")})
+	if err != nil {
+		if err.Error() == "client not found" {
+			middleware.RespondWithError(c, http.StatusNotFound, "Client not found")
+		} else {
+			middleware.RespondWithError(c, http.StatusInternalServerError, "Failed to revoke client")
+		}
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,264 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eaglebank/shared/cqrs"
+	"github.com/eaglebank/shared/models"
+	"github.com/gin-gonic/gin"
+)
+
+// ---- mock implementations ----
+
+type mockClientCommander struct {
+	issueFn  func(cqrs.ClientCredentialsCommand) (*models.AccessToken, error)
+	createFn func(cqrs.CreateClientCommand) (*models.APIClientCredentials, error)
+	revokeFn func(cqrs.RevokeClientCommand) error
+}
+
+func (m *mockClientCommander) IssueClientToken(_ context.Context, cmd cqrs.ClientCredentialsCommand) (*models.AccessToken, error) {
+	if m.issueFn != nil {
+		return m.issueFn(cmd)
+	}
+	return nil, fmt.Errorf("not configured")
+}
+func (m *mockClientCommander) CreateClient(_ context.Context, cmd cqrs.CreateClientCommand) (*models.APIClientCredentials, error) {
+	if m.createFn != nil {
+		return m.createFn(cmd)
+	}
+	return nil, fmt.Errorf("not configured")
+}
+func (m *mockClientCommander) RevokeClient(_ context.Context, cmd cqrs.RevokeClientCommand) error {
+	if m.revokeFn != nil {
+		return m.revokeFn(cmd)
+	}
+	return fmt.Errorf("not configured")
+}
+
+type mockClientQuerier struct {
+	listFn func() ([]*models.APIClient, error)
+}
+
+func (m *mockClientQuerier) ListClients() ([]*models.APIClient, error) {
+	if m.listFn != nil {
+		return m.listFn()
+	}
+	return nil, fmt.Errorf("not configured")
+}
+
+// ---- helper ----
+
+func newClientTestRouter(cmds ClientCommander, queries ClientQuerier) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	h := NewClientHandler(cmds, queries)
+	r.POST("/v1/auth/token", h.Token)
+	clients := r.Group("/admin/v1/clients", fakeAuthToken("usr-admin", "jti-001"))
+	clients.POST("", h.CreateClient)
+	clients.GET("", h.ListClients)
+	clients.DELETE("/:clientId", h.RevokeClient)
+	return r
+}
+
+// issueAsClient accepts cli-batch with secret s3cret and the scopes it was
+// registered with, accounts:read and transactions:read.
+func issueAsClient(cmd cqrs.ClientCredentialsCommand) (*models.AccessToken, error) {
+	if cmd.ClientID != "cli-batch" || cmd.ClientSecret != "s3cret" {
+		return nil, fmt.Errorf("invalid client")
+	}
+	scope := "accounts:read transactions:read"
+	for _, s := range cmd.Scopes {
+		if !strings.Contains(scope, s) {
+			return nil, fmt.Errorf("invalid scope")
+		}
+	}
+	if len(cmd.Scopes) > 0 {
+		scope = strings.Join(cmd.Scopes, " ")
+	}
+	return &models.AccessToken{AccessToken: "mock.jwt.token", ExpiresIn: 15 * time.Minute, Scope: scope}, nil
+}
+
+// ---- tests ----
+
+func TestClientToken(t *testing.T) {
+	tests := []struct {
+		name           string
+		form           url.Values
+		basicUser      string
+		basicPass      string
+		expectedStatus int
+		expectedError  string
+		expectedScope  string
+	}{
+		{
+			name:           "success - credentials in the form",
+			form:           url.Values{"grant_type": {"client_credentials"}, "client_id": {"cli-batch"}, "client_secret": {"s3cret"}},
+			expectedStatus: http.StatusOK,
+			expectedScope:  "accounts:read transactions:read",
+		},
+		{
+			name:           "success - HTTP Basic with a narrower scope",
+			form:           url.Values{"grant_type": {"client_credentials"}, "scope": {"accounts:read"}},
+			basicUser:      "cli-batch",
+			basicPass:      "s3cret",
+			expectedStatus: http.StatusOK,
+			expectedScope:  "accounts:read",
+		},
+		{
+			name:           "unauthorised - wrong secret",
+			form:           url.Values{"grant_type": {"client_credentials"}},
+			basicUser:      "cli-batch",
+			basicPass:      "guess",
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  "invalid_client",
+		},
+		{
+			name:           "unauthorised - no credentials",
+			form:           url.Values{"grant_type": {"client_credentials"}},
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  "invalid_client",
+		},
+		{
+			name:           "bad request - credentials sent twice",
+			form:           url.Values{"grant_type": {"client_credentials"}, "client_id": {"cli-batch"}},
+			basicUser:      "cli-batch",
+			basicPass:      "s3cret",
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_request",
+		},
+		{
+			name:           "bad request - scope not granted to the client",
+			form:           url.Values{"grant_type": {"client_credentials"}, "client_id": {"cli-batch"}, "client_secret": {"s3cret"}, "scope": {"accounts:freeze"}},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_scope",
+		},
+		{
+			name:           "bad request - password grant",
+			form:           url.Values{"grant_type": {"password"}, "username": {"alice@example.com"}, "password": {"securepass123"}},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "unsupported_grant_type",
+		},
+		{
+			name:           "bad request - missing grant type",
+			form:           url.Values{"client_id": {"cli-batch"}, "client_secret": {"s3cret"}},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "invalid_request",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newClientTestRouter(&mockClientCommander{issueFn: issueAsClient}, &mockClientQuerier{})
+			req, _ := http.NewRequest(http.MethodPost, "/v1/auth/token", strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.basicUser != "" {
+				req.SetBasicAuth(tt.basicUser, tt.basicPass)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("[%s] expected %d got %d; body: %s", tt.name, tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if w.Header().Get("Cache-Control") != "no-store" {
+				t.Errorf("[%s] expected Cache-Control no-store", tt.name)
+			}
+			if tt.expectedError != "" {
+				var resp TokenErrorResponse
+				json.Unmarshal(w.Body.Bytes(), &resp)
+				if resp.Error != tt.expectedError {
+					t.Errorf("[%s] expected error %q got %q", tt.name, tt.expectedError, resp.Error)
+				}
+				return
+			}
+			var resp TokenResponse
+			json.Unmarshal(w.Body.Bytes(), &resp)
+			if resp.TokenType != "Bearer" || resp.ExpiresIn != 900 || resp.Scope != tt.expectedScope {
+				t.Errorf("[%s] unexpected response %+v", tt.name, resp)
+			}
+		})
+	}
+}
+
+func TestCreateClient(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           interface{}
+		createFn       func(cqrs.CreateClientCommand) (*models.APIClientCredentials, error)
+		expectedStatus int
+	}{
+		{
+			name: "success - secret returned once",
+			body: map[string]interface{}{"name": "nightly statements", "scopes": []string{"accounts:read"}},
+			createFn: func(cmd cqrs.CreateClientCommand) (*models.APIClientCredentials, error) {
+				if cmd.ActorID != "usr-admin" {
+					return nil, fmt.Errorf("unexpected actor %s", cmd.ActorID)
+				}
+				return &models.APIClientCredentials{
+					Client: &models.APIClient{ID: "cli-001", Name: cmd.Name, SecretHash: "hash", Scopes: cmd.Scopes},
+					Secret: "s3cret",
+				}, nil
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "bad request - unknown scope",
+			body: map[string]interface{}{"name": "nightly statements", "scopes": []string{"everything"}},
+			createFn: func(cmd cqrs.CreateClientCommand) (*models.APIClientCredentials, error) {
+				return nil, fmt.Errorf("invalid scope")
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "bad request - no scopes",
+			body:           map[string]interface{}{"name": "nightly statements", "scopes": []string{}},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "bad request - missing name",
+			body:           map[string]interface{}{"scopes": []string{"accounts:read"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newClientTestRouter(&mockClientCommander{createFn: tt.createFn}, &mockClientQuerier{})
+			w := authDoRequest(router, http.MethodPost, "/admin/v1/clients", tt.body)
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("[%s] expected %d got %d; body: %s", tt.name, tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if w.Code == http.StatusCreated {
+				if body := w.Body.String(); !strings.Contains(body, `"clientSecret":"s3cret"`) || strings.Contains(body, "hash") {
+					t.Errorf("[%s] expected the secret but not its hash, got %s", tt.name, body)
+				}
+			}
+		})
+	}
+}
+
+func TestRevokeClient(t *testing.T) {
+	tests := []struct {
+		name           string
+		revokeFn       func(cqrs.RevokeClientCommand) error
+		expectedStatus int
+	}{
+		{name: "success", revokeFn: func(cmd cqrs.RevokeClientCommand) error { return nil }, expectedStatus: http.StatusNoContent},
+		{name: "not found", revokeFn: func(cmd cqrs.RevokeClientCommand) error { return fmt.Errorf("client not found") }, expectedStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newClientTestRouter(&mockClientCommander{revokeFn: tt.revokeFn}, &mockClientQuerier{})
+			w := authDoRequest(router, http.MethodDelete, "/admin/v1/clients/cli-001", nil)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("[%s] expected %d got %d; body: %s", tt.name, tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
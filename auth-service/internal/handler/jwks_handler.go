@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/eaglebank/shared/jwks"
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSQuerier returns the public keys that verify tokens issued by this service.
+type JWKSQuerier interface {
+	JWKS() jwks.Set
+}
+
+// JWKSHandler publishes the token verification keys.
+type JWKSHandler struct {
+	queries JWKSQuerier
+}
+
+func NewJWKSHandler(queries JWKSQuerier) *JWKSHandler {
+	return &JWKSHandler{queries: queries}
+}
+
+// GetJWKS serves the key set. Verifiers cache it for up to five minutes, so a
+// new key must be published at least that long before it signs tokens.
+func (h *JWKSHandler) GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.queries.JWKS())
+}
@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/eaglebank/shared/jwks"
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetJWKS(t *testing.T) {
+	keys, err := jwks.GenerateKeySet()
+	if err != nil {
+		t.Fatalf("failed to generate keys: %v", err)
+	}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/.well-known/jwks.json", NewJWKSHandler(keys).GetJWKS)
+
+	w := authDoRequest(r, http.MethodGet, "/.well-known/jwks.json", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d; body: %s", w.Code, w.Body.String())
+	}
+	parsed, err := jwks.Parse(w.Body.Bytes())
+	if err != nil {
+		t.Fatalf("response is not a valid JWKS: %v", err)
+	}
+	if _, ok := parsed[keys.ActiveKeyID()]; !ok {
+		t.Errorf("expected the active key %s to be published", keys.ActiveKeyID())
+	}
+	var raw map[string][]map[string]any
+	json.Unmarshal(w.Body.Bytes(), &raw)
+	for _, k := range raw["keys"] {
+		if _, ok := k["d"]; ok {
+			t.Error("private key material must not be published")
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package query
+
+import (
+	"github.com/eaglebank/auth-service/internal/repository"
+	"github.com/eaglebank/shared/models"
+)
+
+type ClientQueryService struct {
+	clientRepo *repository.APIClientRepository
+}
+
+func NewClientQueryService(clientRepo *repository.APIClientRepository) *ClientQueryService {
+	return &ClientQueryService{clientRepo: clientRepo}
+}
+
+// ListClients returns every API client, including revoked ones, newest first.
+func (s *ClientQueryService) ListClients() ([]*models.APIClient, error) {
+	return s.clientRepo.List()
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/eaglebank/shared/models"
+)
+
+type APIClientRepository struct {
+	db *sql.DB
+}
+
+func NewAPIClientRepository(db *sql.DB) *APIClientRepository {
+	return &APIClientRepository{db: db}
+}
+
+const apiClientColumns = `id, name, secret_hash, scopes, created_by, created_at, revoked_at`
+
+// Create stores a new client. Its CreatedAt is set from the database.
+func (r *APIClientRepository) Create(client *models.APIClient) error {
+	err := r.db.QueryRow(`
+		INSERT INTO api_clients (id, name, secret_hash, scopes, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at`,
+		client.ID, client.Name, client.SecretHash, strings.Join(client.Scopes, " "), client.CreatedBy,
+	).Scan(&client.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create api client: %w", err)
+	}
+	return nil
+}
+
+// GetByID returns a client, including revoked ones, or "client not found".
+func (r *APIClientRepository) GetByID(id string) (*models.APIClient, error) {
+	return scanAPIClient(r.db.QueryRow(`SELECT `+apiClientColumns+` FROM api_clients WHERE id = $1`, id))
+}
+
+// List returns every client, newest first.
+func (r *APIClientRepository) List() ([]*models.APIClient, error) {
+	rows, err := r.db.Query(`SELECT ` + apiClientColumns + ` FROM api_clients ORDER BY created_at DESC, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api clients: %w", err)
+	}
+	defer rows.Close()
+
+	clients := []*models.APIClient{}
+	for rows.Next() {
+		client, err := scanAPIClient(rows)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, client)
+	}
+	return clients, rows.Err()
+}
+
+// Revoke marks a client revoked. It returns "client not found" if no such
+// client exists; revoking a revoked client changes nothing.
+func (r *APIClientRepository) Revoke(id string) error {
+	result, err := r.db.Exec(
+		`UPDATE api_clients SET revoked_at = COALESCE(revoked_at, NOW()) WHERE id = $1`, id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api client: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to revoke api client: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("client not found")
+	}
+	return nil
+}
+
+func scanAPIClient(row rowScanner) (*models.APIClient, error) {
+	var client models.APIClient
+	var scopes string
+	var revokedAt sql.NullTime
+	err := row.Scan(&client.ID, &client.Name, &client.SecretHash, &scopes, &client.CreatedBy,
+		&client.CreatedAt, &revokedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("client not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api client: %w", err)
+	}
+	client.Scopes = strings.Fields(scopes)
+	if revokedAt.Valid {
+		client.RevokedAt = &revokedAt.Time
+	}
+	return &client, nil
+}
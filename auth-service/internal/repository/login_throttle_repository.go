@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const (
+	loginFailuresKeyPrefix = "auth:login:failures:"
+	loginWaitKeyPrefix     = "auth:login:wait:"
+)
+
+// failScript counts a failure, starting the window on the first one so that
+// a steady trickle of failures cannot keep the counter alive forever.
+//
+// KEYS[1] failure counter; ARGV: window in milliseconds.
+var failScript = goredis.NewScript(`
+local n = redis.call('INCR', KEYS[1])
+if n == 1 then
+  redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return n
+`)
+
+// LoginThrottleRepository counts failed logins in Redis per scope, such as
+// an email address or a client IP, and holds the time each scope must wait
+// before its next attempt.
+type LoginThrottleRepository struct {
+	client *goredis.Client
+}
+
+func NewLoginThrottleRepository(client *goredis.Client) *LoginThrottleRepository {
+	return &LoginThrottleRepository{client: client}
+}
+
+// Wait returns how long the caller must wait before trying to log in again,
+// the longest of the waits imposed on any of the scopes.
+func (r *LoginThrottleRepository) Wait(ctx context.Context, scopes ...string) (time.Duration, error) {
+	var wait time.Duration
+	for _, scope := range scopes {
+		ttl, err := r.client.PTTL(ctx, loginWaitKeyPrefix+scope).Result()
+		if err != nil {
+			return 0, fmt.Errorf("failed to read login wait: %w", err)
+		}
+		if ttl > wait {
+			wait = ttl
+		}
+	}
+	return wait, nil
+}
+
+// Fail counts a failed login against scope and returns the number of
+// failures within the current window.
+func (r *LoginThrottleRepository) Fail(ctx context.Context, scope string, window time.Duration) (int64, error) {
+	n, err := failScript.Run(ctx, r.client, []string{loginFailuresKeyPrefix + scope}, window.Milliseconds()).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to record login failure: %w", err)
+	}
+	return n, nil
+}
+
+// Delay makes scope wait d before its next attempt.
+func (r *LoginThrottleRepository) Delay(ctx context.Context, scope string, d time.Duration) error {
+	if err := r.client.Set(ctx, loginWaitKeyPrefix+scope, 1, d).Err(); err != nil {
+		return fmt.Errorf("failed to set login wait: %w", err)
+	}
+	return nil
+}
+
+// Reset forgets the failures and wait recorded against scope.
+func (r *LoginThrottleRepository) Reset(ctx context.Context, scope string) error {
+	return r.client.Del(ctx, loginFailuresKeyPrefix+scope, loginWaitKeyPrefix+scope).Err()
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const mfaChallengeKeyPrefix = "auth:mfa:challenge:"
+
+// MFAChallengeRepository keeps pending MFA challenges in Redis, keyed by the
+// hash of the challenge token. A challenge is discarded after maxAttempts
+// wrong codes, so the code space cannot be searched within its lifetime.
+type MFAChallengeRepository struct {
+	client      *goredis.Client
+	ttl         time.Duration
+	maxAttempts int64
+}
+
+func NewMFAChallengeRepository(client *goredis.Client, ttl time.Duration, maxAttempts int64) *MFAChallengeRepository {
+	return &MFAChallengeRepository{client: client, ttl: ttl, maxAttempts: maxAttempts}
+}
+
+// TTL is how long a challenge can be answered.
+func (r *MFAChallengeRepository) TTL() time.Duration {
+	return r.ttl
+}
+
+func (r *MFAChallengeRepository) Create(ctx context.Context, tokenHash, userID string) error {
+	key := mfaChallengeKeyPrefix + tokenHash
+	_, err := r.client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.HSet(ctx, key, "userId", userID, "attempts", 0)
+		pipe.Expire(ctx, key, r.ttl)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create mfa challenge: %w", err)
+	}
+	return nil
+}
+
+// Get returns the user a challenge was issued to, or "invalid mfa token".
+func (r *MFAChallengeRepository) Get(ctx context.Context, tokenHash string) (string, error) {
+	userID, err := r.client.HGet(ctx, mfaChallengeKeyPrefix+tokenHash, "userId").Result()
+	if err == goredis.Nil {
+		return "", fmt.Errorf("invalid mfa token")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get mfa challenge: %w", err)
+	}
+	return userID, nil
+}
+
+// Fail counts a wrong code against the challenge, deleting it once the
+// attempts are used up.
+func (r *MFAChallengeRepository) Fail(ctx context.Context, tokenHash string) error {
+	key := mfaChallengeKeyPrefix + tokenHash
+	attempts, err := r.client.HIncrBy(ctx, key, "attempts", 1).Result()
+	if err != nil {
+		return fmt.Errorf("failed to record mfa attempt: %w", err)
+	}
+	if attempts >= r.maxAttempts {
+		return r.Delete(ctx, tokenHash)
+	}
+	return nil
+}
+
+// Delete ends a challenge, e.g. once it has been answered.
+func (r *MFAChallengeRepository) Delete(ctx context.Context, tokenHash string) error {
+	return r.client.Del(ctx, mfaChallengeKeyPrefix+tokenHash).Err()
+}
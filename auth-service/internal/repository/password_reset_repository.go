@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/eaglebank/shared/events"
+)
+
+// PasswordResetToken is a stored password reset token. As with refresh
+// tokens, only the SHA-256 of the token is kept.
+type PasswordResetToken struct {
+	ID        string
+	UserID    string
+	TokenHash string
+	ExpiresAt time.Time
+}
+
+type PasswordResetRepository struct {
+	db *sql.DB
+}
+
+func NewPasswordResetRepository(db *sql.DB) *PasswordResetRepository {
+	return &PasswordResetRepository{db: db}
+}
+
+// Create stores t and invalidates the user's earlier unused tokens, so only
+// the most recent reset link works.
+func (r *PasswordResetRepository) Create(t *PasswordResetToken) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE password_reset_tokens SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL`, t.UserID); err != nil {
+		return fmt.Errorf("failed to invalidate reset tokens: %w", err)
+	}
+	_, err = tx.Exec(`
+		INSERT INTO password_reset_tokens (id, user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)`,
+		t.ID, t.UserID, t.TokenHash, t.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create reset token: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Reset spends the token with tokenHash and sets the user's password in one
+// transaction. It also lifts any login lock and revokes every refresh token,
+// returning them so their access tokens can be denied. An unknown, used or
+// expired token is "invalid reset token".
+func (r *PasswordResetRepository) Reset(ctx context.Context, tokenHash, passwordHash string) (string, []RefreshToken, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var userID string
+	var expiresAt time.Time
+	var usedAt sql.NullTime
+	err = tx.QueryRow(`
+		SELECT user_id, expires_at, used_at FROM password_reset_tokens
+		WHERE token_hash = $1
+		FOR UPDATE`, tokenHash,
+	).Scan(&userID, &expiresAt, &usedAt)
+	if err == sql.ErrNoRows {
+		return "", nil, fmt.Errorf("invalid reset token")
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get reset token: %w", err)
+	}
+	if usedAt.Valid || time.Now().After(expiresAt) {
+		return "", nil, fmt.Errorf("invalid reset token")
+	}
+
+	var lockedUntil sql.NullTime
+	err = tx.QueryRow(`SELECT locked_until FROM users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, userID).Scan(&lockedUntil)
+	if err == sql.ErrNoRows {
+		return "", nil, fmt.Errorf("invalid reset token")
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	_, err = tx.Exec(`
+		UPDATE users SET password_hash = $2, locked_until = NULL, updated_at = $3
+		WHERE id = $1`,
+		userID, passwordHash, time.Now().UTC(),
+	)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to update password: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE password_reset_tokens SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL`, userID); err != nil {
+		return "", nil, fmt.Errorf("failed to spend reset token: %w", err)
+	}
+
+	rows, err := tx.Query(`
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL
+		RETURNING `+refreshTokenColumns, userID)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	var revoked []RefreshToken
+	for rows.Next() {
+		t, err := scanRefreshToken(rows)
+		if err != nil {
+			rows.Close()
+			return "", nil, err
+		}
+		revoked = append(revoked, *t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return "", nil, fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	if lockedUntil.Valid {
+		event := events.UserUnlockedEvent{UserID: userID, Reason: "password_reset"}
+		if err := events.WriteOutbox(tx, events.NewOutboxEvent(ctx, events.AuthEventsStream, events.UserUnlocked, event)); err != nil {
+			return "", nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return "", nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return userID, revoked, nil
+}
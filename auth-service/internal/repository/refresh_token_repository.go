@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// RefreshToken is a stored refresh token. Only the SHA-256 of the token is
+// kept. Tokens descended from the same login share a FamilyID; each records
+// the access token issued alongside it so the family can be revoked whole.
+type RefreshToken struct {
+	ID              string
+	UserID          string
+	FamilyID        string
+	TokenHash       string
+	AccessTokenID   string
+	AccessExpiresAt time.Time
+	ExpiresAt       time.Time
+	CreatedAt       time.Time
+	RotatedAt       *time.Time
+	RevokedAt       *time.Time
+}
+
+type RefreshTokenRepository struct {
+	db *sql.DB
+}
+
+func NewRefreshTokenRepository(db *sql.DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: db}
+}
+
+const refreshTokenColumns = `id, user_id, family_id, token_hash, access_token_id, access_expires_at,
+	expires_at, created_at, rotated_at, revoked_at`
+
+func (r *RefreshTokenRepository) Create(t *RefreshToken) error {
+	return insertRefreshToken(r.db, t)
+}
+
+// Rotate exchanges the token with tokenHash for next, which joins the same
+// family. The current token is returned together with "refresh token reuse
+// detected" if it was already rotated or revoked, "refresh token expired" if
+// it has lapsed, or "invalid refresh token" if it does not exist; in those
+// cases nothing is written.
+func (r *RefreshTokenRepository) Rotate(tokenHash string, next *RefreshToken) (*RefreshToken, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	current, err := scanRefreshToken(tx.QueryRow(
+		`SELECT `+refreshTokenColumns+` FROM refresh_tokens WHERE token_hash = $1 FOR UPDATE`, tokenHash,
+	))
+	if err != nil {
+		return nil, err
+	}
+	if current.RotatedAt != nil || current.RevokedAt != nil {
+		return current, fmt.Errorf("refresh token reuse detected")
+	}
+	if !current.ExpiresAt.After(time.Now()) {
+		return current, fmt.Errorf("refresh token expired")
+	}
+
+	if _, err := tx.Exec(`UPDATE refresh_tokens SET rotated_at = NOW() WHERE id = $1`, current.ID); err != nil {
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	next.UserID = current.UserID
+	next.FamilyID = current.FamilyID
+	if err := insertRefreshToken(tx, next); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return current, nil
+}
+
+// GetByHash returns the token stored under tokenHash.
+func (r *RefreshTokenRepository) GetByHash(tokenHash string) (*RefreshToken, error) {
+	return scanRefreshToken(r.db.QueryRow(
+		`SELECT `+refreshTokenColumns+` FROM refresh_tokens WHERE token_hash = $1`, tokenHash,
+	))
+}
+
+// RevokeFamily revokes every token in a family and returns them all, so the
+// caller can deny the access tokens that were issued alongside.
+func (r *RefreshTokenRepository) RevokeFamily(familyID string) ([]RefreshToken, error) {
+	rows, err := r.db.Query(`
+		UPDATE refresh_tokens SET revoked_at = COALESCE(revoked_at, NOW())
+		WHERE family_id = $1
+		RETURNING `+refreshTokenColumns, familyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []RefreshToken
+	for rows.Next() {
+		t, err := scanRefreshToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *t)
+	}
+	return tokens, rows.Err()
+}
+
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func insertRefreshToken(db execer, t *RefreshToken) error {
+	_, err := db.Exec(`
+		INSERT INTO refresh_tokens (id, user_id, family_id, token_hash, access_token_id, access_expires_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		t.ID, t.UserID, t.FamilyID, t.TokenHash, t.AccessTokenID, t.AccessExpiresAt, t.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRefreshToken(row rowScanner) (*RefreshToken, error) {
+	var t RefreshToken
+	var rotatedAt, revokedAt sql.NullTime
+	err := row.Scan(&t.ID, &t.UserID, &t.FamilyID, &t.TokenHash, &t.AccessTokenID, &t.AccessExpiresAt,
+		&t.ExpiresAt, &t.CreatedAt, &rotatedAt, &revokedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("invalid refresh token")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+	if rotatedAt.Valid {
+		t.RotatedAt = &rotatedAt.Time
+	}
+	if revokedAt.Valid {
+		t.RevokedAt = &revokedAt.Time
+	}
+	return &t, nil
+}
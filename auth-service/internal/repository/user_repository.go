@@ -1,9 +1,12 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
+	"github.com/eaglebank/shared/events"
 	"github.com/eaglebank/shared/models"
 )
 
@@ -19,18 +22,19 @@ func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 	query := `
 		SELECT id, name, email, password_hash, phone_number,
 			   address_line1, address_line2, address_line3, address_town, address_county, address_postcode,
-			   created_at, updated_at
+			   created_at, updated_at, locked_until
 		FROM users
 		WHERE email = $1 AND deleted_at IS NULL
 	`
 
 	var user models.User
 	var line2, line3 sql.NullString
+	var lockedUntil sql.NullTime
 
 	err := r.db.QueryRow(query, email).Scan(
 		&user.ID, &user.Name, &user.Email, &user.PasswordHash, &user.PhoneNumber,
 		&user.Address.Line1, &line2, &line3, &user.Address.Town, &user.Address.County, &user.Address.Postcode,
-		&user.CreatedAt, &user.UpdatedAt,
+		&user.CreatedAt, &user.UpdatedAt, &lockedUntil,
 	)
 
 	if err == sql.ErrNoRows {
@@ -46,6 +50,175 @@ func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 	if line3.Valid {
 		user.Address.Line3 = line3.String
 	}
+	if lockedUntil.Valid {
+		user.LockedUntil = &lockedUntil.Time
+	}
 
 	return &user, nil
 }
+
+// GetByID returns an active user's identity for re-issuing tokens.
+func (r *UserRepository) GetByID(id string) (*models.User, error) {
+	var user models.User
+	err := r.db.QueryRow(
+		`SELECT id, name, email FROM users WHERE id = $1 AND deleted_at IS NULL`, id,
+	).Scan(&user.ID, &user.Name, &user.Email)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("user not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return &user, nil
+}
+
+// GetRoles returns the user's staff roles, sorted; customers have none.
+func (r *UserRepository) GetRoles(userID string) ([]string, error) {
+	rows, err := r.db.Query(`SELECT role FROM user_roles WHERE user_id = $1 ORDER BY role`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get roles: %w", err)
+	}
+	defer rows.Close()
+	var roles []string
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, fmt.Errorf("failed to scan role: %w", err)
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+// MFA is a user's confirmed TOTP enrolment as auth-service needs it.
+type MFA struct {
+	SecretCiphertext string
+	// LastUsedStep is the most recent accepted TOTP step, or -1.
+	LastUsedStep int64
+}
+
+// GetMFA returns the user's confirmed TOTP enrolment, or nil if MFA is not
+// enabled. Pending enrolments do not affect login.
+func (r *UserRepository) GetMFA(userID string) (*MFA, error) {
+	var mfa MFA
+	var lastStep sql.NullInt64
+	err := r.db.QueryRow(
+		`SELECT secret_ciphertext, last_used_step FROM user_mfa WHERE user_id = $1 AND confirmed_at IS NOT NULL`, userID,
+	).Scan(&mfa.SecretCiphertext, &lastStep)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mfa: %w", err)
+	}
+	mfa.LastUsedStep = -1
+	if lastStep.Valid {
+		mfa.LastUsedStep = lastStep.Int64
+	}
+	return &mfa, nil
+}
+
+// UseTOTPStep records step as the latest accepted TOTP step. It reports false
+// if that step or a later one was already used, so two concurrent logins
+// cannot both spend the same code.
+func (r *UserRepository) UseTOTPStep(userID string, step int64) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE user_mfa SET last_used_step = $2
+		WHERE user_id = $1 AND (last_used_step IS NULL OR last_used_step < $2)`,
+		userID, step,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to record mfa use: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// UseRecoveryCode marks the unused recovery code with codeHash as spent,
+// reporting false if there is none.
+func (r *UserRepository) UseRecoveryCode(userID, codeHash string) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE mfa_recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`,
+		userID, codeHash,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// Lock blocks the user from logging in until event.LockedUntil and records
+// the lock in the outbox. It reports false, writing nothing, if the user is
+// already locked.
+func (r *UserRepository) Lock(ctx context.Context, event events.UserLockedEvent) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE users SET locked_until = $2
+		WHERE id = $1 AND (locked_until IS NULL OR locked_until <= $3)`,
+		event.UserID, event.LockedUntil, time.Now().UTC(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to lock user: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return false, nil
+	}
+	if err := events.WriteOutbox(tx, events.NewOutboxEvent(ctx, events.AuthEventsStream, events.UserLocked, event)); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// UnlockExpired clears every lock that has run out and records each unlock
+// in the outbox, returning how many were cleared. Expired locks no longer
+// block login; this only keeps the audit trail accurate.
+func (r *UserRepository) UnlockExpired() (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		UPDATE users SET locked_until = NULL
+		WHERE locked_until <= $1
+		RETURNING id`, time.Now().UTC(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to unlock users: %w", err)
+	}
+	var unlocked []events.OutboxEvent
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan user: %w", err)
+		}
+		event := events.UserUnlockedEvent{UserID: userID, Reason: "expired"}
+		unlocked = append(unlocked, events.NewOutboxEvent(context.Background(), events.AuthEventsStream, events.UserUnlocked, event))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to unlock users: %w", err)
+	}
+	if len(unlocked) == 0 {
+		return 0, nil
+	}
+	if err := events.WriteOutbox(tx, unlocked...); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(unlocked), nil
+}
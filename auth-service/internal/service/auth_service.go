@@ -1,4 +1,4 @@
 // Package service is retained for Go module compatibility.
 // All auth business logic has been moved into the CQRS package:
-//   - internal/query  — AuthQueryService (login, token refresh)
+//   - internal/command — AuthCommandService (login, token refresh, logout)
 package service
@@ -21,6 +21,28 @@ fi
 echo "✅ Docker and Docker Compose are installed"
 echo ""
 
+# Generate a token signing key on first run
+if ! ls keys/jwt/*.pem &> /dev/null; then
+    echo "🔑 Generating a JWT signing key..."
+    mkdir -p keys/jwt
+    openssl genpkey -algorithm ed25519 -out "keys/jwt/$(date +%Y%m%d).pem"
+    echo ""
+fi
+
+# Generate the key that encrypts MFA secrets on first run
+if ! grep -q '^MFA_ENCRYPTION_KEY=.' .env 2>/dev/null; then
+    echo "🔑 Generating an MFA encryption key..."
+    echo "MFA_ENCRYPTION_KEY=$(openssl rand -base64 32)" >> .env
+    echo ""
+fi
+
+# Generate the key that signs email verification links on first run
+if ! grep -q '^EMAIL_VERIFICATION_KEY=.' .env 2>/dev/null; then
+    echo "🔑 Generating an email verification key..."
+    echo "EMAIL_VERIFICATION_KEY=$(openssl rand -base64 32)" >> .env
+    echo ""
+fi
+
 # Stop any existing containers
 echo "🛑 Stopping any existing containers..."
 docker-compose down -v 2>/dev/null || true
@@ -0,0 +1,80 @@
+package admin
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/eaglebank/shared/events"
+	"github.com/eaglebank/shared/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+const auditDetailsKey = "auditDetails"
+
+// AuditPublisher publishes audit records. *events.Publisher satisfies it.
+type AuditPublisher interface {
+	Publish(ctx context.Context, stream, eventType string, data any) error
+}
+
+// Audit records every request to the routes it guards as an admin.action
+// event on audit.events once the handler has answered, including requests
+// the policy refused. It must run after ScopedAuthMiddleware and before any scope
+// check. A record that cannot be published is logged instead; the request
+// has already been served by then.
+func Audit(publisher AuditPublisher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		principal := middleware.GetPrincipal(c)
+		record := events.AdminActionEvent{
+			ActorID: principal.Subject(),
+			Roles:   principal.Roles,
+			Method:  c.Request.Method,
+			Route:   c.FullPath(),
+			Params:  auditParams(c),
+			Status:  c.Writer.Status(),
+		}
+		if details, ok := c.Get(auditDetailsKey); ok {
+			record.Details = details.(map[string]string)
+		}
+		ctx := context.WithoutCancel(c.Request.Context())
+		slog.InfoContext(ctx, "AUDIT",
+			"actor_id", record.ActorID,
+			"params", record.Params,
+			"status", record.Status,
+			"details", record.Details,
+		)
+		if err := publisher.Publish(ctx, events.AuditEventsStream, events.AdminAction, record); err != nil {
+			slog.ErrorContext(ctx, "Failed to publish audit record", "actor_id", record.ActorID, "err", err)
+		}
+	}
+}
+
+// AuditDetail adds key=value to the audit record of the current request,
+// e.g. the reason given for an action or the ID of what it created.
+func AuditDetail(c *gin.Context, key, value string) {
+	details, ok := c.Get(auditDetailsKey)
+	if !ok {
+		details = make(map[string]string)
+		c.Set(auditDetailsKey, details)
+	}
+	details.(map[string]string)[key] = value
+}
+
+// auditParams collects the path parameters and the first value of each
+// query parameter.
+func auditParams(c *gin.Context) map[string]string {
+	params := make(map[string]string)
+	for _, p := range c.Params {
+		params[p.Key] = p.Value
+	}
+	for key, values := range c.Request.URL.Query() {
+		if _, ok := params[key]; !ok && len(values) > 0 {
+			params[key] = values[0]
+		}
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	return params
+}
@@ -0,0 +1,85 @@
+package admin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eaglebank/shared/events"
+	"github.com/eaglebank/shared/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// ---- mock implementation ----
+
+type recordingPublisher struct {
+	records []events.AdminActionEvent
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, stream, eventType string, data any) error {
+	if stream == events.AuditEventsStream && eventType == events.AdminAction {
+		p.records = append(p.records, data.(events.AdminActionEvent))
+	}
+	return nil
+}
+
+// ---- tests ----
+
+func TestAudit(t *testing.T) {
+	tests := []struct {
+		name           string
+		scopes         []string
+		expectedStatus int
+		expectedDetail string
+	}{
+		{
+			name:           "allowed - recorded with the handler's details",
+			scopes:         []string{middleware.ScopeAccountsFreeze},
+			expectedStatus: http.StatusNoContent,
+			expectedDetail: "fraud report",
+		},
+		{
+			name:           "refused - recorded without running the handler",
+			scopes:         []string{middleware.ScopeAccountsRead},
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher := &recordingPublisher{}
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				c.Set("userId", "usr-staff")
+				c.Set("scopes", tt.scopes)
+				c.Next()
+			})
+			r.POST("/admin/v1/accounts/:accountNumber/freeze", Audit(publisher), middleware.RequireScope(middleware.ScopeAccountsFreeze), func(c *gin.Context) {
+				AuditDetail(c, "reason", "fraud report")
+				c.Status(http.StatusNoContent)
+			})
+
+			req, _ := http.NewRequest(http.MethodPost, "/admin/v1/accounts/01234567/freeze?note=x", nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("[%s] expected %d got %d", tt.name, tt.expectedStatus, w.Code)
+			}
+			if len(publisher.records) != 1 {
+				t.Fatalf("[%s] expected one audit record, got %d", tt.name, len(publisher.records))
+			}
+			record := publisher.records[0]
+			if record.ActorID != "usr-staff" || record.Route != "/admin/v1/accounts/:accountNumber/freeze" || record.Status != tt.expectedStatus {
+				t.Errorf("[%s] unexpected record %+v", tt.name, record)
+			}
+			if record.Params["accountNumber"] != "01234567" || record.Params["note"] != "x" {
+				t.Errorf("[%s] expected path and query params, got %v", tt.name, record.Params)
+			}
+			if record.Details["reason"] != tt.expectedDetail {
+				t.Errorf("[%s] expected reason %q, got %v", tt.name, tt.expectedDetail, record.Details)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+// Package admin holds operational HTTP handlers shared by the services.
+package admin
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/eaglebank/shared/events"
+	"github.com/eaglebank/shared/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// DeadLetterManager defines the dead-letter operations used by DLQHandler.
+type DeadLetterManager interface {
+	List(ctx context.Context, stream, after string, count int64) ([]events.DeadLetter, error)
+	Replay(ctx context.Context, stream, id string) error
+	Discard(ctx context.Context, stream, id string) error
+}
+
+// DLQHandler exposes the dead-letter streams of the streams a service consumes.
+type DLQHandler struct {
+	dlq     DeadLetterManager
+	streams map[string]bool
+}
+
+type ListDeadLettersResponse struct {
+	DeadLetters []events.DeadLetter `json:"deadLetters"`
+}
+
+// NewDLQHandler creates a handler that only serves the given source streams.
+func NewDLQHandler(dlq DeadLetterManager, streams ...string) *DLQHandler {
+	allowed := make(map[string]bool, len(streams))
+	for _, s := range streams {
+		allowed[s] = true
+	}
+	return &DLQHandler{dlq: dlq, streams: allowed}
+}
+
+// Register mounts the DLQ routes on group: GET /:stream, POST /:stream/:id/replay
+// and DELETE /:stream/:id.
+func (h *DLQHandler) Register(group *gin.RouterGroup) {
+	group.GET("/:stream", h.ListDeadLetters)
+	group.POST("/:stream/:id/replay", h.ReplayDeadLetter)
+	group.DELETE("/:stream/:id", h.DiscardDeadLetter)
+}
+
+func (h *DLQHandler) ListDeadLetters(c *gin.Context) {
+	stream, ok := h.stream(c)
+	if !ok {
+		return
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "50"), 10, 64)
+	if err != nil || limit < 1 || limit > 500 {
+		middleware.RespondWithError(c, http.StatusBadRequest, "limit must be between 1 and 500")
+		return
+	}
+
+	letters, err := h.dlq.List(c.Request.Context(), stream, c.Query("after"), limit)
+	if err != nil {
+		middleware.RespondWithError(c, http.StatusInternalServerError, "Failed to list dead letters")
+		return
+	}
+	c.JSON(http.StatusOK, ListDeadLettersResponse{DeadLetters: letters})
+}
+
+func (h *DLQHandler) ReplayDeadLetter(c *gin.Context) {
+	stream, ok := h.stream(c)
+	if !ok {
+		return
+	}
+	if err := h.dlq.Replay(c.Request.Context(), stream, c.Param("id")); err != nil {
+		respondWithDLQError(c, err, "Failed to replay dead letter")
+		return
+	}
+	c.Status(http.StatusAccepted)
+}
+
+func (h *DLQHandler) DiscardDeadLetter(c *gin.Context) {
+	stream, ok := h.stream(c)
+	if !ok {
+		return
+	}
+	if err := h.dlq.Discard(c.Request.Context(), stream, c.Param("id")); err != nil {
+		respondWithDLQError(c, err, "Failed to discard dead letter")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// stream validates the :stream parameter against the streams this service owns.
+func (h *DLQHandler) stream(c *gin.Context) (string, bool) {
+	stream := c.Param("stream")
+	if !h.streams[stream] {
+		middleware.RespondWithError(c, http.StatusNotFound, "Unknown stream")
+		return "", false
+	}
+	return stream, true
+}
+
+func respondWithDLQError(c *gin.Context, err error, fallback string) {
+	if err.Error() == "dead letter not found" {
+		middleware.RespondWithError(c, http.StatusNotFound, "Dead letter not found")
+		return
+	}
+	middleware.RespondWithError(c, http.StatusInternalServerError, fallback)
+}
@@ -0,0 +1,150 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eaglebank/shared/events"
+	"github.com/gin-gonic/gin"
+)
+
+// ---- mock implementation ----
+
+type mockDeadLetterManager struct {
+	listFn    func(stream, after string, count int64) ([]events.DeadLetter, error)
+	replayFn  func(stream, id string) error
+	discardFn func(stream, id string) error
+}
+
+func (m *mockDeadLetterManager) List(_ context.Context, stream, after string, count int64) ([]events.DeadLetter, error) {
+	if m.listFn != nil {
+		return m.listFn(stream, after, count)
+	}
+	return nil, fmt.Errorf("not configured")
+}
+func (m *mockDeadLetterManager) Replay(_ context.Context, stream, id string) error {
+	if m.replayFn != nil {
+		return m.replayFn(stream, id)
+	}
+	return fmt.Errorf("not configured")
+}
+func (m *mockDeadLetterManager) Discard(_ context.Context, stream, id string) error {
+	if m.discardFn != nil {
+		return m.discardFn(stream, id)
+	}
+	return fmt.Errorf("not configured")
+}
+
+// ---- helpers ----
+
+func newDLQTestRouter(dlq DeadLetterManager) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	NewDLQHandler(dlq, events.TransactionEventsStream).Register(r.Group("/admin/dlq"))
+	return r
+}
+
+func dlqDoRequest(router *gin.Engine, method, url string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, url, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// ---- tests ----
+
+func TestListDeadLetters(t *testing.T) {
+	tests := []struct {
+		name           string
+		url            string
+		listFn         func(stream, after string, count int64) ([]events.DeadLetter, error)
+		expectedStatus int
+	}{
+		{
+			name: "success - list dead letters for a consumed stream",
+			url:  "/admin/dlq/transaction.events?limit=10",
+			listFn: func(stream, after string, count int64) ([]events.DeadLetter, error) {
+				return []events.DeadLetter{{ID: "1-0", Reason: "boom"}}, nil
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "not found - stream not consumed by this service",
+			url:            "/admin/dlq/user.events",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "bad request - limit out of range",
+			url:            "/admin/dlq/transaction.events?limit=0",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newDLQTestRouter(&mockDeadLetterManager{listFn: tt.listFn})
+			w := dlqDoRequest(router, http.MethodGet, tt.url)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("[%s] expected %d got %d; body: %s", tt.name, tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestReplayDeadLetter(t *testing.T) {
+	tests := []struct {
+		name           string
+		replayFn       func(stream, id string) error
+		expectedStatus int
+	}{
+		{
+			name:           "success - replay dead letter",
+			replayFn:       func(stream, id string) error { return nil },
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:           "not found - dead letter does not exist",
+			replayFn:       func(stream, id string) error { return fmt.Errorf("dead letter not found") },
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newDLQTestRouter(&mockDeadLetterManager{replayFn: tt.replayFn})
+			w := dlqDoRequest(router, http.MethodPost, "/admin/dlq/transaction.events/1-0/replay")
+			if w.Code != tt.expectedStatus {
+				t.Errorf("[%s] expected %d got %d; body: %s", tt.name, tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestDiscardDeadLetter(t *testing.T) {
+	tests := []struct {
+		name           string
+		discardFn      func(stream, id string) error
+		expectedStatus int
+	}{
+		{
+			name:           "success - discard dead letter",
+			discardFn:      func(stream, id string) error { return nil },
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "not found - dead letter does not exist",
+			discardFn:      func(stream, id string) error { return fmt.Errorf("dead letter not found") },
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newDLQTestRouter(&mockDeadLetterManager{discardFn: tt.discardFn})
+			w := dlqDoRequest(router, http.MethodDelete, "/admin/dlq/transaction.events/1-0")
+			if w.Code != tt.expectedStatus {
+				t.Errorf("[%s] expected %d got %d; body: %s", tt.name, tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+// Package correlation links the work done for one request across services.
+// The gateway assigns each request an ID, which becomes the correlation ID
+// of every event that follows from it. Each event also names its cause: the
+// request, or the event whose handler published it.
+package correlation
+
+import (
+	"context"
+
+	"github.com/eaglebank/shared/utils"
+)
+
+// Header carries the request ID between clients, the gateway and services.
+const Header = "X-Request-ID"
+
+// IDs places a piece of work in its chain. CorrelationID is the ID of the
+// request that started the chain; CausationID is the request or event that
+// directly caused the work.
+type IDs struct {
+	CorrelationID string
+	CausationID   string
+}
+
+type contextKey struct{}
+
+// NewContext returns ctx carrying ids.
+func NewContext(ctx context.Context, ids IDs) context.Context {
+	return context.WithValue(ctx, contextKey{}, ids)
+}
+
+// FromContext returns the IDs carried by ctx, or zero IDs.
+func FromContext(ctx context.Context) IDs {
+	ids, _ := ctx.Value(contextKey{}).(IDs)
+	return ids
+}
+
+// NewRequestID generates an ID for a request that arrived without one.
+func NewRequestID() string {
+	return utils.GenerateID("req")
+}
+
+// ValidRequestID reports whether an ID supplied by a caller is safe to adopt:
+// non-empty, at most 128 characters, and limited to letters, digits and
+// "-", "_", ".", ":" so it cannot forge log lines.
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
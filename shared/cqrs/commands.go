@@ -1,6 +1,12 @@
 package cqrs
 
-import "github.com/eaglebank/shared/models"
+import (
+	"time"
+
+	"github.com/eaglebank/shared/middleware"
+	"github.com/eaglebank/shared/models"
+	"github.com/eaglebank/shared/money"
+)
 
 type CreateUserCommand struct {
 	Name        string
@@ -22,38 +28,168 @@ type DeleteUserCommand struct {
 	UserID string
 }
 
+// VerifyEmailCommand activates the user named in a signed verification
+// token.
+type VerifyEmailCommand struct {
+	Token string
+}
+
+// ResendVerificationCommand sends a fresh verification link to a user who
+// is still pending verification.
+type ResendVerificationCommand struct {
+	UserID string
+}
+
+// EnrolMFACommand starts (or restarts) TOTP enrolment for a user.
+type EnrolMFACommand struct {
+	UserID string
+}
+
+// ConfirmMFACommand enables a pending enrolment once the user proves their
+// authenticator produces valid codes.
+type ConfirmMFACommand struct {
+	UserID string
+	Code   string
+}
+
+// ChangePasswordCommand replaces a user's password after checking the
+// current one, and signs out every session.
+type ChangePasswordCommand struct {
+	UserID          string
+	CurrentPassword string
+	NewPassword     string
+}
+
 type CreateAccountCommand struct {
 	UserID      string
 	Name        string
 	AccountType string
 }
 
+// Commands on an existing customer resource carry the caller's Principal;
+// the command service authorises it against the resource owner with the
+// policy in shared/middleware.
+
 type UpdateAccountCommand struct {
-	AccountNumber    string
-	RequestingUserID string
-	Name             string
-	AccountType      string
+	AccountNumber string
+	Principal     middleware.Principal
+	Name          string
+	AccountType   string
 }
 
 type DeleteAccountCommand struct {
-	AccountNumber    string
-	RequestingUserID string
+	AccountNumber string
+	Principal     middleware.Principal
+}
+
+// FreezeAccountCommand stops an account accepting transactions and
+// transfers. ActorID is the staff member or API client taking the action.
+type FreezeAccountCommand struct {
+	AccountNumber string
+	ActorID       string
+	Reason        string
+}
+
+// UnfreezeAccountCommand lifts a freeze placed by FreezeAccountCommand.
+type UnfreezeAccountCommand struct {
+	AccountNumber string
+	ActorID       string
+	Reason        string
 }
 
 type CreateTransactionCommand struct {
 	AccountNumber string
-	UserID        string
-	Amount        float64
+	Principal     middleware.Principal
+	Amount        money.Amount
 	Currency      string
 	Type          string
 	Reference     string
 }
 
+// CreateTransferCommand moves Amount from AccountNumber, which Principal must
+// be able to act on, to ToAccountNumber, which may belong to any user.
+type CreateTransferCommand struct {
+	AccountNumber   string
+	Principal       middleware.Principal
+	ToAccountNumber string
+	Amount          money.Amount
+	Currency        string
+	Reference       string
+}
+
+// PostAdjustmentCommand posts a manual correction to any account on behalf
+// of ActorID, a staff member or API client. Type is ledger.AdjustmentCredit or
+// ledger.AdjustmentDebit, and Reason is recorded as the reference.
+type PostAdjustmentCommand struct {
+	AccountNumber string
+	ActorID       string
+	Type          string
+	Amount        money.Amount
+	Currency      string
+	Reason        string
+}
+
 type LoginCommand struct {
 	Email    string
 	Password string
+	// IP is the client address, used to throttle failed attempts.
+	IP string
 }
 
+// RefreshTokenCommand exchanges a refresh token for a new token pair. The
+// presented refresh token is rotated and cannot be used again.
 type RefreshTokenCommand struct {
-	Token string
+	RefreshToken string
+}
+
+// VerifyMFACommand completes a login that returned an MFA challenge. Code is
+// a TOTP code or an unused recovery code.
+type VerifyMFACommand struct {
+	MFAToken string
+	Code     string
+	// IP is the client address, used to throttle wrong codes.
+	IP string
+}
+
+// RequestPasswordResetCommand sends a reset link to Email if it belongs to
+// a user.
+type RequestPasswordResetCommand struct {
+	Email string
+}
+
+// ConfirmPasswordResetCommand sets a new password using a reset token.
+type ConfirmPasswordResetCommand struct {
+	Token       string
+	NewPassword string
+}
+
+// ClientCredentialsCommand authenticates an API client by its secret and
+// issues it an access token. Scopes narrows the token to a subset of the
+// client's scopes; empty means all of them.
+type ClientCredentialsCommand struct {
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+}
+
+// CreateClientCommand registers an API client on behalf of ActorID.
+type CreateClientCommand struct {
+	Name    string
+	Scopes  []string
+	ActorID string
+}
+
+// RevokeClientCommand stops a client obtaining tokens. Tokens already issued
+// run until they expire.
+type RevokeClientCommand struct {
+	ClientID string
+}
+
+// LogoutCommand revokes the refresh token family of RefreshToken and the
+// access token the request was made with.
+type LogoutCommand struct {
+	UserID               string
+	RefreshToken         string
+	AccessTokenID        string
+	AccessTokenExpiresAt time.Time
 }
@@ -1,19 +1,38 @@
 package cqrs
 
+import (
+	"time"
+
+	"github.com/eaglebank/shared/middleware"
+	"github.com/eaglebank/shared/money"
+)
+
+// Queries for a single owner's data carry the caller's Principal; the query
+// service authorises it against the resource owner with the policy in
+// shared/middleware.
+
 // ---------- User queries ----------
 
 // GetUserQuery fetches a single user by ID, subject to ownership check.
 type GetUserQuery struct {
-	UserID           string
-	RequestingUserID string
+	UserID    string
+	Principal middleware.Principal
+}
+
+// SearchUsersQuery finds users for back-office staff. Email matches exactly
+// (case-insensitively) and Name by substring; zero-valued filters are not applied.
+type SearchUsersQuery struct {
+	Email string
+	Name  string
+	Limit int
 }
 
 // ---------- Account queries ----------
 
 // GetAccountQuery fetches a single account by account number.
 type GetAccountQuery struct {
-	AccountNumber    string
-	RequestingUserID string
+	AccountNumber string
+	Principal     middleware.Principal
 }
 
 // ListAccountsQuery fetches all accounts belonging to a user.
@@ -21,17 +40,52 @@ type ListAccountsQuery struct {
 	UserID string
 }
 
+// GetBalanceQuery derives an account's ledger balance as at AsAt.
+type GetBalanceQuery struct {
+	AccountNumber string
+	Principal     middleware.Principal
+	AsAt          time.Time
+}
+
 // ---------- Transaction queries ----------
 
 // GetTransactionQuery fetches a single transaction.
 type GetTransactionQuery struct {
 	TransactionID string
 	AccountNumber string
-	UserID        string
+	Principal     middleware.Principal
 }
 
-// ListTransactionsQuery fetches all transactions for an account.
+// ListTransactionsQuery fetches one page of an account's transactions.
+// Zero-valued filters are not applied.
 type ListTransactionsQuery struct {
 	AccountNumber string
-	UserID        string
+	Principal     middleware.Principal
+	Limit         int
+	Cursor        string
+	// Ascending lists oldest first; the default is newest first.
+	Ascending bool
+	From      time.Time
+	To        time.Time
+	Type      string
+	MinAmount *money.Amount
+	MaxAmount *money.Amount
+	Reference string
+}
+
+// GetStatementQuery fetches an account's statement for [From, To).
+type GetStatementQuery struct {
+	AccountNumber string
+	Principal     middleware.Principal
+	From          time.Time
+	To            time.Time
+}
+
+// ---------- Transfer queries ----------
+
+// GetTransferQuery fetches a transfer initiated from AccountNumber.
+type GetTransferQuery struct {
+	TransferID    string
+	AccountNumber string
+	Principal     middleware.Principal
 }
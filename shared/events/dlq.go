@@ -0,0 +1,121 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DeadLetterStream returns the name of the dead-letter stream for stream.
+func DeadLetterStream(stream string) string {
+	return stream + ".dlq"
+}
+
+// DeadLetter is a message that a Subscriber gave up on, as stored in the
+// dead-letter stream.
+type DeadLetter struct {
+	ID         string    `json:"id"`
+	SourceID   string    `json:"sourceId"`
+	Group      string    `json:"group"`
+	Consumer   string    `json:"consumer"`
+	Reason     string    `json:"reason"`
+	Deliveries int64     `json:"deliveries"`
+	FailedAt   time.Time `json:"failedAt"`
+	Event      string    `json:"event"`
+}
+
+// DLQ lists, replays and discards entries in the dead-letter streams.
+type DLQ struct {
+	client *redis.Client
+}
+
+func NewDLQ(client *redis.Client) *DLQ {
+	return &DLQ{client: client}
+}
+
+// List returns up to count dead letters for stream, oldest first, starting
+// after the entry ID given in after ("" for the beginning).
+func (d *DLQ) List(ctx context.Context, stream, after string, count int64) ([]DeadLetter, error) {
+	start := "-"
+	if after != "" {
+		start = "(" + after
+	}
+	messages, err := d.client.XRangeN(ctx, DeadLetterStream(stream), start, "+", count).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+	letters := make([]DeadLetter, 0, len(messages))
+	for _, m := range messages {
+		letters = append(letters, toDeadLetter(m))
+	}
+	return letters, nil
+}
+
+// replayGroupField marks a replayed message with the consumer group that
+// dead-lettered it. Subscribers in other groups, which handled the original
+// message already, acknowledge it without handling it again.
+const replayGroupField = "replayGroup"
+
+// Replay re-publishes the original event onto stream as a new message for
+// the consumer group that failed it, and removes it from the dead-letter
+// stream.
+func (d *DLQ) Replay(ctx context.Context, stream, id string) error {
+	letter, err := d.get(ctx, stream, id)
+	if err != nil {
+		return err
+	}
+	values := map[string]any{"event": letter.Event}
+	if letter.Group != "" {
+		values[replayGroupField] = letter.Group
+	}
+	if err := NewPublisher(d.client).publishFields(ctx, stream, values); err != nil {
+		return err
+	}
+	return d.Discard(ctx, stream, id)
+}
+
+// Discard deletes a dead letter without replaying it.
+func (d *DLQ) Discard(ctx context.Context, stream, id string) error {
+	n, err := d.client.XDel(ctx, DeadLetterStream(stream), id).Result()
+	if err != nil {
+		return fmt.Errorf("failed to discard dead letter: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("dead letter not found")
+	}
+	return nil
+}
+
+func (d *DLQ) get(ctx context.Context, stream, id string) (*DeadLetter, error) {
+	messages, err := d.client.XRangeN(ctx, DeadLetterStream(stream), id, id, 1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead letter: %w", err)
+	}
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("dead letter not found")
+	}
+	letter := toDeadLetter(messages[0])
+	return &letter, nil
+}
+
+func toDeadLetter(m redis.XMessage) DeadLetter {
+	field := func(key string) string {
+		v, _ := m.Values[key].(string)
+		return v
+	}
+	deliveries, _ := strconv.ParseInt(field("deliveries"), 10, 64)
+	failedAt, _ := time.Parse(time.RFC3339Nano, field("failedAt"))
+	return DeadLetter{
+		ID:         m.ID,
+		SourceID:   field("sourceId"),
+		Group:      field("group"),
+		Consumer:   field("consumer"),
+		Reason:     field("reason"),
+		Deliveries: deliveries,
+		FailedAt:   failedAt,
+		Event:      field("event"),
+	}
+}
@@ -0,0 +1,74 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestDLQReplayReachesOnlyTheFailingGroup(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	event, _ := json.Marshal(Event{ID: "evt-1", Type: TransactionCreated, Data: map[string]string{}})
+	id, err := client.XAdd(ctx, &redis.XAddArgs{
+		Stream: DeadLetterStream(TransactionEventsStream),
+		Values: map[string]any{"event": string(event), "group": "failed-group", "reason": "boom", "deliveries": 5},
+	}).Result()
+	if err != nil {
+		t.Fatalf("failed to add dead letter: %v", err)
+	}
+
+	handled := map[string]*atomic.Int32{"failed-group": {}, "other-group": {}}
+	var subscribers []*Subscriber
+	for group, count := range handled {
+		count := count
+		subscriber := NewSubscriber(client, SubscriberConfig{
+			Group:         group,
+			Stream:        TransactionEventsStream,
+			BlockDuration: 10 * time.Millisecond,
+			Handler: func(context.Context, Event) error {
+				count.Add(1)
+				return nil
+			},
+		})
+		if err := client.XGroupCreateMkStream(ctx, TransactionEventsStream, group, "$").Err(); err != nil {
+			t.Fatalf("failed to create group: %v", err)
+		}
+		subscribers = append(subscribers, subscriber)
+	}
+
+	if err := NewDLQ(client).Replay(ctx, TransactionEventsStream, id); err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	runCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	for _, subscriber := range subscribers {
+		go subscriber.Start(runCtx)
+	}
+	<-runCtx.Done()
+	time.Sleep(20 * time.Millisecond)
+
+	if got := handled["failed-group"].Load(); got != 1 {
+		t.Errorf("expected the failing group to handle the replay once, got %d", got)
+	}
+	if got := handled["other-group"].Load(); got != 0 {
+		t.Errorf("expected the other group to skip the replay, got %d", got)
+	}
+	for group := range handled {
+		pending, err := client.XPending(ctx, TransactionEventsStream, group).Result()
+		if err != nil || pending.Count != 0 {
+			t.Errorf("expected %s to acknowledge the replay, got %v %v", group, pending, err)
+		}
+	}
+	if letters, _ := NewDLQ(client).List(ctx, TransactionEventsStream, "", 10); len(letters) != 0 {
+		t.Errorf("expected the dead letter to be removed, got %v", letters)
+	}
+}
@@ -1,33 +1,93 @@
 package events
 
-import "time"
+import (
+	"context"
+	"time"
+
+	"github.com/eaglebank/shared/correlation"
+	"github.com/eaglebank/shared/money"
+	"github.com/eaglebank/shared/utils"
+)
 
 // Event types
 const (
 	UserCreated = "user.created"
 	UserUpdated = "user.updated"
 	UserDeleted = "user.deleted"
+	// UserVerified is published when a user confirms their email address.
+	UserVerified = "user.verified"
+	// UserEmailChanged is published when a user changes their email address,
+	// which must then be verified again.
+	UserEmailChanged = "user.email_changed"
+
+	UserLocked   = "user.locked"
+	UserUnlocked = "user.unlocked"
 
 	AccountCreated = "account.created"
 	AccountUpdated = "account.updated"
 	AccountDeleted = "account.deleted"
+	// AccountFrozen and AccountUnfrozen record staff freezing an account and lifting the freeze.
+	AccountFrozen   = "account.frozen"
+	AccountUnfrozen = "account.unfrozen"
 
 	TransactionCreated = "transaction.created"
 	BalanceUpdated     = "balance.updated"
+	// TransactionPosted and TransactionRejected report whether account-service
+	// posted a transaction to the ledger or refused it for good.
+	TransactionPosted   = "transaction.posted"
+	TransactionRejected = "transaction.rejected"
+
+	TransferLegApplied = "transfer.leg_applied"
+	TransferLegFailed  = "transfer.leg_failed"
+
+	// AdminAction records a request made through a back-office API.
+	AdminAction = "admin.action"
 )
 
 // Stream names
 const (
 	UserEventsStream        = "user.events"
+	AuthEventsStream        = "auth.events"
 	AccountEventsStream     = "account.events"
 	TransactionEventsStream = "transaction.events"
+	TransferEventsStream    = "transfer.events"
+	AuditEventsStream       = "audit.events"
 )
 
-// Base event structure
+// Event is the envelope of every message on a stream. CorrelationID is the
+// request that started the chain of events and CausationID the request or
+// event that directly caused this one; see package correlation.
 type Event struct {
-	Type      string    `json:"type"`
-	Timestamp time.Time `json:"timestamp"`
-	Data      any       `json:"data"`
+	ID            string    `json:"id,omitempty"`
+	Type          string    `json:"type"`
+	Timestamp     time.Time `json:"timestamp"`
+	CorrelationID string    `json:"correlationId,omitempty"`
+	CausationID   string    `json:"causationId,omitempty"`
+	Data          any       `json:"data"`
+}
+
+// newEvent builds an envelope for data, placed in the chain carried by ctx.
+func newEvent(ctx context.Context, eventType string, data any) Event {
+	ids := correlation.FromContext(ctx)
+	return Event{
+		ID:            utils.GenerateID("evt"),
+		Type:          eventType,
+		Timestamp:     time.Now().UTC(),
+		CorrelationID: ids.CorrelationID,
+		CausationID:   ids.CausationID,
+		Data:          data,
+	}
+}
+
+// Caused returns the IDs for work done in response to e: the same
+// correlation ID, with e as the cause. An event published outside any
+// request starts its own chain.
+func (e Event) Caused() correlation.IDs {
+	correlationID := e.CorrelationID
+	if correlationID == "" {
+		correlationID = e.ID
+	}
+	return correlation.IDs{CorrelationID: correlationID, CausationID: e.ID}
 }
 
 // User events
@@ -47,6 +107,34 @@ type UserDeletedEvent struct {
 	UserID string `json:"userId"`
 }
 
+type UserVerifiedEvent struct {
+	UserID string `json:"userId"`
+	Email  string `json:"email"`
+}
+
+type UserEmailChangedEvent struct {
+	UserID string `json:"userId"`
+	Email  string `json:"email"`
+}
+
+// Auth events
+//
+// These are an audit trail of security-relevant changes made by auth-service.
+type UserLockedEvent struct {
+	UserID      string    `json:"userId"`
+	Email       string    `json:"email"`
+	Failures    int64     `json:"failures"`
+	IP          string    `json:"ip,omitempty"`
+	LockedUntil time.Time `json:"lockedUntil"`
+}
+
+// UserUnlockedEvent reports a lock being lifted. Reason is "expired" or
+// "password_reset".
+type UserUnlockedEvent struct {
+	UserID string `json:"userId"`
+	Reason string `json:"reason"`
+}
+
 // Account events
 type AccountCreatedEvent struct {
 	AccountNumber string `json:"accountNumber"`
@@ -66,18 +154,67 @@ type AccountDeletedEvent struct {
 	UserID        string `json:"userId"`
 }
 
+// AccountFreezeEvent is the payload of account.frozen and account.unfrozen.
+// UserID owns the account; ActorID is the staff member or API client who
+// acted.
+type AccountFreezeEvent struct {
+	AccountNumber string `json:"accountNumber"`
+	UserID        string `json:"userId"`
+	ActorID       string `json:"actorId"`
+	Reason        string `json:"reason"`
+}
+
 // Transaction events
 type TransactionCreatedEvent struct {
-	TransactionID string  `json:"transactionId"`
-	AccountNumber string  `json:"accountNumber"`
-	UserID        string  `json:"userId"`
-	Amount        float64 `json:"amount"`
-	Type          string  `json:"type"`
-	Currency      string  `json:"currency"`
+	TransactionID string       `json:"transactionId"`
+	AccountNumber string       `json:"accountNumber"`
+	UserID        string       `json:"userId"`
+	Amount        money.Amount `json:"amount"`
+	Type          string       `json:"type"`
+	Currency      string       `json:"currency"`
+	TransferID    string       `json:"transferId,omitempty"`
 }
 
 type BalanceUpdatedEvent struct {
-	AccountNumber string  `json:"accountNumber"`
-	NewBalance    float64 `json:"newBalance"`
-	Change        float64 `json:"change"`
+	AccountNumber string       `json:"accountNumber"`
+	NewBalance    money.Amount `json:"newBalance"`
+	Change        money.Amount `json:"change"`
+}
+
+// TransactionOutcomeEvent is the payload of transaction.posted and
+// transaction.rejected. Reason says why a transaction was rejected.
+type TransactionOutcomeEvent struct {
+	TransactionID string `json:"transactionId"`
+	AccountNumber string `json:"accountNumber"`
+	Type          string `json:"type"`
+	TransferID    string `json:"transferId,omitempty"`
+	Reason        string `json:"reason,omitempty"`
+}
+
+// Transfer events
+//
+// TransferLegEvent reports whether account-service applied one leg of a
+// transfer (transfer_out, transfer_in or transfer_reversal) to a balance.
+// transaction-service consumes these to advance the transfer saga.
+type TransferLegEvent struct {
+	TransferID    string `json:"transferId"`
+	TransactionID string `json:"transactionId"`
+	AccountNumber string `json:"accountNumber"`
+	Type          string `json:"type"`
+	Reason        string `json:"reason,omitempty"`
+}
+
+// Audit events
+//
+// AdminActionEvent records one back-office request: who made it, what it
+// addressed, and how it was answered. Details carries what the handler
+// chose to add, such as the ID of a transaction it created.
+type AdminActionEvent struct {
+	ActorID string            `json:"actorId"`
+	Roles   []string          `json:"roles,omitempty"`
+	Method  string            `json:"method"`
+	Route   string            `json:"route"`
+	Params  map[string]string `json:"params,omitempty"`
+	Status  int               `json:"status"`
+	Details map[string]string `json:"details,omitempty"`
 }
@@ -0,0 +1,63 @@
+package events
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	handlerDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "eagle",
+		Name:      "event_handler_duration_seconds",
+		Help:      "Time taken by subscriber handlers per delivery, by stream and consumer group.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"stream", "group"})
+
+	handlerFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eagle",
+		Name:      "event_handler_failures_total",
+		Help:      "Deliveries whose handler returned an error, by stream and consumer group.",
+	}, []string{"stream", "group"})
+
+	consumerLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "eagle",
+		Name:      "event_consumer_lag",
+		Help:      "Entries in the stream not yet delivered to the consumer group, from XINFO GROUPS.",
+	}, []string{"stream", "group"})
+
+	consumerPending = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "eagle",
+		Name:      "event_consumer_pending",
+		Help:      "Entries delivered to the consumer group but not yet acknowledged (the PEL).",
+	}, []string{"stream", "group"})
+)
+
+// recordGroupInfo refreshes the lag and pending gauges for the subscriber's
+// consumer group.
+func (s *Subscriber) recordGroupInfo(ctx context.Context) error {
+	group, err := s.groupInfo(ctx)
+	if err != nil {
+		return err
+	}
+	consumerLag.WithLabelValues(s.stream, s.group).Set(float64(group.Lag))
+	consumerPending.WithLabelValues(s.stream, s.group).Set(float64(group.Pending))
+	return nil
+}
+
+// groupInfo returns the XINFO GROUPS entry for the subscriber's group.
+func (s *Subscriber) groupInfo(ctx context.Context) (*redis.XInfoGroup, error) {
+	groups, err := s.client.XInfoGroups(ctx, s.stream).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read consumer groups: %w", err)
+	}
+	for i := range groups {
+		if groups[i].Name == s.group {
+			return &groups[i], nil
+		}
+	}
+	return nil, fmt.Errorf("consumer group %s not found on %s", s.group, s.stream)
+}
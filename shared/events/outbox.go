@@ -0,0 +1,52 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/eaglebank/shared/tracing"
+)
+
+// OutboxEvent is a domain event waiting to be written to the outbox table.
+// Write repositories accept these alongside the business row so that both are
+// committed in the same SQL transaction.
+type OutboxEvent struct {
+	Stream string
+	Event  Event
+	// Trace is the trace context of the write, so the Relay's publish joins
+	// the trace of the request that queued the event.
+	Trace map[string]string
+}
+
+// NewOutboxEvent builds an OutboxEvent for the given stream and event type,
+// taking its correlation and causation IDs and trace context from ctx.
+func NewOutboxEvent(ctx context.Context, stream, eventType string, data any) OutboxEvent {
+	return OutboxEvent{Stream: stream, Event: newEvent(ctx, eventType, data), Trace: tracing.Inject(ctx)}
+}
+
+// WriteOutbox inserts the events into the outbox table using the caller's
+// transaction. The Relay later drains the table into the Redis streams.
+func WriteOutbox(tx *sql.Tx, outboxEvents ...OutboxEvent) error {
+	query := `
+		INSERT INTO outbox (stream, event_type, payload, created_at, trace_context)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	for _, e := range outboxEvents {
+		payload, err := json.Marshal(e.Event)
+		if err != nil {
+			return fmt.Errorf("failed to marshal outbox event: %w", err)
+		}
+		var traceContext []byte
+		if len(e.Trace) > 0 {
+			if traceContext, err = json.Marshal(e.Trace); err != nil {
+				return fmt.Errorf("failed to marshal outbox trace context: %w", err)
+			}
+		}
+		if _, err := tx.Exec(query, e.Stream, e.Event.Type, payload, e.Event.Timestamp, traceContext); err != nil {
+			return fmt.Errorf("failed to write outbox event: %w", err)
+		}
+	}
+	return nil
+}
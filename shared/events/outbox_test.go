@@ -0,0 +1,244 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeOutbox is an in-memory outbox table behind a database/sql connector.
+// It understands only the statements WriteOutbox and Relay issue. Writes made
+// in a transaction are applied on commit and dropped on rollback.
+type fakeOutbox struct {
+	mu     sync.Mutex
+	rows   []*fakeOutboxRow
+	nextID int64
+}
+
+type fakeOutboxRow struct {
+	id           int64
+	stream       string
+	eventType    string
+	payload      []byte
+	traceContext []byte
+	createdAt    time.Time
+	publishedAt  *time.Time
+}
+
+func newFakeOutboxDB(t *testing.T) (*sql.DB, *fakeOutbox) {
+	t.Helper()
+	store := &fakeOutbox{}
+	db := sql.OpenDB(fakeConnector{store: store})
+	t.Cleanup(func() { db.Close() })
+	return db, store
+}
+
+// unpublished returns the streams of the rows not yet published, in id order.
+func (f *fakeOutbox) unpublished() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var streams []string
+	for _, row := range f.rows {
+		if row.publishedAt == nil {
+			streams = append(streams, row.stream)
+		}
+	}
+	return streams
+}
+
+type fakeConnector struct{ store *fakeOutbox }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{store: c.store}, nil
+}
+
+func (c fakeConnector) Driver() driver.Driver { return nil }
+
+type fakeConn struct {
+	store   *fakeOutbox
+	inTx    bool
+	pending []func()
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("fake outbox: prepared statements are not supported")
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.inTx = true
+	return fakeTx{conn: c}, nil
+}
+
+type fakeTx struct{ conn *fakeConn }
+
+func (tx fakeTx) Commit() error {
+	tx.conn.store.mu.Lock()
+	defer tx.conn.store.mu.Unlock()
+	for _, apply := range tx.conn.pending {
+		apply()
+	}
+	tx.conn.pending, tx.conn.inTx = nil, false
+	return nil
+}
+
+func (tx fakeTx) Rollback() error {
+	tx.conn.pending, tx.conn.inTx = nil, false
+	return nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	store := c.store
+	var apply func()
+	switch {
+	case strings.Contains(query, "INSERT INTO outbox"):
+		row := &fakeOutboxRow{
+			stream:    args[0].Value.(string),
+			eventType: args[1].Value.(string),
+			payload:   args[2].Value.([]byte),
+			createdAt: args[3].Value.(time.Time),
+		}
+		row.traceContext, _ = args[4].Value.([]byte)
+		apply = func() {
+			store.nextID++
+			row.id = store.nextID
+			store.rows = append(store.rows, row)
+		}
+	case strings.Contains(query, "UPDATE outbox SET published_at"):
+		id, at := args[0].Value.(int64), args[1].Value.(time.Time)
+		apply = func() {
+			for _, row := range store.rows {
+				if row.id == id {
+					row.publishedAt = &at
+				}
+			}
+		}
+	case strings.Contains(query, "DELETE FROM outbox"):
+		cutoff := args[0].Value.(time.Time)
+		apply = func() {
+			kept := store.rows[:0]
+			for _, row := range store.rows {
+				if row.publishedAt == nil || !row.publishedAt.Before(cutoff) {
+					kept = append(kept, row)
+				}
+			}
+			store.rows = kept
+		}
+	default:
+		return nil, fmt.Errorf("fake outbox: unexpected statement %q", query)
+	}
+	if c.inTx {
+		c.pending = append(c.pending, apply)
+	} else {
+		store.mu.Lock()
+		apply()
+		store.mu.Unlock()
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if !strings.Contains(query, "SELECT id, stream, payload, trace_context") {
+		return nil, fmt.Errorf("fake outbox: unexpected query %q", query)
+	}
+	limit := int(args[0].Value.(int64))
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+	var batch []*fakeOutboxRow
+	for _, row := range c.store.rows {
+		if row.publishedAt == nil {
+			batch = append(batch, row)
+		}
+	}
+	sort.Slice(batch, func(i, j int) bool { return batch[i].id < batch[j].id })
+	if len(batch) > limit {
+		batch = batch[:limit]
+	}
+	return &fakeRows{batch: batch}, nil
+}
+
+type fakeRows struct{ batch []*fakeOutboxRow }
+
+func (r *fakeRows) Columns() []string { return []string{"id", "stream", "payload", "trace_context"} }
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.batch) == 0 {
+		return io.EOF
+	}
+	row := r.batch[0]
+	r.batch = r.batch[1:]
+	dest[0], dest[1], dest[2], dest[3] = row.id, row.stream, row.payload, row.traceContext
+	return nil
+}
+
+func TestWriteOutbox(t *testing.T) {
+	db, store := newFakeOutboxDB(t)
+	ctx := context.Background()
+	created := NewOutboxEvent(ctx, AccountEventsStream, AccountCreated, AccountCreatedEvent{AccountNumber: "01234567"})
+	traced := created
+	traced.Trace = map[string]string{"traceparent": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"}
+	updated := NewOutboxEvent(ctx, AccountEventsStream, AccountUpdated, AccountUpdatedEvent{AccountNumber: "01234567"})
+
+	tests := []struct {
+		name     string
+		events   []OutboxEvent
+		commit   bool
+		expected []OutboxEvent
+	}{
+		{name: "committed events are queued in order", events: []OutboxEvent{created, updated}, commit: true, expected: []OutboxEvent{created, updated}},
+		{name: "trace context is stored with the event", events: []OutboxEvent{traced}, commit: true, expected: []OutboxEvent{traced}},
+		{name: "rolled back events are never queued", events: []OutboxEvent{created}, commit: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store.rows = nil
+			tx, err := db.Begin()
+			if err != nil {
+				t.Fatalf("failed to begin: %v", err)
+			}
+			if err := WriteOutbox(tx, tt.events...); err != nil {
+				t.Fatalf("[%s] unexpected error: %v", tt.name, err)
+			}
+			if tt.commit {
+				err = tx.Commit()
+			} else {
+				err = tx.Rollback()
+			}
+			if err != nil {
+				t.Fatalf("failed to end transaction: %v", err)
+			}
+
+			if len(store.rows) != len(tt.expected) {
+				t.Fatalf("[%s] expected %d rows got %d", tt.name, len(tt.expected), len(store.rows))
+			}
+			for i, want := range tt.expected {
+				row := store.rows[i]
+				var event Event
+				if err := json.Unmarshal(row.payload, &event); err != nil {
+					t.Fatalf("[%s] row %d payload is not an event: %v", tt.name, i, err)
+				}
+				if row.stream != want.Stream || row.eventType != want.Event.Type || event.ID != want.Event.ID {
+					t.Errorf("[%s] row %d expected %s %s %s got %s %s %s", tt.name, i, want.Stream, want.Event.Type, want.Event.ID, row.stream, row.eventType, event.ID)
+				}
+				var trace map[string]string
+				if row.traceContext != nil {
+					json.Unmarshal(row.traceContext, &trace)
+				}
+				if len(trace) != len(want.Trace) || trace["traceparent"] != want.Trace["traceparent"] {
+					t.Errorf("[%s] row %d expected trace context %v got %v", tt.name, i, want.Trace, trace)
+				}
+			}
+		})
+	}
+}
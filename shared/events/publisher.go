@@ -4,9 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"time"
 
+	"github.com/eaglebank/shared/tracing"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Publisher struct {
@@ -17,28 +20,61 @@ func NewPublisher(client *redis.Client) *Publisher {
 	return &Publisher{client: client}
 }
 
+// Publish appends an event to stream straight away, taking its correlation
+// and causation IDs from ctx.
 func (p *Publisher) Publish(ctx context.Context, stream, eventType string, data any) error {
-	event := Event{
-		Type:      eventType,
-		Timestamp: time.Now().UTC(),
-		Data:      data,
-	}
-
-	eventJSON, err := json.Marshal(event)
+	eventJSON, err := json.Marshal(newEvent(ctx, eventType, data))
 	if err != nil {
 		return fmt.Errorf("failed to marshal event: %w", err)
 	}
 
+	return p.publishPayload(ctx, stream, eventJSON)
+}
+
+// publishPayload appends an already-serialised Event to the stream. It is
+// shared by Publish and the outbox Relay, which stores the envelope verbatim.
+// The publish is recorded as a producer span whose trace context travels in
+// the message's fields alongside the event, for the consumer to continue.
+func (p *Publisher) publishPayload(ctx context.Context, stream string, eventJSON []byte) error {
+	return p.publishFields(ctx, stream, map[string]any{"event": eventJSON})
+}
+
+// publishFields appends a message made of values plus the trace context.
+func (p *Publisher) publishFields(ctx context.Context, stream string, values map[string]any) error {
+	ctx, span := tracing.Tracer().Start(ctx, stream+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKey.String("redis"),
+			semconv.MessagingDestinationName(stream),
+			semconv.MessagingOperationTypePublish,
+		),
+	)
+	defer span.End()
+
+	for key, value := range tracing.Inject(ctx) {
+		values[key] = value
+	}
 	args := &redis.XAddArgs{
 		Stream: stream,
-		Values: map[string]any{
-			"event": eventJSON,
-		},
+		Values: values,
 	}
 
 	if _, err := p.client.XAdd(ctx, args).Result(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "publish failed")
 		return fmt.Errorf("failed to publish event: %w", err)
 	}
 
 	return nil
 }
+
+// messageTrace returns the trace context publishPayload stored in a message.
+func messageTrace(values map[string]any) map[string]string {
+	fields := make(map[string]string, len(values))
+	for key, value := range values {
+		if s, ok := value.(string); ok && key != "event" && key != replayGroupField {
+			fields[key] = s
+		}
+	}
+	return fields
+}
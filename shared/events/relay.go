@@ -0,0 +1,187 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/eaglebank/shared/tracing"
+)
+
+// Relay drains the transactional outbox into the Redis streams. Rows are
+// marked published only after XADD succeeds, so delivery is at-least-once:
+// a crash between the two steps re-publishes the row on the next pass and
+// consumers are expected to be idempotent.
+type Relay struct {
+	db           *sql.DB
+	publisher    *Publisher
+	batchSize    int
+	pollInterval time.Duration
+	retention    time.Duration
+	lastPurge    time.Time
+}
+
+type RelayConfig struct {
+	BatchSize    int
+	PollInterval time.Duration
+	// Retention is how long published rows are kept before being purged.
+	Retention time.Duration
+}
+
+// RelayStats reports how far the relay is behind the outbox table.
+type RelayStats struct {
+	Pending    int64   `json:"pending"`
+	LagSeconds float64 `json:"lagSeconds"`
+}
+
+type outboxRow struct {
+	id           int64
+	stream       string
+	payload      []byte
+	traceContext []byte
+}
+
+// context returns ctx carrying the trace context of the write that queued
+// the row, if it was traced.
+func (row outboxRow) context(ctx context.Context) context.Context {
+	var fields map[string]string
+	if len(row.traceContext) == 0 || json.Unmarshal(row.traceContext, &fields) != nil {
+		return ctx
+	}
+	return tracing.Extract(ctx, fields)
+}
+
+func NewRelay(db *sql.DB, publisher *Publisher, config RelayConfig) *Relay {
+	if config.BatchSize == 0 {
+		config.BatchSize = 100
+	}
+	if config.PollInterval == 0 {
+		config.PollInterval = 500 * time.Millisecond
+	}
+	if config.Retention == 0 {
+		config.Retention = 24 * time.Hour
+	}
+
+	return &Relay{
+		db:           db,
+		publisher:    publisher,
+		batchSize:    config.BatchSize,
+		pollInterval: config.PollInterval,
+		retention:    config.Retention,
+	}
+}
+
+func (r *Relay) Start(ctx context.Context) error {
+	slog.Info("Outbox relay started", "batch", r.batchSize, "interval", r.pollInterval)
+
+	// A batch is finished even if ctx is cancelled partway, so rows already
+	// published are marked as such rather than published again on restart.
+	work := context.WithoutCancel(ctx)
+	for {
+		n, err := r.drain(work)
+		if err != nil {
+			slog.Error("Error draining outbox", "err", err)
+		}
+		r.purge(work)
+
+		// Keep draining while full batches are coming back; otherwise wait.
+		if ctx.Err() == nil && err == nil && n == r.batchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			slog.Info("Outbox relay stopping")
+			return ctx.Err()
+		case <-time.After(r.pollInterval):
+		}
+	}
+}
+
+// drain publishes one batch of unpublished outbox rows in id order and
+// returns the number of rows published.
+func (r *Relay) drain(ctx context.Context) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin outbox transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// SKIP LOCKED lets several replicas run a relay without blocking each other.
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, stream, payload, trace_context
+		FROM outbox
+		WHERE published_at IS NULL
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`, r.batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read outbox: %w", err)
+	}
+
+	var batch []outboxRow
+	for rows.Next() {
+		var row outboxRow
+		if err := rows.Scan(&row.id, &row.stream, &row.payload, &row.traceContext); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan outbox row: %w", err)
+		}
+		batch = append(batch, row)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read outbox: %w", err)
+	}
+
+	published := 0
+	var publishErr error
+	for _, row := range batch {
+		// Stop at the first failure so later rows are never published ahead of it.
+		if publishErr = r.publisher.publishPayload(row.context(ctx), row.stream, row.payload); publishErr != nil {
+			break
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE outbox SET published_at = $2 WHERE id = $1`, row.id, time.Now().UTC()); err != nil {
+			return 0, fmt.Errorf("failed to mark outbox row %d published: %w", row.id, err)
+		}
+		published++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit outbox transaction: %w", err)
+	}
+	return published, publishErr
+}
+
+// purge deletes published rows older than the retention period. It runs at
+// most once per retention/24 so the delete stays off the hot path.
+func (r *Relay) purge(ctx context.Context) {
+	if time.Since(r.lastPurge) < r.retention/24 {
+		return
+	}
+	r.lastPurge = time.Now()
+	cutoff := time.Now().UTC().Add(-r.retention)
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM outbox WHERE published_at < $1`, cutoff); err != nil {
+		slog.Error("Failed to purge outbox", "err", err)
+	}
+}
+
+// Stats returns the number of unpublished rows and the age of the oldest one.
+func (r *Relay) Stats(ctx context.Context) (RelayStats, error) {
+	var stats RelayStats
+	var oldest sql.NullTime
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), MIN(created_at)
+		FROM outbox
+		WHERE published_at IS NULL
+	`).Scan(&stats.Pending, &oldest)
+	if err != nil {
+		return stats, fmt.Errorf("failed to read outbox stats: %w", err)
+	}
+	if oldest.Valid {
+		stats.LagSeconds = time.Now().UTC().Sub(oldest.Time).Seconds()
+	}
+	return stats, nil
+}
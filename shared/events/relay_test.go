@@ -0,0 +1,126 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// queueOutbox commits one outbox row per stream, in order, and returns the
+// event IDs written.
+func queueOutbox(t *testing.T, ctx context.Context, relay *Relay, streams ...string) []string {
+	t.Helper()
+	tx, err := relay.db.Begin()
+	if err != nil {
+		t.Fatalf("failed to begin: %v", err)
+	}
+	var ids []string
+	for _, stream := range streams {
+		e := NewOutboxEvent(ctx, stream, TransactionCreated, map[string]string{"stream": stream})
+		if err := WriteOutbox(tx, e); err != nil {
+			t.Fatalf("failed to write outbox: %v", err)
+		}
+		ids = append(ids, e.Event.ID)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	return ids
+}
+
+// streamEventIDs returns the IDs of the events on a stream in publish order.
+func streamEventIDs(t *testing.T, ctx context.Context, client *redis.Client, stream string) []string {
+	t.Helper()
+	messages, err := client.XRange(ctx, stream, "-", "+").Result()
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", stream, err)
+	}
+	var ids []string
+	for _, msg := range messages {
+		var event Event
+		if err := json.Unmarshal([]byte(msg.Values["event"].(string)), &event); err != nil {
+			t.Fatalf("failed to decode event on %s: %v", stream, err)
+		}
+		ids = append(ids, event.ID)
+	}
+	return ids
+}
+
+func TestRelayDrain(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("publishes in id order batch by batch and marks rows sent", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		t.Cleanup(func() { client.Close() })
+		db, store := newFakeOutboxDB(t)
+		relay := NewRelay(db, NewPublisher(client), RelayConfig{BatchSize: 2})
+
+		ids := queueOutbox(t, ctx, relay, TransactionEventsStream, TransactionEventsStream, TransactionEventsStream)
+
+		for i, expected := range []int{2, 1, 0} {
+			n, err := relay.drain(ctx)
+			if err != nil {
+				t.Fatalf("drain %d: unexpected error: %v", i, err)
+			}
+			if n != expected {
+				t.Errorf("drain %d: expected %d rows published got %d", i, expected, n)
+			}
+		}
+		if got := streamEventIDs(t, ctx, client, TransactionEventsStream); !reflect.DeepEqual(got, ids) {
+			t.Errorf("expected events in outbox order %v got %v", ids, got)
+		}
+		if pending := store.unpublished(); len(pending) != 0 {
+			t.Errorf("expected every row marked sent, %d left", len(pending))
+		}
+	})
+
+	t.Run("a publish failure leaves the row and those after it for retry", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		t.Cleanup(func() { client.Close() })
+		db, store := newFakeOutboxDB(t)
+		relay := NewRelay(db, NewPublisher(client), RelayConfig{BatchSize: 10})
+
+		// XADD to a key holding a string fails with WRONGTYPE.
+		const broken = "broken.events"
+		mr.Set(broken, "not a stream")
+		ids := queueOutbox(t, ctx, relay, TransactionEventsStream, broken, TransactionEventsStream)
+
+		n, err := relay.drain(ctx)
+		if err == nil {
+			t.Fatal("expected the publish failure to be returned")
+		}
+		if n != 1 {
+			t.Errorf("expected 1 row published before the failure got %d", n)
+		}
+		if got, want := store.unpublished(), []string{broken, TransactionEventsStream}; !reflect.DeepEqual(got, want) {
+			t.Errorf("expected rows %v left for retry got %v", want, got)
+		}
+		if got := streamEventIDs(t, ctx, client, TransactionEventsStream); !reflect.DeepEqual(got, ids[:1]) {
+			t.Errorf("expected no event published past the failure, got %v", got)
+		}
+
+		mr.Del(broken)
+		n, err = relay.drain(ctx)
+		if err != nil {
+			t.Fatalf("retry: unexpected error: %v", err)
+		}
+		if n != 2 {
+			t.Errorf("retry: expected 2 rows published got %d", n)
+		}
+		if got := streamEventIDs(t, ctx, client, broken); !reflect.DeepEqual(got, ids[1:2]) {
+			t.Errorf("retry: expected %v on %s got %v", ids[1:2], broken, got)
+		}
+		if got, want := streamEventIDs(t, ctx, client, TransactionEventsStream), []string{ids[0], ids[2]}; !reflect.DeepEqual(got, want) {
+			t.Errorf("retry: expected %v on %s got %v", want, TransactionEventsStream, got)
+		}
+		if pending := store.unpublished(); len(pending) != 0 {
+			t.Errorf("retry: expected every row marked sent, %v left", pending)
+		}
+	})
+}
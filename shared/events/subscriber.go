@@ -3,11 +3,20 @@ package events
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"sync/atomic"
 	"time"
 
+	"github.com/eaglebank/shared/correlation"
+	"github.com/eaglebank/shared/logging"
+	"github.com/eaglebank/shared/tracing"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Handler func(ctx context.Context, event Event) error
@@ -20,6 +29,20 @@ type Subscriber struct {
 	handler       Handler
 	batchSize     int64
 	blockDuration time.Duration
+	maxDeliveries int64
+	claimInterval time.Duration
+	retryBackoff  time.Duration
+	maxBackoff    time.Duration
+	stallTimeout  time.Duration
+	maxLag        int64
+	lastClaim     time.Time
+	// progress is when the subscriber last polled the stream or finished a
+	// message, in Unix nanoseconds; stopped is set once Start returns.
+	progress atomic.Int64
+	stopped  atomic.Bool
+	// failures holds the last handler error per pending message ID so the
+	// reason can be recorded if the message ends up in the dead-letter stream.
+	failures map[string]string
 }
 
 type SubscriberConfig struct {
@@ -29,8 +52,33 @@ type SubscriberConfig struct {
 	Handler       Handler
 	BatchSize     int64
 	BlockDuration time.Duration
+	// MaxDeliveries is how many times a message is handed to Handler before
+	// it is moved to the dead-letter stream.
+	MaxDeliveries int64
+	// ClaimInterval is how often the pending entries list is scanned for
+	// messages that are due a retry.
+	ClaimInterval time.Duration
+	// RetryBackoff is the idle time before the first retry; it doubles with
+	// each delivery up to MaxBackoff.
+	RetryBackoff time.Duration
+	MaxBackoff   time.Duration
+	// StallTimeout is how long the subscriber may go without polling the
+	// stream or finishing a message before CheckAlive fails.
+	StallTimeout time.Duration
+	// MaxLag is how many undelivered entries the consumer group may fall
+	// behind by before CheckLag fails.
+	MaxLag int64
 }
 
+// permanentError marks a message that can never be handled (e.g. it cannot be
+// decoded), so retrying it is pointless and it goes straight to the DLQ.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
 func NewSubscriber(client *redis.Client, config SubscriberConfig) *Subscriber {
 	if config.BatchSize == 0 {
 		config.BatchSize = 10
@@ -38,8 +86,26 @@ func NewSubscriber(client *redis.Client, config SubscriberConfig) *Subscriber {
 	if config.BlockDuration == 0 {
 		config.BlockDuration = 5 * time.Second
 	}
+	if config.MaxDeliveries == 0 {
+		config.MaxDeliveries = 5
+	}
+	if config.ClaimInterval == 0 {
+		config.ClaimInterval = 10 * time.Second
+	}
+	if config.RetryBackoff == 0 {
+		config.RetryBackoff = 10 * time.Second
+	}
+	if config.MaxBackoff == 0 {
+		config.MaxBackoff = 5 * time.Minute
+	}
+	if config.StallTimeout == 0 {
+		config.StallTimeout = 2 * time.Minute
+	}
+	if config.MaxLag == 0 {
+		config.MaxLag = 1000
+	}
 
-	return &Subscriber{
+	s := &Subscriber{
 		client:        client,
 		group:         config.Group,
 		consumer:      config.Consumer,
@@ -47,26 +113,47 @@ func NewSubscriber(client *redis.Client, config SubscriberConfig) *Subscriber {
 		handler:       config.Handler,
 		batchSize:     config.BatchSize,
 		blockDuration: config.BlockDuration,
+		maxDeliveries: config.MaxDeliveries,
+		claimInterval: config.ClaimInterval,
+		retryBackoff:  config.RetryBackoff,
+		maxBackoff:    config.MaxBackoff,
+		stallTimeout:  config.StallTimeout,
+		maxLag:        config.MaxLag,
+		failures:      make(map[string]string),
 	}
+	s.markProgress()
+	return s
 }
 
 func (s *Subscriber) Start(ctx context.Context) error {
+	defer s.stopped.Store(true)
+
 	// Create consumer group if it doesn't exist
 	err := s.client.XGroupCreateMkStream(ctx, s.stream, s.group, "0").Err()
 	if err != nil && err.Error() != "BUSYGROUP Consumer Group name already exists" {
 		return fmt.Errorf("failed to create consumer group: %w", err)
 	}
 
-	log.Printf("Subscriber started: stream=%s, group=%s, consumer=%s", s.stream, s.group, s.consumer)
+	slog.Info("Subscriber started", "stream", s.stream, "group", s.group, "consumer", s.consumer)
 
 	for {
 		select {
 		case <-ctx.Done():
-			log.Printf("Subscriber stopping: %s", s.stream)
+			slog.Info("Subscriber stopping", "stream", s.stream)
 			return ctx.Err()
 		default:
-			if err := s.readMessages(ctx); err != nil {
-				log.Printf("Error reading messages: %v", err)
+			s.markProgress()
+			if time.Since(s.lastClaim) >= s.claimInterval {
+				s.lastClaim = time.Now()
+				if err := s.reclaimPending(ctx); err != nil {
+					slog.Error("Error reclaiming pending messages", "stream", s.stream, "err", err)
+				}
+				if err := s.recordGroupInfo(ctx); err != nil {
+					slog.Error("Error recording consumer group metrics", "stream", s.stream, "err", err)
+				}
+			}
+			if err := s.readMessages(ctx); err != nil && ctx.Err() == nil {
+				slog.Error("Error reading messages", "stream", s.stream, "err", err)
 				time.Sleep(time.Second)
 			}
 		}
@@ -91,32 +178,229 @@ func (s *Subscriber) readMessages(ctx context.Context) error {
 
 	for _, stream := range streams {
 		for _, message := range stream.Messages {
-			if err := s.processMessage(ctx, message); err != nil {
-				log.Printf("Failed to process message %s: %v", message.ID, err)
-				// Don't ACK failed messages - they'll be retried
-				continue
+			// Once stopping, leave the rest of the batch pending to be
+			// reclaimed on restart.
+			if ctx.Err() != nil {
+				return nil
 			}
+			s.handleMessage(ctx, message, 1)
+		}
+	}
 
-			// ACK successful message
-			if err := s.client.XAck(ctx, s.stream, s.group, message.ID).Err(); err != nil {
-				log.Printf("Failed to ACK message %s: %v", message.ID, err)
-			}
+	return nil
+}
+
+// handleMessage runs the handler for a message on its nth delivery and ACKs,
+// dead-letters or leaves it pending for a later retry. Each delivery is a
+// consumer span, the child of the span that published the message. The
+// message is seen through even if ctx is cancelled meanwhile, so stopping
+// the subscriber never abandons a handler halfway.
+func (s *Subscriber) handleMessage(ctx context.Context, message redis.XMessage, deliveries int64) {
+	ctx = context.WithoutCancel(ctx)
+	if group, ok := message.Values[replayGroupField].(string); ok && group != s.group {
+		// Replayed for another group; this one handled the original.
+		s.markProgress()
+		if err := s.client.XAck(ctx, s.stream, s.group, message.ID).Err(); err != nil {
+			slog.ErrorContext(ctx, "Failed to ACK message replayed for another group", "stream", s.stream, "message_id", message.ID, "err", err)
+		}
+		return
+	}
+	ctx, span := tracing.Tracer().Start(tracing.Extract(ctx, messageTrace(message.Values)), s.stream+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKey.String("redis"),
+			semconv.MessagingDestinationName(s.stream),
+			semconv.MessagingOperationTypeDeliver,
+			semconv.MessagingMessageID(message.ID),
+			attribute.Int64("messaging.delivery_count", deliveries),
+		),
+	)
+	defer span.End()
+	ctx = logging.With(ctx, "stream", s.stream, "message_id", message.ID, "delivery", deliveries)
+
+	start := time.Now()
+	err := s.processMessage(ctx, message)
+	handlerDuration.WithLabelValues(s.stream, s.group).Observe(time.Since(start).Seconds())
+	s.markProgress()
+	if err == nil {
+		delete(s.failures, message.ID)
+		if err := s.client.XAck(ctx, s.stream, s.group, message.ID).Err(); err != nil {
+			slog.ErrorContext(ctx, "Failed to ACK message", "err", err)
 		}
+		return
+	}
+
+	handlerFailures.WithLabelValues(s.stream, s.group).Inc()
+	span.RecordError(err)
+	span.SetStatus(codes.Error, "handler failed")
+	slog.ErrorContext(ctx, "Failed to process message", "max_deliveries", s.maxDeliveries, "err", err)
+	var permanent *permanentError
+	if errors.As(err, &permanent) || deliveries >= s.maxDeliveries {
+		s.deadLetter(ctx, message, err.Error(), deliveries)
+		return
+	}
+	// Leave the message un-ACKed; reclaimPending retries it after a backoff.
+	s.failures[message.ID] = err.Error()
+}
+
+func (s *Subscriber) markProgress() {
+	s.progress.Store(time.Now().UnixNano())
+}
+
+// CheckAlive returns an error if the subscriber has stopped, or has neither
+// polled the stream nor finished a message within its stall timeout.
+func (s *Subscriber) CheckAlive(context.Context) error {
+	if s.stopped.Load() {
+		return fmt.Errorf("subscriber for %s has stopped", s.stream)
+	}
+	if idle := time.Since(time.Unix(0, s.progress.Load())); idle > s.stallTimeout {
+		return fmt.Errorf("subscriber for %s has made no progress for %s", s.stream, idle.Round(time.Second))
+	}
+	return nil
+}
+
+// CheckLag returns an error if the consumer group is more than MaxLag
+// entries behind the stream.
+func (s *Subscriber) CheckLag(ctx context.Context) error {
+	group, err := s.groupInfo(ctx)
+	if err != nil {
+		return err
+	}
+	if group.Lag > s.maxLag {
+		return fmt.Errorf("consumer group %s is %d entries behind on %s", s.group, group.Lag, s.stream)
+	}
+	return nil
+}
+
+// reclaimPending scans the group's pending entries list for messages whose
+// idle time exceeds the backoff for their delivery count. Those that have
+// used up their deliveries are dead-lettered; the rest are claimed by this
+// consumer and retried.
+func (s *Subscriber) reclaimPending(ctx context.Context) error {
+	pending, err := s.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: s.stream,
+		Group:  s.group,
+		Idle:   s.retryBackoff,
+		Start:  "-",
+		End:    "+",
+		Count:  s.batchSize * 10,
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("failed to read pending entries: %w", err)
 	}
 
+	for _, entry := range pending {
+		if entry.RetryCount >= s.maxDeliveries {
+			s.deadLetterPending(ctx, entry)
+			continue
+		}
+		if entry.Idle < s.backoff(entry.RetryCount) {
+			continue
+		}
+		claimed, err := s.client.XClaim(ctx, &redis.XClaimArgs{
+			Stream:   s.stream,
+			Group:    s.group,
+			Consumer: s.consumer,
+			MinIdle:  entry.Idle,
+			Messages: []string{entry.ID},
+		}).Result()
+		if err != nil {
+			slog.Error("Failed to claim message", "stream", s.stream, "message_id", entry.ID, "err", err)
+			continue
+		}
+		// XCLAIM increments the delivery counter, so this is delivery RetryCount+1.
+		for _, message := range claimed {
+			if ctx.Err() != nil {
+				return nil
+			}
+			s.handleMessage(ctx, message, entry.RetryCount+1)
+		}
+	}
 	return nil
 }
 
+// backoff returns the idle time a message must reach before its next delivery.
+func (s *Subscriber) backoff(deliveries int64) time.Duration {
+	d := s.retryBackoff
+	for i := int64(1); i < deliveries && d < s.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > s.maxBackoff {
+		d = s.maxBackoff
+	}
+	return d
+}
+
+// deadLetterPending moves a pending entry that has exhausted its deliveries
+// to the DLQ, fetching its body from the stream first.
+func (s *Subscriber) deadLetterPending(ctx context.Context, entry redis.XPendingExt) {
+	messages, err := s.client.XRangeN(ctx, s.stream, entry.ID, entry.ID, 1).Result()
+	if err != nil {
+		slog.Error("Failed to read pending message", "stream", s.stream, "message_id", entry.ID, "err", err)
+		return
+	}
+	if len(messages) == 0 {
+		// The entry was trimmed from the stream; nothing left to dead-letter.
+		delete(s.failures, entry.ID)
+		s.client.XAck(ctx, s.stream, s.group, entry.ID)
+		return
+	}
+	reason, ok := s.failures[entry.ID]
+	if !ok {
+		reason = "max deliveries exceeded"
+	}
+	ctx = logging.With(ctx, "stream", s.stream, "message_id", entry.ID, "delivery", entry.RetryCount)
+	s.deadLetter(ctx, messages[0], reason, entry.RetryCount)
+}
+
+// deadLetter copies the message to <stream>.dlq with the failure reason and
+// ACKs it on the source stream so it leaves the pending entries list. ctx
+// carries the message's log fields.
+func (s *Subscriber) deadLetter(ctx context.Context, message redis.XMessage, reason string, deliveries int64) {
+	eventData, _ := message.Values["event"].(string)
+	err := s.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: DeadLetterStream(s.stream),
+		Values: map[string]any{
+			"event":      eventData,
+			"sourceId":   message.ID,
+			"group":      s.group,
+			"consumer":   s.consumer,
+			"reason":     reason,
+			"deliveries": deliveries,
+			"failedAt":   time.Now().UTC().Format(time.RFC3339Nano),
+		},
+	}).Err()
+	if err != nil {
+		// Keep the message pending so the next reclaim pass tries again.
+		slog.ErrorContext(ctx, "Failed to dead-letter message", "err", err)
+		return
+	}
+	delete(s.failures, message.ID)
+	if err := s.client.XAck(ctx, s.stream, s.group, message.ID).Err(); err != nil {
+		slog.ErrorContext(ctx, "Failed to ACK dead-lettered message", "err", err)
+	}
+	slog.WarnContext(ctx, "Message dead-lettered", "dlq", DeadLetterStream(s.stream), "reason", reason)
+}
+
 func (s *Subscriber) processMessage(ctx context.Context, message redis.XMessage) error {
 	eventData, ok := message.Values["event"].(string)
 	if !ok {
-		return fmt.Errorf("invalid message format")
+		return &permanentError{fmt.Errorf("invalid message format")}
 	}
 
 	var event Event
 	if err := json.Unmarshal([]byte(eventData), &event); err != nil {
-		return fmt.Errorf("failed to unmarshal event: %w", err)
+		return &permanentError{fmt.Errorf("failed to unmarshal event: %w", err)}
 	}
 
-	return s.handler(ctx, event)
+	// Anything the handler publishes continues the event's chain, and
+	// anything it logs names the event
+	ids := event.Caused()
+	ctx = logging.With(ctx,
+		"event_id", event.ID,
+		"event_type", event.Type,
+		"correlation_id", ids.CorrelationID,
+		"causation_id", event.CausationID,
+	)
+	return s.handler(correlation.NewContext(ctx, ids), event)
 }
@@ -0,0 +1,98 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestSubscriberChecks(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	subscriber := NewSubscriber(client, SubscriberConfig{
+		Group:        "test-group",
+		Stream:       TransactionEventsStream,
+		Handler:      func(context.Context, Event) error { return nil },
+		StallTimeout: time.Minute,
+		MaxLag:       2,
+	})
+
+	if err := subscriber.CheckLag(ctx); err == nil {
+		t.Error("expected the lag check to fail before the consumer group exists")
+	}
+	if err := client.XGroupCreateMkStream(ctx, TransactionEventsStream, "test-group", "0").Err(); err != nil {
+		t.Fatalf("failed to create group: %v", err)
+	}
+	publisher := NewPublisher(client)
+	for i := 0; i < 3; i++ {
+		if err := publisher.Publish(ctx, TransactionEventsStream, TransactionCreated, map[string]string{}); err != nil {
+			t.Fatalf("failed to publish: %v", err)
+		}
+		err := subscriber.CheckLag(ctx)
+		if want := i >= 2; (err != nil) != want {
+			t.Errorf("with %d undelivered entries expected failure %v, got %v", i+1, want, err)
+		}
+	}
+
+	if err := subscriber.CheckAlive(ctx); err != nil {
+		t.Errorf("expected a new subscriber to be alive, got %v", err)
+	}
+	subscriber.progress.Store(time.Now().Add(-2 * time.Minute).UnixNano())
+	if err := subscriber.CheckAlive(ctx); err == nil {
+		t.Error("expected a stalled subscriber to fail the liveness check")
+	}
+	subscriber.markProgress()
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	subscriber.Start(cancelled)
+	if err := subscriber.CheckAlive(ctx); err == nil {
+		t.Error("expected a stopped subscriber to fail the liveness check")
+	}
+}
+
+func TestSubscriberFinishesMessageOnStop(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	handling := make(chan struct{})
+	var handlerErr error
+	subscriber := NewSubscriber(client, SubscriberConfig{
+		Group:         "test-group",
+		Stream:        TransactionEventsStream,
+		BlockDuration: 10 * time.Millisecond,
+		Handler: func(ctx context.Context, _ Event) error {
+			close(handling)
+			time.Sleep(50 * time.Millisecond)
+			handlerErr = ctx.Err()
+			return handlerErr
+		},
+	})
+	if err := NewPublisher(client).Publish(context.Background(), TransactionEventsStream, TransactionCreated, map[string]string{}); err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		subscriber.Start(ctx)
+		close(stopped)
+	}()
+	<-handling
+	cancel()
+	<-stopped
+
+	if handlerErr != nil {
+		t.Errorf("expected the handler's context to outlive the stop, got %v", handlerErr)
+	}
+	pending, err := client.XPending(context.Background(), TransactionEventsStream, "test-group").Result()
+	if err != nil || pending.Count != 0 {
+		t.Errorf("expected the message to be acknowledged, got %v %v", pending, err)
+	}
+}
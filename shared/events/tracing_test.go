@@ -0,0 +1,81 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/eaglebank/shared/correlation"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestEventCarriesTraceAndCorrelation(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	ctx := correlation.NewContext(context.Background(), correlation.IDs{CorrelationID: "req-1", CausationID: "req-1"})
+	ctx, request := provider.Tracer("test").Start(ctx, "POST /v1/accounts/:accountNumber/transactions")
+	if err := NewPublisher(client).Publish(ctx, TransactionEventsStream, TransactionCreated, map[string]string{"transactionId": "tan-1"}); err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+	request.End()
+
+	messages, err := client.XRange(context.Background(), TransactionEventsStream, "-", "+").Result()
+	if err != nil || len(messages) != 1 {
+		t.Fatalf("expected one message, got %v %v", messages, err)
+	}
+
+	var handled context.Context
+	var event Event
+	subscriber := NewSubscriber(client, SubscriberConfig{
+		Group:  "test-group",
+		Stream: TransactionEventsStream,
+		Handler: func(ctx context.Context, e Event) error {
+			handled, event = ctx, e
+			return nil
+		},
+	})
+	subscriber.handleMessage(context.Background(), messages[0], 1)
+
+	if handled == nil {
+		t.Fatal("expected the handler to run")
+	}
+	if got := trace.SpanContextFromContext(handled).TraceID(); got != request.SpanContext().TraceID() {
+		t.Errorf("expected the consumer to continue trace %s, got %s", request.SpanContext().TraceID(), got)
+	}
+	if event.CorrelationID != "req-1" || event.CausationID != "req-1" {
+		t.Errorf("expected the event to name request req-1, got %+v", event)
+	}
+	if got := correlation.FromContext(handled); got.CorrelationID != "req-1" || got.CausationID != event.ID {
+		t.Errorf("expected the handler's work to be caused by %s in chain req-1, got %+v", event.ID, got)
+	}
+
+	var publish, process sdktrace.ReadOnlySpan
+	for _, span := range spans.Ended() {
+		switch span.SpanKind() {
+		case trace.SpanKindProducer:
+			publish = span
+		case trace.SpanKindConsumer:
+			process = span
+		}
+	}
+	if publish == nil || process == nil {
+		t.Fatalf("expected a producer and a consumer span, got %d spans", len(spans.Ended()))
+	}
+	if process.Parent().SpanID() != publish.SpanContext().SpanID() {
+		t.Errorf("expected the consumer span to be the child of the producer span")
+	}
+}
@@ -0,0 +1,185 @@
+// Package health runs the checks behind a service's /livez and /readyz
+// endpoints. Liveness says whether the process is still doing its work, so an
+// orchestrator knows when to restart it; readiness says whether the
+// dependencies it needs to serve requests are usable, so it knows when to
+// send it traffic.
+package health
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusDown = "down"
+)
+
+// checkTimeout bounds each check, so one hung dependency cannot hold up a
+// probe.
+const checkTimeout = 2 * time.Second
+
+// Result is the outcome of a check. Checks that cover several components,
+// such as the gateway's check of a service, report each under Checks.
+type Result struct {
+	Status string            `json:"status"`
+	Error  string            `json:"error,omitempty"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+// OK reports whether the check passed.
+func (r Result) OK() bool { return r.Status == StatusOK }
+
+// Checker checks one component.
+type Checker interface {
+	Check(ctx context.Context) Result
+}
+
+// CheckFunc adapts a function to a Checker. The component is down when it
+// returns an error.
+type CheckFunc func(ctx context.Context) error
+
+func (f CheckFunc) Check(ctx context.Context) Result {
+	if err := f(ctx); err != nil {
+		return Result{Status: StatusDown, Error: err.Error()}
+	}
+	return Result{Status: StatusOK}
+}
+
+// DB checks that the database answers a ping.
+func DB(db *sql.DB) Checker {
+	return CheckFunc(db.PingContext)
+}
+
+// Redis checks that Redis answers a ping.
+func Redis(client *goredis.Client) Checker {
+	return CheckFunc(func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	})
+}
+
+// Registry holds a service's liveness and readiness checks.
+type Registry struct {
+	mu        sync.RWMutex
+	liveness  map[string]Checker
+	readiness map[string]Checker
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		liveness:  make(map[string]Checker),
+		readiness: make(map[string]Checker),
+	}
+}
+
+// AddLiveness registers a check that fails only when restarting the process
+// would help, such as a stopped subscriber.
+func (r *Registry) AddLiveness(name string, checker Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.liveness[name] = checker
+}
+
+// AddReadiness registers a check of something the service needs to serve
+// requests, such as its database.
+func (r *Registry) AddReadiness(name string, checker Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.readiness[name] = checker
+}
+
+// Live runs the liveness checks.
+func (r *Registry) Live(ctx context.Context) Result {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return run(ctx, r.liveness)
+}
+
+// Ready runs the readiness checks.
+func (r *Registry) Ready(ctx context.Context) Result {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return run(ctx, r.readiness)
+}
+
+// run runs checks concurrently, each within checkTimeout. The result is down
+// if any check is.
+func run(ctx context.Context, checks map[string]Checker) Result {
+	result := Result{Status: StatusOK}
+	if len(checks) == 0 {
+		return result
+	}
+	result.Checks = make(map[string]Result, len(checks))
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for name, checker := range checks {
+		wg.Add(1)
+		go func(name string, checker Checker) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+			res := checker.Check(ctx)
+			mu.Lock()
+			defer mu.Unlock()
+			result.Checks[name] = res
+			if !res.OK() {
+				result.Status = StatusDown
+			}
+		}(name, checker)
+	}
+	wg.Wait()
+	return result
+}
+
+// Register mounts /livez and /readyz on router. Each answers 200 with the
+// result of its checks when they pass and 503 when any fails.
+func (r *Registry) Register(router gin.IRoutes) {
+	router.GET("/livez", handler(r.Live))
+	router.GET("/readyz", handler(r.Ready))
+}
+
+func handler(check func(context.Context) Result) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result := check(c.Request.Context())
+		status := http.StatusOK
+		if !result.OK() {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, result)
+	}
+}
+
+// cached reuses a checker's result for a while.
+type cached struct {
+	checker Checker
+	ttl     time.Duration
+
+	mu      sync.Mutex
+	result  Result
+	checked time.Time
+}
+
+// Cached returns a Checker that runs checker at most once per ttl and
+// answers with the last result in between. Concurrent callers wait for the
+// same run, so frequent probes cost one check per ttl.
+func Cached(checker Checker, ttl time.Duration) Checker {
+	return &cached{checker: checker, ttl: ttl}
+}
+
+func (c *cached) Check(ctx context.Context) Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.checked.IsZero() || time.Since(c.checked) >= c.ttl {
+		c.result = c.checker.Check(ctx)
+		c.checked = time.Now()
+	}
+	return c.result
+}
@@ -0,0 +1,100 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRegistryHandlers(t *testing.T) {
+	up := CheckFunc(func(context.Context) error { return nil })
+	down := CheckFunc(func(context.Context) error { return errors.New("connection refused") })
+
+	tests := []struct {
+		name           string
+		path           string
+		liveness       map[string]Checker
+		readiness      map[string]Checker
+		expectedStatus int
+		expectedChecks map[string]string
+	}{
+		{name: "no checks - live", path: "/livez", expectedStatus: http.StatusOK},
+		{name: "all ready", path: "/readyz", readiness: map[string]Checker{"postgres": up, "redis": up}, expectedStatus: http.StatusOK, expectedChecks: map[string]string{"postgres": StatusOK, "redis": StatusOK}},
+		{name: "one dependency down", path: "/readyz", readiness: map[string]Checker{"postgres": down, "redis": up}, expectedStatus: http.StatusServiceUnavailable, expectedChecks: map[string]string{"postgres": StatusDown, "redis": StatusOK}},
+		{name: "readiness failure does not affect liveness", path: "/livez", liveness: map[string]Checker{"subscriber": up}, readiness: map[string]Checker{"postgres": down}, expectedStatus: http.StatusOK, expectedChecks: map[string]string{"subscriber": StatusOK}},
+		{name: "stopped subscriber", path: "/livez", liveness: map[string]Checker{"subscriber": down}, expectedStatus: http.StatusServiceUnavailable, expectedChecks: map[string]string{"subscriber": StatusDown}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewRegistry()
+			for name, checker := range tt.liveness {
+				registry.AddLiveness(name, checker)
+			}
+			for name, checker := range tt.readiness {
+				registry.AddReadiness(name, checker)
+			}
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			registry.Register(r)
+
+			req, _ := http.NewRequest(http.MethodGet, tt.path, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("[%s] expected status %d got %d", tt.name, tt.expectedStatus, w.Code)
+			}
+			var result Result
+			if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+				t.Fatalf("[%s] failed to decode body: %v", tt.name, err)
+			}
+			if len(result.Checks) != len(tt.expectedChecks) {
+				t.Errorf("[%s] expected checks %v got %v", tt.name, tt.expectedChecks, result.Checks)
+			}
+			for name, status := range tt.expectedChecks {
+				if result.Checks[name].Status != status {
+					t.Errorf("[%s] expected %s to be %s got %+v", tt.name, name, status, result.Checks[name])
+				}
+			}
+		})
+	}
+}
+
+func TestCheckTimeout(t *testing.T) {
+	registry := NewRegistry()
+	registry.AddReadiness("hung", CheckFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if result := registry.Ready(ctx); result.OK() || result.Checks["hung"].Error == "" {
+		t.Errorf("expected a hung check to fail once the context ends, got %+v", result)
+	}
+}
+
+func TestCached(t *testing.T) {
+	calls := 0
+	checker := Cached(CheckFunc(func(context.Context) error {
+		calls++
+		return nil
+	}), 50*time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		checker.Check(context.Background())
+	}
+	if calls != 1 {
+		t.Errorf("expected one check within the ttl, got %d", calls)
+	}
+	time.Sleep(60 * time.Millisecond)
+	checker.Check(context.Background())
+	if calls != 2 {
+		t.Errorf("expected a fresh check after the ttl, got %d", calls)
+	}
+}
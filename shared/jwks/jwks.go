@@ -0,0 +1,139 @@
+// Package jwks signs and verifies JWTs with asymmetric keys identified by a
+// kid, and publishes or consumes the public half as a JSON Web Key Set (RFC
+// 7517). Only auth-service holds private keys; every other service verifies
+// against the published set.
+package jwks
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported signing algorithms.
+const (
+	RS256 = "RS256"
+	EdDSA = "EdDSA"
+)
+
+// Algorithms lists the algorithms a verifier should accept.
+var Algorithms = []string{RS256, EdDSA}
+
+var (
+	ErrNoKeys         = errors.New("jwks: no signing keys")
+	ErrUnknownKey     = errors.New("jwks: unknown key id")
+	ErrUnsupportedKey = errors.New("jwks: unsupported key type")
+)
+
+// Key is a single JSON Web Key. Only the public members used by RSA and
+// Ed25519 keys are represented.
+type Key struct {
+	KeyID     string `json:"kid"`
+	KeyType   string `json:"kty"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use,omitempty"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// OKP (Ed25519)
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// Set is a JSON Web Key Set document.
+type Set struct {
+	Keys []Key `json:"keys"`
+}
+
+// PublicKey encodes pub as a JWK with the given kid.
+func PublicKey(kid string, pub crypto.PublicKey) (Key, error) {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		return Key{
+			KeyID: kid, KeyType: "RSA", Algorithm: RS256, Use: "sig",
+			N: base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		}, nil
+	case ed25519.PublicKey:
+		return Key{
+			KeyID: kid, KeyType: "OKP", Algorithm: EdDSA, Use: "sig",
+			Curve: "Ed25519", X: base64.RawURLEncoding.EncodeToString(k),
+		}, nil
+	default:
+		return Key{}, ErrUnsupportedKey
+	}
+}
+
+// Public decodes the key material of a JWK.
+func (k Key) Public() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("jwks: invalid modulus for %s: %w", k.KeyID, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("jwks: invalid exponent for %s: %w", k.KeyID, err)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("jwks: exponent out of range for %s", k.KeyID)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "OKP":
+		if k.Curve != "Ed25519" {
+			return nil, ErrUnsupportedKey
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("jwks: invalid Ed25519 key %s", k.KeyID)
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, ErrUnsupportedKey
+	}
+}
+
+// Parse decodes a JWKS document into verification keys by kid. Keys of an
+// unsupported type are skipped so a set can carry keys for other consumers.
+func Parse(data []byte) (map[string]crypto.PublicKey, error) {
+	var set Set
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("jwks: invalid key set: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.Public()
+		if errors.Is(err, ErrUnsupportedKey) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys[k.KeyID] = pub
+	}
+	return keys, nil
+}
+
+// signingMethod returns the JWT algorithm for a private key.
+func signingMethod(key crypto.Signer) (jwt.SigningMethod, error) {
+	switch key.(type) {
+	case *rsa.PrivateKey:
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PrivateKey:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, ErrUnsupportedKey
+	}
+}
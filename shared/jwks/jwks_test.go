@@ -0,0 +1,138 @@
+package jwks
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func writePEM(t *testing.T, dir, kid string, key any) {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0o600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+}
+
+func verify(t *testing.T, src Source, token string) error {
+	t.Helper()
+	_, err := jwt.Parse(token, Keyfunc(context.Background(), src), jwt.WithValidMethods(Algorithms))
+	return err
+}
+
+func TestKeySetRoundTrip(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	generated, err := GenerateKeySet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	writePEM(t, dir, "rsa-2024", rsaKey)
+	writePEM(t, dir, "ed-2025", generated.active.Key)
+
+	for _, active := range []string{"rsa-2024", "ed-2025"} {
+		t.Run(active, func(t *testing.T) {
+			keys, err := LoadKeySet(dir, active)
+			if err != nil {
+				t.Fatalf("failed to load keys: %v", err)
+			}
+			token, err := keys.Sign(jwt.MapClaims{"sub": "usr-001"})
+			if err != nil {
+				t.Fatalf("failed to sign: %v", err)
+			}
+
+			// Verify through the published document, as another service would.
+			doc, _ := json.Marshal(keys.JWKS())
+			parsed, err := Parse(doc)
+			if err != nil {
+				t.Fatalf("failed to parse JWKS: %v", err)
+			}
+			if len(parsed) != 2 {
+				t.Errorf("expected both keys to be published, got %d", len(parsed))
+			}
+			if err := verify(t, StaticSource(parsed), token); err != nil {
+				t.Errorf("token signed with %s did not verify: %v", active, err)
+			}
+		})
+	}
+}
+
+func TestNewKeySetRequiresActiveKey(t *testing.T) {
+	a, _ := GenerateKeySet()
+	b, _ := GenerateKeySet()
+	if _, err := NewKeySet(append(a.keys, b.keys...), ""); err == nil {
+		t.Error("expected an error when several keys are loaded without an active kid")
+	}
+	if _, err := NewKeySet(a.keys, "missing"); err == nil {
+		t.Error("expected an error for an unknown active kid")
+	}
+}
+
+func TestRemoteSourceRotation(t *testing.T) {
+	oldKeys, _ := GenerateKeySet()
+	newKeys, _ := GenerateKeySet()
+
+	var published atomic.Value
+	published.Store(oldKeys.JWKS())
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		json.NewEncoder(w).Encode(published.Load())
+	}))
+	defer server.Close()
+
+	src := NewRemoteSource(server.URL)
+	src.minRefresh = 0
+
+	oldToken, _ := oldKeys.Sign(jwt.MapClaims{"sub": "usr-001"})
+	if err := verify(t, src, oldToken); err != nil {
+		t.Fatalf("old token did not verify: %v", err)
+	}
+	if err := verify(t, src, oldToken); err != nil || fetches.Load() != 1 {
+		t.Fatalf("expected the cached set to be reused, got %d fetches (err %v)", fetches.Load(), err)
+	}
+
+	// The issuer starts publishing the new key alongside the old one; a token
+	// with the new kid triggers a refetch.
+	published.Store(Set{Keys: append(oldKeys.JWKS().Keys, newKeys.JWKS().Keys...)})
+	newToken, _ := newKeys.Sign(jwt.MapClaims{"sub": "usr-001"})
+	if err := verify(t, src, newToken); err != nil {
+		t.Fatalf("new token did not verify after rotation: %v", err)
+	}
+	if err := verify(t, src, oldToken); err != nil {
+		t.Errorf("old token stopped verifying during the overlap: %v", err)
+	}
+
+	// Unknown kids are not refetched more often than minRefresh.
+	src.minRefresh = time.Hour
+	stranger, _ := GenerateKeySet()
+	strangerToken, _ := stranger.Sign(jwt.MapClaims{"sub": "usr-001"})
+	before := fetches.Load()
+	for i := 0; i < 3; i++ {
+		if err := verify(t, src, strangerToken); err == nil {
+			t.Fatal("token from an unknown key verified")
+		}
+	}
+	if fetches.Load() != before {
+		t.Errorf("expected unknown kids not to trigger refetches within minRefresh, got %d", fetches.Load()-before)
+	}
+}
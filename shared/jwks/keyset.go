@@ -0,0 +1,135 @@
+package jwks
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SigningKey is a private key and the kid it is published under.
+type SigningKey struct {
+	KeyID string
+	Key   crypto.Signer
+}
+
+// KeySet holds auth-service's private keys. The active key signs new tokens;
+// every key in the set is published, so tokens signed with a key that is being
+// rotated out keep verifying until it is removed.
+type KeySet struct {
+	active SigningKey
+	keys   []SigningKey
+}
+
+// NewKeySet builds a key set that signs with activeKID. activeKID may be
+// empty when there is exactly one key.
+func NewKeySet(keys []SigningKey, activeKID string) (*KeySet, error) {
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+	if activeKID == "" {
+		if len(keys) > 1 {
+			return nil, fmt.Errorf("jwks: %d signing keys but no active key id", len(keys))
+		}
+		activeKID = keys[0].KeyID
+	}
+	set := &KeySet{keys: keys}
+	for _, k := range keys {
+		if _, err := signingMethod(k.Key); err != nil {
+			return nil, fmt.Errorf("jwks: key %s: %w", k.KeyID, err)
+		}
+		if k.KeyID == activeKID {
+			set.active = k
+		}
+	}
+	if set.active.Key == nil {
+		return nil, fmt.Errorf("jwks: active key %s not found", activeKID)
+	}
+	return set, nil
+}
+
+// LoadKeySet reads PKCS #8 PEM private keys (RSA or Ed25519) from dir. Each
+// file is named <kid>.pem.
+func LoadKeySet(dir, activeKID string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	keys := make([]SigningKey, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("jwks: failed to read %s: %w", path, err)
+		}
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("jwks: %s is not PEM encoded", path)
+		}
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("jwks: failed to parse %s: %w", path, err)
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("jwks: %s: %w", path, ErrUnsupportedKey)
+		}
+		keys = append(keys, SigningKey{KeyID: strings.TrimSuffix(filepath.Base(path), ".pem"), Key: signer})
+	}
+	return NewKeySet(keys, activeKID)
+}
+
+// GenerateKeySet returns a key set with one new Ed25519 key. Tokens signed
+// with it stop verifying when the process exits, so it is only suitable for
+// local development.
+func GenerateKeySet() (*KeySet, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("jwks: failed to generate key: %w", err)
+	}
+	kid := fmt.Sprintf("ephemeral-%x", priv.Public().(ed25519.PublicKey)[:4])
+	return NewKeySet([]SigningKey{{KeyID: kid, Key: priv}}, kid)
+}
+
+// ActiveKeyID returns the kid new tokens are signed with.
+func (s *KeySet) ActiveKeyID() string {
+	return s.active.KeyID
+}
+
+// Sign returns claims as a JWT signed with the active key and carrying its kid.
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	method, _ := signingMethod(s.active.Key)
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = s.active.KeyID
+	return token.SignedString(s.active.Key)
+}
+
+// JWKS returns the public key set to publish.
+func (s *KeySet) JWKS() Set {
+	set := Set{Keys: make([]Key, 0, len(s.keys))}
+	for _, k := range s.keys {
+		jwk, _ := PublicKey(k.KeyID, k.Key.Public())
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// Key implements Source, so the issuer can verify its own tokens without a
+// round trip to its JWKS endpoint.
+func (s *KeySet) Key(_ context.Context, kid string) (crypto.PublicKey, error) {
+	for _, k := range s.keys {
+		if k.KeyID == kid {
+			return k.Key.Public(), nil
+		}
+	}
+	return nil, ErrUnknownKey
+}
@@ -0,0 +1,149 @@
+package jwks
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Source resolves a kid to a verification key.
+type Source interface {
+	Key(ctx context.Context, kid string) (crypto.PublicKey, error)
+}
+
+// Keyfunc returns a jwt.Keyfunc that looks up the token's kid in src and
+// checks the key matches the token's algorithm, so an RSA key can never be
+// used to verify an EdDSA token or vice versa.
+func Keyfunc(ctx context.Context, src Source) jwt.Keyfunc {
+	return func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, fmt.Errorf("jwks: token has no kid")
+		}
+		key, err := src.Key(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		switch key.(type) {
+		case *rsa.PublicKey:
+			if token.Method.Alg() != RS256 {
+				return nil, fmt.Errorf("jwks: key %s does not sign %s", kid, token.Method.Alg())
+			}
+		case ed25519.PublicKey:
+			if token.Method.Alg() != EdDSA {
+				return nil, fmt.Errorf("jwks: key %s does not sign %s", kid, token.Method.Alg())
+			}
+		default:
+			return nil, ErrUnsupportedKey
+		}
+		return key, nil
+	}
+}
+
+// StaticSource is a fixed set of verification keys.
+type StaticSource map[string]crypto.PublicKey
+
+// LoadFile reads a JWKS document from path.
+func LoadFile(path string) (StaticSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("jwks: failed to read %s: %w", path, err)
+	}
+	keys, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return StaticSource(keys), nil
+}
+
+func (s StaticSource) Key(_ context.Context, kid string) (crypto.PublicKey, error) {
+	if key, ok := s[kid]; ok {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+const (
+	defaultMaxAge     = 5 * time.Minute
+	defaultMinRefresh = 10 * time.Second
+)
+
+// RemoteSource fetches a JWKS document over HTTP and caches it. The set is
+// refetched once it is older than maxAge, or early when a token names a kid
+// that is not cached (a key the issuer has just started publishing), at most
+// once per minRefresh. If a refetch fails the cached keys keep being used.
+type RemoteSource struct {
+	url        string
+	client     *http.Client
+	maxAge     time.Duration
+	minRefresh time.Duration
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+	triedAt   time.Time
+}
+
+// NewRemoteSource creates a source for the JWKS at url. Nothing is fetched
+// until the first lookup, so services can start before the issuer.
+func NewRemoteSource(url string) *RemoteSource {
+	return &RemoteSource{
+		url:        url,
+		client:     &http.Client{Timeout: 5 * time.Second},
+		maxAge:     defaultMaxAge,
+		minRefresh: defaultMinRefresh,
+	}
+}
+
+func (r *RemoteSource) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, cached := r.keys[kid]
+	stale := time.Since(r.fetchedAt) > r.maxAge
+	if (stale || !cached) && time.Since(r.triedAt) >= r.minRefresh {
+		r.triedAt = time.Now()
+		keys, err := r.fetch(ctx)
+		if err != nil && r.keys == nil {
+			return nil, err
+		}
+		if err == nil {
+			r.keys = keys
+			r.fetchedAt = time.Now()
+			key, cached = r.keys[kid]
+		}
+	}
+	if !cached {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+func (r *RemoteSource) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("jwks: failed to fetch %s: %w", r.url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks: fetching %s returned %d", r.url, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("jwks: failed to read %s: %w", r.url, err)
+	}
+	return Parse(data)
+}
@@ -0,0 +1,164 @@
+// Package ledger models money movements as double-entry journal entries.
+//
+// Every JournalEntry carries two or more Postings whose amounts sum to zero.
+// Amounts are signed from the ledger account's point of view: a positive
+// posting credits the account (raises a customer balance), a negative one
+// debits it. A customer account's balance is therefore the sum of its
+// postings, and the internal accounts below hold the other side of every
+// movement.
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/eaglebank/shared/money"
+	"github.com/eaglebank/shared/utils"
+)
+
+// Internal ledger accounts. Customer accounts are identified by their account number.
+const (
+	// Cash is money entering or leaving the bank (deposits and withdrawals).
+	Cash = "internal:cash"
+	// Suspense holds transfer funds between the debit and credit legs.
+	Suspense = "internal:suspense"
+	// FeeIncome receives fees charged to customer accounts.
+	FeeIncome = "internal:fee_income"
+	// InterestExpense funds interest paid to customer accounts.
+	InterestExpense = "internal:interest_expense"
+	// Adjustments holds the other side of manual corrections posted by staff.
+	Adjustments = "internal:adjustments"
+	// OpeningEquity holds the other side of the opening entries that brought
+	// balances from before the ledger into it.
+	OpeningEquity = "internal:opening_equity"
+)
+
+// Transaction types that can be posted to the ledger.
+const (
+	Deposit          = "deposit"
+	Withdrawal       = "withdrawal"
+	Fee              = "fee"
+	Interest         = "interest"
+	TransferOut      = "transfer_out"
+	TransferIn       = "transfer_in"
+	TransferReversal = "transfer_reversal"
+	AdjustmentCredit = "adjustment_credit"
+	AdjustmentDebit  = "adjustment_debit"
+)
+
+var (
+	ErrUnbalanced     = errors.New("ledger: postings do not sum to zero")
+	ErrTooFewPostings = errors.New("ledger: an entry needs at least two postings")
+	ErrUnknownType    = errors.New("ledger: unknown transaction type")
+	ErrNonPositive    = errors.New("ledger: transaction amount must be positive")
+)
+
+// Posting is one line of a journal entry against a single ledger account.
+type Posting struct {
+	Account string       `json:"account"`
+	Amount  money.Amount `json:"amount"`
+}
+
+// JournalEntry is a balanced set of postings recording one transaction.
+type JournalEntry struct {
+	ID            string    `json:"id"`
+	TransactionID string    `json:"transactionId"`
+	Type          string    `json:"type"`
+	Currency      string    `json:"currency"`
+	EffectiveAt   time.Time `json:"effectiveAt"`
+	Postings      []Posting `json:"postings"`
+}
+
+// counterparty is the internal account on the other side of each transaction
+// type, and whether the customer account is credited.
+var counterparty = map[string]struct {
+	account string
+	credit  bool
+}{
+	Deposit:          {Cash, true},
+	Withdrawal:       {Cash, false},
+	Fee:              {FeeIncome, false},
+	Interest:         {InterestExpense, true},
+	TransferOut:      {Suspense, false},
+	TransferIn:       {Suspense, true},
+	TransferReversal: {Suspense, true},
+	AdjustmentCredit: {Adjustments, true},
+	AdjustmentDebit:  {Adjustments, false},
+}
+
+// ForTransaction builds the journal entry for a customer transaction of the
+// given type against accountNumber.
+func ForTransaction(transactionID, transactionType, accountNumber, currency string, amount money.Amount, at time.Time) (*JournalEntry, error) {
+	side, ok := counterparty[transactionType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownType, transactionType)
+	}
+	if !amount.IsPositive() {
+		return nil, ErrNonPositive
+	}
+	customer := amount
+	if !side.credit {
+		customer = amount.Neg()
+	}
+	entry := &JournalEntry{
+		ID:            utils.GenerateID("jnl"),
+		TransactionID: transactionID,
+		Type:          transactionType,
+		Currency:      currency,
+		EffectiveAt:   at,
+		Postings: []Posting{
+			{Account: accountNumber, Amount: customer},
+			{Account: side.account, Amount: customer.Neg()},
+		},
+	}
+	return entry, entry.Validate()
+}
+
+// IsDebit reports whether a transaction type takes money out of the customer account.
+func IsDebit(transactionType string) bool {
+	side, ok := counterparty[transactionType]
+	return ok && !side.credit
+}
+
+// DebitTypes lists the transaction types that take money out of the customer account.
+func DebitTypes() []string {
+	var types []string
+	for t, side := range counterparty {
+		if !side.credit {
+			types = append(types, t)
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Validate checks that the entry has at least two postings and sums to zero.
+func (e *JournalEntry) Validate() error {
+	if len(e.Postings) < 2 {
+		return ErrTooFewPostings
+	}
+	var sum money.Amount
+	for _, p := range e.Postings {
+		var err error
+		if sum, err = sum.Add(p.Amount); err != nil {
+			return err
+		}
+	}
+	if !sum.IsZero() {
+		return fmt.Errorf("%w: off by %s", ErrUnbalanced, sum)
+	}
+	return nil
+}
+
+// Change returns the net amount the entry posts to account.
+func (e *JournalEntry) Change(account string) money.Amount {
+	var net money.Amount
+	for _, p := range e.Postings {
+		if p.Account == account {
+			net += p.Amount
+		}
+	}
+	return net
+}
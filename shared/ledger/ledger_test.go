@@ -0,0 +1,76 @@
+package ledger
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/eaglebank/shared/money"
+)
+
+func TestForTransaction(t *testing.T) {
+	tests := []struct {
+		name           string
+		txType         string
+		amount         money.Amount
+		customerChange money.Amount
+		counterparty   string
+		wantErr        error
+	}{
+		{name: "deposit credits customer from cash", txType: Deposit, amount: 1000, customerChange: 1000, counterparty: Cash},
+		{name: "withdrawal debits customer to cash", txType: Withdrawal, amount: 1000, customerChange: -1000, counterparty: Cash},
+		{name: "fee debits customer to fee income", txType: Fee, amount: 250, customerChange: -250, counterparty: FeeIncome},
+		{name: "interest credits customer from interest expense", txType: Interest, amount: 12, customerChange: 12, counterparty: InterestExpense},
+		{name: "transfer out moves funds into suspense", txType: TransferOut, amount: 500, customerChange: -500, counterparty: Suspense},
+		{name: "transfer in releases funds from suspense", txType: TransferIn, amount: 500, customerChange: 500, counterparty: Suspense},
+		{name: "reversal refunds from suspense", txType: TransferReversal, amount: 500, customerChange: 500, counterparty: Suspense},
+		{name: "adjustment credit from adjustments", txType: AdjustmentCredit, amount: 300, customerChange: 300, counterparty: Adjustments},
+		{name: "adjustment debit to adjustments", txType: AdjustmentDebit, amount: 300, customerChange: -300, counterparty: Adjustments},
+		{name: "unknown type", txType: "gift", amount: 100, wantErr: ErrUnknownType},
+		{name: "zero amount", txType: Deposit, amount: 0, wantErr: ErrNonPositive},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := ForTransaction("tan-001", tt.txType, "12345678", "GBP", tt.amount, time.Now())
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("[%s] expected %v got %v", tt.name, tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("[%s] unexpected error: %v", tt.name, err)
+			}
+			if got := entry.Change("12345678"); got != tt.customerChange {
+				t.Errorf("[%s] expected customer change %s got %s", tt.name, tt.customerChange, got)
+			}
+			if got := entry.Change(tt.counterparty); got != tt.customerChange.Neg() {
+				t.Errorf("[%s] expected %s change %s got %s", tt.name, tt.counterparty, tt.customerChange.Neg(), got)
+			}
+			if IsDebit(tt.txType) != tt.customerChange.IsNegative() {
+				t.Errorf("[%s] IsDebit disagrees with posting sign", tt.name)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		postings []Posting
+		wantErr  error
+	}{
+		{name: "balanced", postings: []Posting{{"12345678", 100}, {Cash, -100}}},
+		{name: "balanced across three accounts", postings: []Posting{{"12345678", -1050}, {Cash, 1000}, {FeeIncome, 50}}},
+		{name: "unbalanced", postings: []Posting{{"12345678", 100}, {Cash, -99}}, wantErr: ErrUnbalanced},
+		{name: "single posting", postings: []Posting{{"12345678", 0}}, wantErr: ErrTooFewPostings},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&JournalEntry{Postings: tt.postings}).Validate()
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("[%s] expected %v got %v", tt.name, tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+// Package lifecycle runs a service's HTTP server alongside its background
+// workers, such as event subscribers and the outbox relay, and shuts them
+// down in order on SIGINT or SIGTERM: the server stops accepting connections
+// and drains in-flight requests, then the workers are stopped and waited
+// for, then resources such as the database and Redis are closed.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+type Config struct {
+	Addr    string
+	Handler http.Handler
+	// DrainTimeout bounds how long in-flight requests, and then workers, are
+	// given to finish once shutdown starts.
+	DrainTimeout time.Duration
+	// ReadHeaderTimeout bounds how long a client may take to send request
+	// headers.
+	ReadHeaderTimeout time.Duration
+}
+
+type worker struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+type closer struct {
+	name  string
+	close func() error
+}
+
+// App is a service process: one HTTP server, its workers and the resources
+// they share.
+type App struct {
+	server       *http.Server
+	drainTimeout time.Duration
+	workers      []worker
+	closers      []closer
+}
+
+func New(config Config) *App {
+	if config.DrainTimeout == 0 {
+		config.DrainTimeout = 20 * time.Second
+	}
+	if config.ReadHeaderTimeout == 0 {
+		config.ReadHeaderTimeout = 10 * time.Second
+	}
+	return &App{
+		server: &http.Server{
+			Addr:              config.Addr,
+			Handler:           config.Handler,
+			ReadHeaderTimeout: config.ReadHeaderTimeout,
+		},
+		drainTimeout: config.DrainTimeout,
+	}
+}
+
+// Go registers a worker to run for the life of the process. Its context is
+// cancelled at shutdown, after the server has drained; run should then
+// finish the work in hand and return.
+func (a *App) Go(name string, run func(ctx context.Context) error) {
+	a.workers = append(a.workers, worker{name: name, run: run})
+}
+
+// OnClose registers a resource to close once the server and every worker
+// have stopped. Resources are closed in the order they were registered.
+func (a *App) OnClose(name string, close func() error) {
+	a.closers = append(a.closers, closer{name: name, close: close})
+}
+
+// Run starts the workers and serves HTTP until the process is signalled or
+// the server fails, then shuts everything down. It returns the server's
+// error, if it failed. A second signal during shutdown kills the process.
+func (a *App) Run() error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(ctx, stop)
+	return a.run(ctx)
+}
+
+// run serves until ctx is done or the server fails.
+func (a *App) run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", a.server.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", a.server.Addr, err)
+	}
+
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	var workers sync.WaitGroup
+	for _, w := range a.workers {
+		workers.Add(1)
+		go func(w worker) {
+			defer workers.Done()
+			if err := w.run(workerCtx); err != nil && !errors.Is(err, context.Canceled) {
+				slog.Error("Worker stopped", "worker", w.name, "err", err)
+			}
+		}(w)
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if err := a.server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+		close(serveErr)
+	}()
+
+	select {
+	case <-ctx.Done():
+		slog.Info("Shutting down...")
+	case err = <-serveErr:
+		err = fmt.Errorf("server failed: %w", err)
+	}
+
+	// Stop accepting connections and let in-flight requests finish.
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), a.drainTimeout)
+	defer cancelDrain()
+	if shutdownErr := a.server.Shutdown(drainCtx); shutdownErr != nil {
+		slog.Warn("Requests still in flight were cut off", "drain_timeout", a.drainTimeout, "err", shutdownErr)
+		a.server.Close()
+	}
+
+	// Then stop the workers, which may still be handling requests' events.
+	stopWorkers()
+	if !waitTimeout(&workers, a.drainTimeout) {
+		slog.Warn("Workers still running, closing resources anyway", "drain_timeout", a.drainTimeout)
+	}
+
+	for _, c := range a.closers {
+		if closeErr := c.close(); closeErr != nil {
+			slog.Error("Failed to close resource", "resource", c.name, "err", closeErr)
+		}
+	}
+	slog.Info("Shutdown complete")
+	return err
+}
+
+// waitTimeout waits for wg, reporting false if it took longer than timeout.
+func waitTimeout(wg *sync.WaitGroup, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
@@ -0,0 +1,106 @@
+package lifecycle
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func TestAppShutdownOrder(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		steps []string
+	)
+	record := func(step string) {
+		mu.Lock()
+		defer mu.Unlock()
+		steps = append(steps, step)
+	}
+
+	started := make(chan struct{})
+	addr := freeAddr(t)
+	app := New(Config{
+		Addr: addr,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			time.Sleep(100 * time.Millisecond)
+			record("request finished")
+			w.WriteHeader(http.StatusOK)
+		}),
+		DrainTimeout: time.Second,
+	})
+	app.Go("subscriber", func(ctx context.Context) error {
+		<-ctx.Done()
+		record("worker stopped")
+		return ctx.Err()
+	})
+	app.OnClose("postgres", func() error { record("postgres closed"); return nil })
+	app.OnClose("redis", func() error { record("redis closed"); return nil })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- app.run(ctx) }()
+
+	status := make(chan int, 1)
+	go func() {
+		for {
+			resp, err := http.Get("http://" + addr)
+			if err == nil {
+				resp.Body.Close()
+				status <- resp.StatusCode
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+	<-started
+	cancel()
+
+	if got := <-status; got != http.StatusOK {
+		t.Errorf("expected the in-flight request to complete, got %d", got)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("expected a clean shutdown, got %v", err)
+	}
+	expected := []string{"request finished", "worker stopped", "postgres closed", "redis closed"}
+	if len(steps) != len(expected) {
+		t.Fatalf("expected steps %v got %v", expected, steps)
+	}
+	for i := range expected {
+		if steps[i] != expected[i] {
+			t.Errorf("expected steps %v got %v", expected, steps)
+			break
+		}
+	}
+}
+
+func TestAppListenFailure(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer l.Close()
+
+	ran := false
+	app := New(Config{Addr: l.Addr().String(), Handler: http.NotFoundHandler()})
+	app.Go("worker", func(ctx context.Context) error { ran = true; return nil })
+	if err := app.run(context.Background()); err == nil {
+		t.Error("expected an error for an address in use")
+	}
+	if ran {
+		t.Error("expected workers not to start when the server cannot listen")
+	}
+}
@@ -0,0 +1,83 @@
+// Package logging sets up structured JSON logging for a service. Records
+// carry the fields of the request or event they were logged for, and pass
+// through a redaction layer that masks personal data and credentials before
+// anything is written.
+//
+// The level is chosen with LOG_LEVEL: debug, info (the default), warn or
+// error.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Setup makes a JSON logger for service the default, for slog and for the
+// standard log package alike.
+func Setup(service string) error {
+	var level slog.Level
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		if err := level.UnmarshalText([]byte(v)); err != nil {
+			return fmt.Errorf("invalid LOG_LEVEL %q", v)
+		}
+	}
+	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})
+	slog.SetDefault(slog.New(NewHandler(handler)).With("service", service))
+	return nil
+}
+
+// NewHandler wraps next so records gain the fields stored in their context
+// by With, and are redacted before next sees them.
+func NewHandler(next slog.Handler) slog.Handler {
+	return contextHandler{next: redactingHandler{next: next}}
+}
+
+type contextKey struct{}
+
+// With returns ctx carrying args, as slog key-value pairs or Attrs, in
+// addition to any fields it already carries. Records logged with ctx include
+// them.
+func With(ctx context.Context, args ...any) context.Context {
+	fields, _ := ctx.Value(contextKey{}).([]slog.Attr)
+	var r slog.Record
+	r.Add(args...)
+	combined := make([]slog.Attr, 0, len(fields)+r.NumAttrs())
+	combined = append(combined, fields...)
+	r.Attrs(func(a slog.Attr) bool {
+		combined = append(combined, a)
+		return true
+	})
+	return context.WithValue(ctx, contextKey{}, combined)
+}
+
+// contextHandler adds the fields stored in a record's context, and the ID of
+// the trace it belongs to.
+type contextHandler struct {
+	next slog.Handler
+}
+
+func (h contextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if fields, ok := ctx.Value(contextKey{}).([]slog.Attr); ok {
+		r.AddAttrs(fields...)
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		r.AddAttrs(slog.String("trace_id", span.TraceID().String()))
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{next: h.next.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{next: h.next.WithGroup(name)}
+}
@@ -0,0 +1,160 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// redacted replaces values that must never be logged at all.
+const redacted = "[REDACTED]"
+
+var (
+	jwtPattern           = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+	bearerPattern        = regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9._~+/-]+=*`)
+	queryPattern         = regexp.MustCompile(`(?i)\b(token|code|secret|password|signature|sig)=[^&\s"]+`)
+	emailPattern         = regexp.MustCompile(`\b([A-Za-z0-9._%+-])[A-Za-z0-9._%+-]*@([A-Za-z0-9.-]+\.[A-Za-z]{2,})\b`)
+	phonePattern         = regexp.MustCompile(`\+\d[\d ()-]{6,18}\d`)
+	accountNumberPattern = regexp.MustCompile(`\b01\d{6}\b`)
+)
+
+// sensitiveKeys name attributes whose values are credentials, dropped
+// whatever they look like.
+var sensitiveKeys = []string{"password", "secret", "token", "authorization", "cookie"}
+
+// Redact masks personal data and credentials in s: tokens and secrets are
+// removed, and emails, phone numbers and account numbers keep only enough to
+// tell them apart.
+func Redact(s string) string {
+	s = jwtPattern.ReplaceAllString(s, redacted)
+	s = bearerPattern.ReplaceAllString(s, "$1 "+redacted)
+	s = queryPattern.ReplaceAllString(s, "$1="+redacted)
+	s = emailPattern.ReplaceAllString(s, "$1***@$2")
+	s = phonePattern.ReplaceAllStringFunc(s, func(phone string) string { return mask(phone, 2) })
+	s = accountNumberPattern.ReplaceAllStringFunc(s, func(account string) string { return mask(account, 4) })
+	return s
+}
+
+// mask replaces every digit but the last keep with '*'.
+func mask(s string, keep int) string {
+	b := []byte(s)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < '0' || b[i] > '9' {
+			continue
+		}
+		if keep > 0 {
+			keep--
+			continue
+		}
+		b[i] = '*'
+	}
+	return string(b)
+}
+
+func sensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, k := range sensitiveKeys {
+		if strings.Contains(key, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactingHandler redacts a record's message and attributes.
+type redactingHandler struct {
+	next slog.Handler
+}
+
+func (h redactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h redactingHandler) Handle(ctx context.Context, r slog.Record) error {
+	out := slog.NewRecord(r.Time, r.Level, Redact(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(redactAttr(a))
+		return true
+	})
+	return h.next.Handle(ctx, out)
+}
+
+func (h redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return redactingHandler{next: h.next.WithAttrs(redactAttrs(attrs))}
+}
+
+func (h redactingHandler) WithGroup(name string) slog.Handler {
+	return redactingHandler{next: h.next.WithGroup(name)}
+}
+
+func redactAttrs(attrs []slog.Attr) []slog.Attr {
+	out := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		out[i] = redactAttr(a)
+	}
+	return out
+}
+
+func redactAttr(a slog.Attr) slog.Attr {
+	v := a.Value.Resolve()
+	if sensitiveKey(a.Key) && v.Kind() != slog.KindGroup {
+		return slog.String(a.Key, redacted)
+	}
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, Redact(v.String()))
+	case slog.KindGroup:
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(redactAttrs(v.Group())...)}
+	case slog.KindAny:
+		switch x := v.Any().(type) {
+		case error:
+			return slog.String(a.Key, Redact(x.Error()))
+		case fmt.Stringer:
+			return slog.String(a.Key, Redact(x.String()))
+		default:
+			return slog.Any(a.Key, redactJSON(x))
+		}
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
+
+// redactJSON redacts a map, slice or struct in the form the JSON handler
+// would write it: every string inside it is redacted, and so is every value
+// under a sensitive key.
+func redactJSON(v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return Redact(fmt.Sprint(v))
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var generic any
+	if err := dec.Decode(&generic); err != nil {
+		return Redact(string(data))
+	}
+	return redactValue(generic)
+}
+
+func redactValue(v any) any {
+	switch x := v.(type) {
+	case string:
+		return Redact(x)
+	case map[string]any:
+		for key, value := range x {
+			if sensitiveKey(key) {
+				x[key] = redacted
+			} else {
+				x[key] = redactValue(value)
+			}
+		}
+	case []any:
+		for i, value := range x {
+			x[i] = redactValue(value)
+		}
+	}
+	return v
+}
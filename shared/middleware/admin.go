@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminTokenMiddleware guards operational endpoints (e.g. the dead-letter
+// API) with the shared secret in ADMIN_API_TOKEN, sent as X-Admin-Token.
+// When the variable is unset the endpoints are disabled entirely.
+func AdminTokenMiddleware() gin.HandlerFunc {
+	token := os.Getenv("ADMIN_API_TOKEN")
+	return func(c *gin.Context) {
+		if token == "" {
+			RespondWithError(c, http.StatusForbidden, "Admin API is disabled")
+			c.Abort()
+			return
+		}
+		provided := c.GetHeader("X-Admin-Token")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			RespondWithError(c, http.StatusUnauthorized, "Invalid admin token")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -5,36 +5,70 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/eaglebank/shared/jwks"
+	"github.com/eaglebank/shared/logging"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtSecretVal []byte
+var jwtKeys jwks.Source
 
-// MustInitJWTSecret reads JWT_SECRET from the environment and stores it for
-// use by AuthMiddleware. It must be called once at service startup before any
-// requests are served. The process exits immediately if the variable is unset
-// so the misconfiguration is caught at boot time rather than at request time.
-func MustInitJWTSecret() {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		log.Fatal("JWT_SECRET environment variable is not set")
+// MustInitJWKS configures the keys AuthMiddleware verifies tokens against:
+// the JWKS document at JWKS_FILE if set, otherwise the one served by
+// auth-service at JWKS_URL, fetched on first use and cached. It must be called
+// once at service startup before any requests are served. The process exits
+// if JWKS_FILE cannot be loaded so the misconfiguration is caught at boot.
+func MustInitJWKS() {
+	if path := os.Getenv("JWKS_FILE"); path != "" {
+		keys, err := jwks.LoadFile(path)
+		if err != nil {
+			log.Fatalf("Failed to load JWKS_FILE: %v", err)
+		}
+		UseJWTKeys(keys)
+		return
+	}
+	url := os.Getenv("JWKS_URL")
+	if url == "" {
+		url = "http://localhost:8081/.well-known/jwks.json"
 	}
-	jwtSecretVal = []byte(secret)
+	UseJWTKeys(jwks.NewRemoteSource(url))
 }
 
-func jwtSecret() []byte {
-	return jwtSecretVal
+// UseJWTKeys sets the verification keys directly, e.g. auth-service using its
+// own key set.
+func UseJWTKeys(src jwks.Source) {
+	jwtKeys = src
 }
 
+// Claims is the access token payload. Roles lists the user's staff roles and
+// Scope the space-separated scopes they grant; both are empty for customers.
+// Tokens issued to an API client carry its ClientID and scopes instead of a
+// UserID.
 type Claims struct {
-	UserID string `json:"userId"`
-	Email  string `json:"email"`
+	UserID   string   `json:"userId,omitempty"`
+	ClientID string   `json:"clientId,omitempty"`
+	Email    string   `json:"email,omitempty"`
+	Roles    []string `json:"roles,omitempty"`
+	Scope    string   `json:"scope,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// AuthMiddleware authenticates requests made by users. Tokens issued to API
+// clients are refused, so routes that authorise by ownership never see a
+// caller without a user ID.
 func AuthMiddleware() gin.HandlerFunc {
+	return authenticate(false)
+}
+
+// ScopedAuthMiddleware authenticates requests made by users or API clients.
+// It is for routes that authorise by scope, e.g. with RequireScope.
+func ScopedAuthMiddleware() gin.HandlerFunc {
+	return authenticate(true)
+}
+
+func authenticate(allowClients bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -57,11 +91,10 @@ func AuthMiddleware() gin.HandlerFunc {
 		tokenString := parts[1]
 		claims := &Claims{}
 
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (any, error) {
-			return jwtSecret(), nil
-		})
+		token, err := jwt.ParseWithClaims(tokenString, claims, jwks.Keyfunc(c.Request.Context(), jwtKeys),
+			jwt.WithValidMethods(jwks.Algorithms), jwt.WithExpirationRequired())
 
-		if err != nil || !token.Valid {
+		if err != nil || !token.Valid || claims.ID == "" || (claims.UserID == "") == (claims.ClientID == "") {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"message": "Invalid or expired token",
 			})
@@ -69,13 +102,60 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Set user ID in context
-		c.Set("userId", claims.UserID)
-		c.Set("email", claims.Email)
+		if claims.ClientID != "" && !allowClients {
+			c.JSON(http.StatusForbidden, gin.H{
+				"message": "This endpoint requires a user token",
+			})
+			c.Abort()
+			return
+		}
+
+		if tokenDenylist != nil {
+			revoked, err := tokenDenylist.IsRevoked(c.Request.Context(), claims.ID)
+			if err != nil {
+				// Fail closed: a revoked token must not get through while Redis is down.
+				c.JSON(http.StatusServiceUnavailable, gin.H{
+					"message": "Unable to verify token",
+				})
+				c.Abort()
+				return
+			}
+			if revoked {
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"message": "Token has been revoked",
+				})
+				c.Abort()
+				return
+			}
+		}
+
+		// Set the caller in context, and on everything logged for the request
+		if claims.ClientID != "" {
+			c.Set("clientId", claims.ClientID)
+			c.Request = c.Request.WithContext(logging.With(c.Request.Context(), "client_id", claims.ClientID))
+		} else {
+			c.Set("userId", claims.UserID)
+			c.Set("email", claims.Email)
+			c.Request = c.Request.WithContext(logging.With(c.Request.Context(), "user_id", claims.UserID))
+		}
+		c.Set("roles", claims.Roles)
+		c.Set("scopes", strings.Fields(claims.Scope))
+		c.Set("tokenId", claims.ID)
+		c.Set("tokenExpiresAt", claims.ExpiresAt.Time)
 		c.Next()
 	}
 }
 
+// GetTokenID returns the jti of the access token that authenticated the
+// request and when that token expires.
+func GetTokenID(c *gin.Context) (string, time.Time, bool) {
+	jti := c.GetString("tokenId")
+	if jti == "" {
+		return "", time.Time{}, false
+	}
+	return jti, c.GetTime("tokenExpiresAt"), true
+}
+
 func GetUserID(c *gin.Context) (string, bool) {
 	userID, exists := c.Get("userId")
 	if !exists {
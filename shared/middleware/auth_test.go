@@ -0,0 +1,184 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/eaglebank/shared/jwks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func testClaims(jti string) Claims {
+	return Claims{
+		UserID: "usr-001",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}
+}
+
+func newTestKeys(t *testing.T) *jwks.KeySet {
+	t.Helper()
+	keys, err := jwks.GenerateKeySet()
+	if err != nil {
+		t.Fatalf("failed to generate keys: %v", err)
+	}
+	UseJWTKeys(keys)
+	t.Cleanup(func() { UseJWTKeys(nil) })
+	return keys
+}
+
+func signTestToken(t *testing.T, keys *jwks.KeySet, jti string) string {
+	t.Helper()
+	signed, err := keys.Sign(testClaims(jti))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return signed
+}
+
+func newAuthTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/private", AuthMiddleware(), func(c *gin.Context) {
+		jti, _, _ := GetTokenID(c)
+		c.String(http.StatusOK, jti)
+	})
+	return r
+}
+
+func authRequest(r *gin.Engine, token string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodGet, "/private", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthMiddlewareSignature(t *testing.T) {
+	keys := newTestKeys(t)
+	other, _ := jwks.GenerateKeySet()
+
+	// A token signed with HS256 using the public key as the secret must not
+	// verify, whatever the kid says.
+	hs := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims("jti-hs"))
+	hs.Header["kid"] = keys.ActiveKeyID()
+	hsToken, _ := hs.SignedString([]byte(keys.JWKS().Keys[0].X))
+
+	tests := []struct {
+		name           string
+		token          string
+		expectedStatus int
+	}{
+		{name: "success - signed with the active key", token: signTestToken(t, keys, "jti-001"), expectedStatus: http.StatusOK},
+		{name: "unauthorised - signed with an unknown key", token: signTestToken(t, other, "jti-002"), expectedStatus: http.StatusUnauthorized},
+		{name: "unauthorised - HS256 with a known kid", token: hsToken, expectedStatus: http.StatusUnauthorized},
+	}
+	r := newAuthTestRouter()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := authRequest(r, tt.token)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("[%s] expected %d got %d; body: %s", tt.name, tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareDenylist(t *testing.T) {
+	keys := newTestKeys(t)
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	denylist := NewRedisTokenDenylist(client)
+	UseTokenDenylist(denylist)
+	t.Cleanup(func() { UseTokenDenylist(nil) })
+
+	if err := denylist.Revoke(context.Background(), "jti-revoked", time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("failed to revoke: %v", err)
+	}
+
+	r := newAuthTestRouter()
+	tests := []struct {
+		name           string
+		token          string
+		redisDown      bool
+		expectedStatus int
+	}{
+		{name: "success - live token", token: signTestToken(t, keys, "jti-live"), expectedStatus: http.StatusOK},
+		{name: "unauthorised - revoked token", token: signTestToken(t, keys, "jti-revoked"), expectedStatus: http.StatusUnauthorized},
+		{name: "unauthorised - token without jti", token: signTestToken(t, keys, ""), expectedStatus: http.StatusUnauthorized},
+		{name: "unavailable - denylist unreachable", token: signTestToken(t, keys, "jti-live"), redisDown: true, expectedStatus: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.redisDown {
+				mr.SetError("connection refused")
+				defer mr.SetError("")
+			}
+			w := authRequest(r, tt.token)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("[%s] expected %d got %d; body: %s", tt.name, tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareClients(t *testing.T) {
+	keys := newTestKeys(t)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/private", AuthMiddleware(), func(c *gin.Context) {
+		c.String(http.StatusOK, GetPrincipal(c).Subject())
+	})
+	r.GET("/scoped", ScopedAuthMiddleware(), func(c *gin.Context) {
+		c.String(http.StatusOK, GetPrincipal(c).Subject())
+	})
+
+	sign := func(userID, clientID string) string {
+		claims := testClaims("jti-" + userID + clientID)
+		claims.UserID = userID
+		claims.ClientID = clientID
+		token, err := keys.Sign(claims)
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		return token
+	}
+
+	tests := []struct {
+		name            string
+		path            string
+		token           string
+		expectedStatus  int
+		expectedSubject string
+	}{
+		{name: "success - user on a user route", path: "/private", token: sign("usr-001", ""), expectedStatus: http.StatusOK, expectedSubject: "usr-001"},
+		{name: "forbidden - client on a user route", path: "/private", token: sign("", "cli-001"), expectedStatus: http.StatusForbidden},
+		{name: "success - user on a scoped route", path: "/scoped", token: sign("usr-001", ""), expectedStatus: http.StatusOK, expectedSubject: "usr-001"},
+		{name: "success - client on a scoped route", path: "/scoped", token: sign("", "cli-001"), expectedStatus: http.StatusOK, expectedSubject: "cli-001"},
+		{name: "unauthorised - token for both a user and a client", path: "/scoped", token: sign("usr-001", "cli-001"), expectedStatus: http.StatusUnauthorized},
+		{name: "unauthorised - token for neither", path: "/scoped", token: sign("", ""), expectedStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("[%s] expected %d got %d; body: %s", tt.name, tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedSubject != "" && w.Body.String() != tt.expectedSubject {
+				t.Errorf("[%s] expected subject %q got %q", tt.name, tt.expectedSubject, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header carrying the client's key.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses served from a stored record.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// IdempotencyRecord is what is stored against an Idempotency-Key. A record
+// without a Status is a reservation for a request that is still in flight.
+type IdempotencyRecord struct {
+	Fingerprint string `json:"fingerprint"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// IdempotencyStore persists idempotency records.
+type IdempotencyStore interface {
+	// Reserve claims key for a new request with the given fingerprint. It
+	// returns nil if the key was free, or the existing record if it was not.
+	Reserve(ctx context.Context, key, fingerprint string) (*IdempotencyRecord, error)
+	// Save stores the completed response for key.
+	Save(ctx context.Context, key string, record *IdempotencyRecord) error
+	// Release frees a reservation so the request can be retried.
+	Release(ctx context.Context, key string) error
+}
+
+// IdempotencyMiddleware makes a mutating route safe to retry. When a request
+// carries an Idempotency-Key header the first response (other than a 5xx) is
+// stored, and later requests with the same key and the same method, path and
+// body get that response replayed. Reusing a key for a different request is
+// rejected with 422, and a retry that arrives while the original is still in
+// flight gets 409. Requests without the header are passed straight through.
+//
+// Keys are scoped to the authenticated user, so the middleware must run after
+// AuthMiddleware.
+func IdempotencyMiddleware(store IdempotencyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			RespondWithError(c, http.StatusBadRequest, "Idempotency-Key must be at most 255 characters")
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			RespondWithError(c, http.StatusBadRequest, "Invalid request body")
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		storeKey := fmt.Sprintf("idempotency:%s:%s", GetPrincipal(c).Subject(), key)
+		fingerprint := requestFingerprint(c.Request.Method, c.Request.URL.Path, body)
+		ctx := c.Request.Context()
+
+		existing, err := store.Reserve(ctx, storeKey, fingerprint)
+		if err != nil {
+			RespondWithError(c, http.StatusServiceUnavailable, "Idempotency store unavailable")
+			c.Abort()
+			return
+		}
+		if existing != nil {
+			switch {
+			case existing.Fingerprint != fingerprint:
+				RespondWithError(c, http.StatusUnprocessableEntity, "Idempotency-Key has already been used for a different request")
+			case existing.Status == 0:
+				RespondWithError(c, http.StatusConflict, "A request with this Idempotency-Key is already in progress")
+			default:
+				c.Header(IdempotentReplayedHeader, "true")
+				c.Data(existing.Status, existing.ContentType, existing.Body)
+			}
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// Server errors are not recorded, so the client can retry with the same key.
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			if err := store.Release(ctx, storeKey); err != nil {
+				slog.ErrorContext(ctx, "Failed to release idempotency key", "key", storeKey, "err", err)
+			}
+			return
+		}
+		record := &IdempotencyRecord{
+			Fingerprint: fingerprint,
+			Status:      c.Writer.Status(),
+			ContentType: c.Writer.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		}
+		if err := store.Save(ctx, storeKey, record); err != nil {
+			slog.ErrorContext(ctx, "Failed to save idempotency record", "key", storeKey, "err", err)
+		}
+	}
+}
+
+func requestFingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder tees the response body so it can be stored.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// RedisIdempotencyStore keeps idempotency records in Redis. Completed records
+// live for ttl; reservations expire after lockTTL so a request that died
+// mid-flight does not block its key for the full retention period.
+type RedisIdempotencyStore struct {
+	client  *goredis.Client
+	ttl     time.Duration
+	lockTTL time.Duration
+}
+
+// NewRedisIdempotencyStore creates a store that retains responses for ttl
+// (24h if zero).
+func NewRedisIdempotencyStore(client *goredis.Client, ttl time.Duration) *RedisIdempotencyStore {
+	if ttl == 0 {
+		ttl = 24 * time.Hour
+	}
+	return &RedisIdempotencyStore{client: client, ttl: ttl, lockTTL: time.Minute}
+}
+
+func (s *RedisIdempotencyStore) Reserve(ctx context.Context, key, fingerprint string) (*IdempotencyRecord, error) {
+	reservation, err := json.Marshal(IdempotencyRecord{Fingerprint: fingerprint})
+	if err != nil {
+		return nil, err
+	}
+	// The existing record can expire between SETNX and GET; try again once.
+	for attempt := 0; attempt < 2; attempt++ {
+		ok, err := s.client.SetNX(ctx, key, reservation, s.lockTTL).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+		}
+		if ok {
+			return nil, nil
+		}
+		data, err := s.client.Get(ctx, key).Bytes()
+		if err == goredis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get idempotency record: %w", err)
+		}
+		var record IdempotencyRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal idempotency record: %w", err)
+		}
+		return &record, nil
+	}
+	return nil, fmt.Errorf("failed to reserve idempotency key")
+}
+
+func (s *RedisIdempotencyStore) Save(ctx context.Context, key string, record *IdempotencyRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, key, data, s.ttl).Err()
+}
+
+func (s *RedisIdempotencyStore) Release(ctx context.Context, key string) error {
+	return s.client.Del(ctx, key).Err()
+}
@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ---- in-memory store ----
+
+type memoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]*IdempotencyRecord
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{records: make(map[string]*IdempotencyRecord)}
+}
+
+func (s *memoryIdempotencyStore) Reserve(_ context.Context, key, fingerprint string) (*IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.records[key]; ok {
+		return existing, nil
+	}
+	s.records[key] = &IdempotencyRecord{Fingerprint: fingerprint}
+	return nil, nil
+}
+
+func (s *memoryIdempotencyStore) Save(_ context.Context, key string, record *IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key] = record
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+// ---- helpers ----
+
+func newIdempotencyTestRouter(store IdempotencyStore, calls *int, status int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userId", c.GetHeader("X-Test-User"))
+		c.Next()
+	})
+	r.POST("/v1/accounts", IdempotencyMiddleware(store), func(c *gin.Context) {
+		*calls++
+		c.JSON(status, gin.H{"call": *calls})
+	})
+	return r
+}
+
+func idempotentRequest(router *gin.Engine, user, key, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodPost, "/v1/accounts", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Test-User", user)
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// ---- tests ----
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	tests := []struct {
+		name             string
+		status           int
+		first            [3]string // user, key, body
+		second           [3]string
+		expectedStatus   int
+		expectedCalls    int
+		expectedReplayed bool
+	}{
+		{
+			name:             "replay - same key and body returns the stored response",
+			status:           http.StatusCreated,
+			first:            [3]string{"usr-001", "key-1", `{"name":"a"}`},
+			second:           [3]string{"usr-001", "key-1", `{"name":"a"}`},
+			expectedStatus:   http.StatusCreated,
+			expectedCalls:    1,
+			expectedReplayed: true,
+		},
+		{
+			name:           "unprocessable entity - same key with a different body",
+			status:         http.StatusCreated,
+			first:          [3]string{"usr-001", "key-1", `{"name":"a"}`},
+			second:         [3]string{"usr-001", "key-1", `{"name":"b"}`},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCalls:  1,
+		},
+		{
+			name:           "separate - keys are scoped per user",
+			status:         http.StatusCreated,
+			first:          [3]string{"usr-001", "key-1", `{"name":"a"}`},
+			second:         [3]string{"usr-002", "key-1", `{"name":"a"}`},
+			expectedStatus: http.StatusCreated,
+			expectedCalls:  2,
+		},
+		{
+			name:           "pass through - no key means no deduplication",
+			status:         http.StatusCreated,
+			first:          [3]string{"usr-001", "", `{"name":"a"}`},
+			second:         [3]string{"usr-001", "", `{"name":"a"}`},
+			expectedStatus: http.StatusCreated,
+			expectedCalls:  2,
+		},
+		{
+			name:           "retry - server errors are not stored",
+			status:         http.StatusInternalServerError,
+			first:          [3]string{"usr-001", "key-1", `{"name":"a"}`},
+			second:         [3]string{"usr-001", "key-1", `{"name":"a"}`},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCalls:  2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			router := newIdempotencyTestRouter(newMemoryIdempotencyStore(), &calls, tt.status)
+			first := idempotentRequest(router, tt.first[0], tt.first[1], tt.first[2])
+			w := idempotentRequest(router, tt.second[0], tt.second[1], tt.second[2])
+			if w.Code != tt.expectedStatus {
+				t.Errorf("[%s] expected %d got %d; body: %s", tt.name, tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if calls != tt.expectedCalls {
+				t.Errorf("[%s] expected handler to run %d times, ran %d", tt.name, tt.expectedCalls, calls)
+			}
+			if replayed := w.Header().Get(IdempotentReplayedHeader) == "true"; replayed != tt.expectedReplayed {
+				t.Errorf("[%s] expected replayed=%v", tt.name, tt.expectedReplayed)
+			}
+			if tt.expectedReplayed && w.Body.String() != first.Body.String() {
+				t.Errorf("[%s] replayed body %s differs from original %s", tt.name, w.Body.String(), first.Body.String())
+			}
+		})
+	}
+}
+
+func TestIdempotencyMiddlewareInFlight(t *testing.T) {
+	store := newMemoryIdempotencyStore()
+	store.records["idempotency:usr-001:key-1"] = &IdempotencyRecord{
+		Fingerprint: requestFingerprint(http.MethodPost, "/v1/accounts", []byte(`{"name":"a"}`)),
+	}
+	calls := 0
+	router := newIdempotencyTestRouter(store, &calls, http.StatusCreated)
+	w := idempotentRequest(router, "usr-001", "key-1", `{"name":"a"}`)
+	if w.Code != http.StatusConflict || calls != 0 {
+		t.Errorf("expected 409 without running the handler, got %d after %d calls", w.Code, calls)
+	}
+}
@@ -1,35 +1,51 @@
 package middleware
 
 import (
-	"log"
+	"log/slog"
+	"net/http"
 	"time"
 
+	"github.com/eaglebank/shared/logging"
 	"github.com/gin-gonic/gin"
 )
 
+// LoggingMiddleware puts the request's ID, method and route in its context,
+// so everything logged while handling it carries them, and logs the request
+// once it has been answered: server errors as errors and client errors as
+// warnings. It should run after RequestIDMiddleware.
 func LoggingMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
-		path := c.Request.URL.Path
-		raw := c.Request.URL.RawQuery
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		ctx := logging.With(c.Request.Context(),
+			"request_id", GetRequestID(c),
+			"method", c.Request.Method,
+			"route", route,
+		)
+		c.Request = c.Request.WithContext(ctx)
 
 		c.Next()
 
-		latency := time.Since(start)
-		statusCode := c.Writer.Status()
-		method := c.Request.Method
-		clientIP := c.ClientIP()
-
-		if raw != "" {
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		path := c.Request.URL.Path
+		if raw := c.Request.URL.RawQuery; raw != "" {
 			path = path + "?" + raw
 		}
-
-		log.Printf("[%s] %d | %13v | %15s | %s",
-			method,
-			statusCode,
-			latency,
-			clientIP,
-			path,
+		slog.Log(c.Request.Context(), level, "Request handled",
+			"status", status,
+			"latency_ms", float64(time.Since(start).Microseconds())/1000,
+			"client_ip", c.ClientIP(),
+			"path", path,
 		)
 	}
 }
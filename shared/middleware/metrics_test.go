@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(MetricsMiddleware())
+	r.GET("/metrics", MetricsHandler())
+	r.GET("/v1/accounts/:accountNumber", func(c *gin.Context) {
+		c.Status(http.StatusNotFound)
+	})
+
+	for _, path := range []string{"/v1/accounts/01234567", "/v1/accounts/07654321", "/nowhere"} {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if got := testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/v1/accounts/:accountNumber", "404")); got != 2 {
+		t.Errorf("expected both account requests under the route pattern, got %v", got)
+	}
+	if got := testutil.ToFloat64(httpRequests.WithLabelValues("GET", "unmatched", "404")); got != 1 {
+		t.Errorf("expected the unrouted request under \"unmatched\", got %v", got)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/metrics", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `eagle_http_request_duration_seconds_count{method="GET",route="/v1/accounts/:accountNumber",status="404"} 2`) {
+		t.Errorf("expected /metrics to expose the request histogram, got %d", w.Code)
+	}
+}
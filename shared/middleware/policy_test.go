@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestScopesForRoles(t *testing.T) {
+	tests := []struct {
+		name     string
+		roles    []string
+		expected []string
+	}{
+		{name: "customer - no roles grant nothing", roles: nil, expected: nil},
+		{name: "unknown role grants nothing", roles: []string{"superuser"}, expected: nil},
+		{
+			name:     "support - read only",
+			roles:    []string{RoleSupport},
+			expected: []string{ScopeAccountsRead, ScopeTransactionsRead, ScopeUsersRead},
+		},
+		{
+			name:  "support and admin - union without duplicates",
+			roles: []string{RoleSupport, RoleAdmin},
+			expected: []string{
+				ScopeAccountsFreeze, ScopeAccountsRead, ScopeAccountsWrite, ScopeClientsManage,
+				ScopeTransactionsAdjust, ScopeTransactionsRead, ScopeUsersRead,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ScopesForRoles(tt.roles); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("[%s] expected %v got %v", tt.name, tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPrincipalCanAccess(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal Principal
+		ownerID   string
+		expected  bool
+	}{
+		{name: "owner", principal: Principal{UserID: "usr-001"}, ownerID: "usr-001", expected: true},
+		{name: "other customer", principal: Principal{UserID: "usr-002"}, ownerID: "usr-001", expected: false},
+		{
+			name:      "staff with the scope",
+			principal: Principal{UserID: "usr-009", Scopes: []string{ScopeAccountsRead}},
+			ownerID:   "usr-001",
+			expected:  true,
+		},
+		{
+			name:      "staff with another scope",
+			principal: Principal{UserID: "usr-009", Scopes: []string{ScopeUsersRead}},
+			ownerID:   "usr-001",
+			expected:  false,
+		},
+		{name: "anonymous never owns", principal: Principal{}, ownerID: "", expected: false},
+		{name: "client never owns", principal: Principal{ClientID: "cli-001"}, ownerID: "", expected: false},
+		{
+			name:      "client with the scope",
+			principal: Principal{ClientID: "cli-001", Scopes: []string{ScopeAccountsRead}},
+			ownerID:   "usr-001",
+			expected:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.principal.CanAccess(tt.ownerID, ScopeAccountsRead); got != tt.expected {
+				t.Errorf("[%s] expected %v got %v", tt.name, tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	keys := newTestKeys(t)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/private", AuthMiddleware(), RequireScope(ScopeAccountsFreeze), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name           string
+		roles          []string
+		expectedStatus int
+	}{
+		{name: "success - admin holds the scope", roles: []string{RoleAdmin}, expectedStatus: http.StatusNoContent},
+		{name: "forbidden - support lacks the scope", roles: []string{RoleSupport}, expectedStatus: http.StatusForbidden},
+		{name: "forbidden - customer", roles: nil, expectedStatus: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := testClaims("jti-" + tt.name)
+			claims.Roles = tt.roles
+			claims.Scope = strings.Join(ScopesForRoles(tt.roles), " ")
+			token, err := keys.Sign(claims)
+			if err != nil {
+				t.Fatalf("failed to sign token: %v", err)
+			}
+			if w := authRequest(r, token); w.Code != tt.expectedStatus {
+				t.Errorf("[%s] expected %d got %d", tt.name, tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Rate limit keys: what a rule counts requests against.
+const (
+	// RateLimitByIP counts requests per client address.
+	RateLimitByIP = "ip"
+	// RateLimitByUser counts requests per authenticated user or API client,
+	// falling back to the client address when there is none. Rules keyed by
+	// user must run after AuthMiddleware.
+	RateLimitByUser = "user"
+)
+
+// RateLimitRule is a token bucket for one route group. Each key gets Burst
+// tokens (Limit if zero), refilled at Limit per Period; a request spends one.
+type RateLimitRule struct {
+	Name   string   `json:"name"`
+	Key    string   `json:"key"`
+	Limit  int      `json:"limit"`
+	Period Duration `json:"period"`
+	Burst  int      `json:"burst,omitempty"`
+}
+
+// Duration is a time.Duration written as a string such as "1m" or "24h".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (r RateLimitRule) capacity() int {
+	if r.Burst > 0 {
+		return r.Burst
+	}
+	return r.Limit
+}
+
+func (r RateLimitRule) validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("rate limit rule without a name")
+	}
+	if r.Key != RateLimitByIP && r.Key != RateLimitByUser {
+		return fmt.Errorf("rate limit rule %q: key must be %q or %q", r.Name, RateLimitByIP, RateLimitByUser)
+	}
+	if r.Limit <= 0 || r.Period <= 0 || r.Burst < 0 {
+		return fmt.Errorf("rate limit rule %q: limit and period must be positive", r.Name)
+	}
+	return nil
+}
+
+// LoadRateLimitRules reads rules from a JSON file of the form
+// {"rules": [{"name": "login", "key": "ip", "limit": 10, "period": "1m"}]}.
+func LoadRateLimitRules(path string) ([]RateLimitRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rate limit rules: %w", err)
+	}
+	var file struct {
+		Rules []RateLimitRule `json:"rules"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse rate limit rules: %w", err)
+	}
+	return file.Rules, nil
+}
+
+// RateLimitResult is the state of a bucket after taking a token.
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is how long until the next token; ResetAfter how long until
+	// the bucket is full again.
+	RetryAfter time.Duration
+	ResetAfter time.Duration
+}
+
+// RateLimitStore holds token buckets.
+type RateLimitStore interface {
+	// Take spends a token from the bucket at key, which holds capacity tokens
+	// and refills at rate tokens per second.
+	Take(ctx context.Context, key string, capacity int, rate float64) (*RateLimitResult, error)
+}
+
+// RateLimiter applies configured rules to the routes they name.
+type RateLimiter struct {
+	store RateLimitStore
+	rules map[string]RateLimitRule
+}
+
+// NewRateLimiter checks rules and returns a limiter keeping its buckets in
+// store.
+func NewRateLimiter(store RateLimitStore, rules []RateLimitRule) (*RateLimiter, error) {
+	byName := make(map[string]RateLimitRule, len(rules))
+	for _, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return nil, err
+		}
+		if _, ok := byName[rule.Name]; ok {
+			return nil, fmt.Errorf("rate limit rule %q defined twice", rule.Name)
+		}
+		byName[rule.Name] = rule
+	}
+	return &RateLimiter{store: store, rules: byName}, nil
+}
+
+// Limit returns middleware enforcing the rule called name. Every response
+// carries RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset (seconds
+// until the bucket is full); a request over the limit gets 429 with
+// Retry-After. If the store is unreachable the request is let through: the
+// limiter protects capacity and must not take the API down with it.
+//
+// Limit panics if no rule is called name, so a typo fails at startup.
+func (l *RateLimiter) Limit(name string) gin.HandlerFunc {
+	rule, ok := l.rules[name]
+	if !ok {
+		panic(fmt.Sprintf("rate limit rule %q is not configured", name))
+	}
+	capacity := rule.capacity()
+	rate := float64(rule.Limit) / time.Duration(rule.Period).Seconds()
+	policy := fmt.Sprintf("%d;w=%d", rule.Limit, int64(time.Duration(rule.Period).Seconds()))
+	if rule.Burst > 0 {
+		policy += fmt.Sprintf(";burst=%d", rule.Burst)
+	}
+
+	return func(c *gin.Context) {
+		subject := c.ClientIP()
+		if rule.Key == RateLimitByUser {
+			if principal := GetPrincipal(c); principal.Subject() != "" {
+				subject = principal.Subject()
+			}
+		}
+		key := fmt.Sprintf("ratelimit:%s:%s", rule.Name, subject)
+
+		result, err := l.store.Take(c.Request.Context(), key, capacity, rate)
+		if err != nil {
+			slog.ErrorContext(c.Request.Context(), "Rate limit not applied", "rule", rule.Name, "err", err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Policy", policy)
+		c.Header("RateLimit-Limit", strconv.Itoa(capacity))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", ceilSeconds(result.ResetAfter))
+		if !result.Allowed {
+			c.Header("Retry-After", ceilSeconds(result.RetryAfter))
+			RespondWithError(c, http.StatusTooManyRequests, "Too many requests; please wait before trying again")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
+
+// takeTokenScript refills the bucket for the time since it was last touched,
+// spends a token if there is one, and returns whether it did, the tokens
+// left, and the milliseconds until the next token and until the bucket is
+// full. Time comes from Redis so every gateway instance agrees.
+var takeTokenScript = goredis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2]) / 1000
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(bucket[1]) or capacity
+local ts = tonumber(bucket[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+
+local retry = 0
+if tokens < 1 then
+	retry = math.ceil((1 - tokens) / rate)
+end
+local reset = math.ceil((capacity - tokens) / rate)
+redis.call('PEXPIRE', KEYS[1], reset + 1000)
+return {allowed, math.floor(tokens), retry, reset}
+`)
+
+// RedisRateLimitStore keeps token buckets in Redis hashes that expire once
+// full, so idle keys cost nothing.
+type RedisRateLimitStore struct {
+	client *goredis.Client
+}
+
+func NewRedisRateLimitStore(client *goredis.Client) *RedisRateLimitStore {
+	return &RedisRateLimitStore{client: client}
+}
+
+func (s *RedisRateLimitStore) Take(ctx context.Context, key string, capacity int, rate float64) (*RateLimitResult, error) {
+	values, err := takeTokenScript.Run(ctx, s.client, []string{key}, capacity, rate).Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+	if len(values) != 4 {
+		return nil, fmt.Errorf("unexpected rate limit script result %v", values)
+	}
+	return &RateLimitResult{
+		Allowed:    values[0] == 1,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+		ResetAfter: time.Duration(values[3]) * time.Millisecond,
+	}, nil
+}
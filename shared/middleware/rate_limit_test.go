@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func newRateLimitTestRouter(t *testing.T, rules []RateLimitRule) (*gin.Engine, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	mr.SetTime(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	limiter, err := NewRateLimiter(NewRedisRateLimitStore(client), rules)
+	if err != nil {
+		t.Fatalf("failed to create limiter: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if userID := c.GetHeader("X-Test-User"); userID != "" {
+			c.Set("userId", userID)
+		}
+		c.Next()
+	})
+	for _, rule := range rules {
+		r.POST("/"+rule.Name, limiter.Limit(rule.Name), func(c *gin.Context) {
+			c.Status(http.StatusNoContent)
+		})
+	}
+	return r, mr
+}
+
+func rateLimitRequest(r *gin.Engine, path, ip, userID string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodPost, path, nil)
+	req.RemoteAddr = ip + ":40000"
+	if userID != "" {
+		req.Header.Set("X-Test-User", userID)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimit(t *testing.T) {
+	r, mr := newRateLimitTestRouter(t, []RateLimitRule{
+		{Name: "login", Key: RateLimitByIP, Limit: 2, Period: Duration(time.Minute)},
+		{Name: "transactions", Key: RateLimitByUser, Limit: 1, Period: Duration(time.Minute)},
+	})
+
+	tests := []struct {
+		name              string
+		path              string
+		ip                string
+		userID            string
+		advance           time.Duration
+		expectedStatus    int
+		expectedRemaining string
+		expectedRetry     string
+	}{
+		{name: "first login", path: "/login", ip: "203.0.113.1", expectedStatus: http.StatusNoContent, expectedRemaining: "1"},
+		{name: "second login", path: "/login", ip: "203.0.113.1", expectedStatus: http.StatusNoContent, expectedRemaining: "0"},
+		{name: "third login is throttled", path: "/login", ip: "203.0.113.1", expectedStatus: http.StatusTooManyRequests, expectedRemaining: "0", expectedRetry: "30"},
+		{name: "another address has its own bucket", path: "/login", ip: "203.0.113.2", expectedStatus: http.StatusNoContent, expectedRemaining: "1"},
+		{name: "a token refills after 30s", path: "/login", ip: "203.0.113.1", advance: 30 * time.Second, expectedStatus: http.StatusNoContent, expectedRemaining: "0"},
+		{name: "user transaction", path: "/transactions", ip: "203.0.113.1", userID: "usr-001", expectedStatus: http.StatusNoContent, expectedRemaining: "0"},
+		{name: "same user from elsewhere is throttled", path: "/transactions", ip: "203.0.113.9", userID: "usr-001", expectedStatus: http.StatusTooManyRequests, expectedRemaining: "0", expectedRetry: "60"},
+		{name: "another user on the same address", path: "/transactions", ip: "203.0.113.1", userID: "usr-002", expectedStatus: http.StatusNoContent, expectedRemaining: "0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.advance > 0 {
+				mr.SetTime(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC).Add(tt.advance))
+			}
+			w := rateLimitRequest(r, tt.path, tt.ip, tt.userID)
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("[%s] expected %d got %d; body: %s", tt.name, tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if got := w.Header().Get("RateLimit-Remaining"); got != tt.expectedRemaining {
+				t.Errorf("[%s] expected RateLimit-Remaining %q got %q", tt.name, tt.expectedRemaining, got)
+			}
+			if got := w.Header().Get("Retry-After"); got != tt.expectedRetry {
+				t.Errorf("[%s] expected Retry-After %q got %q", tt.name, tt.expectedRetry, got)
+			}
+		})
+	}
+}
+
+func TestRateLimitFailsOpen(t *testing.T) {
+	r, mr := newRateLimitTestRouter(t, []RateLimitRule{
+		{Name: "login", Key: RateLimitByIP, Limit: 1, Period: Duration(time.Minute)},
+	})
+	mr.SetError("connection refused")
+
+	for i := 0; i < 3; i++ {
+		if w := rateLimitRequest(r, "/login", "203.0.113.1", ""); w.Code != http.StatusNoContent {
+			t.Fatalf("expected requests through while Redis is down, got %d", w.Code)
+		}
+	}
+}
+
+func TestLoadRateLimitRules(t *testing.T) {
+	tests := []struct {
+		name        string
+		contents    string
+		expectedErr bool
+	}{
+		{
+			name:     "valid",
+			contents: `{"rules": [{"name": "accounts", "key": "user", "limit": 5, "period": "24h", "burst": 2}]}`,
+		},
+		{name: "bad period", contents: `{"rules": [{"name": "login", "key": "ip", "limit": 5, "period": "soon"}]}`, expectedErr: true},
+		{name: "unknown key", contents: `{"rules": [{"name": "login", "key": "email", "limit": 5, "period": "1m"}]}`, expectedErr: true},
+		{name: "zero limit", contents: `{"rules": [{"name": "login", "key": "ip", "limit": 0, "period": "1m"}]}`, expectedErr: true},
+		{
+			name:        "duplicate rule",
+			contents:    `{"rules": [{"name": "login", "key": "ip", "limit": 5, "period": "1m"}, {"name": "login", "key": "ip", "limit": 9, "period": "1m"}]}`,
+			expectedErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "ratelimits.json")
+			if err := os.WriteFile(path, []byte(tt.contents), 0o600); err != nil {
+				t.Fatalf("failed to write rules: %v", err)
+			}
+			rules, err := LoadRateLimitRules(path)
+			if err == nil {
+				_, err = NewRateLimiter(nil, rules)
+			}
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("[%s] expected error %v got %v", tt.name, tt.expectedErr, err)
+			}
+			if err == nil && (rules[0].Period != Duration(24*time.Hour) || rules[0].capacity() != 2) {
+				t.Errorf("[%s] unexpected rule %+v", tt.name, rules[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"github.com/eaglebank/shared/correlation"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDMiddleware adopts the caller's X-Request-ID, or assigns one if it
+// is missing or malformed, and echoes it on the response. The ID is left on
+// the request headers, so proxied requests carry it on, and in the request
+// context as the correlation and causation ID of anything the request
+// publishes. It should be the first middleware so every log line has it.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(correlation.Header)
+		if !correlation.ValidRequestID(id) {
+			id = correlation.NewRequestID()
+			c.Request.Header.Set(correlation.Header, id)
+		}
+		c.Set("requestId", id)
+		c.Header(correlation.Header, id)
+		ctx := correlation.NewContext(c.Request.Context(), correlation.IDs{CorrelationID: id, CausationID: id})
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// GetRequestID returns the ID RequestIDMiddleware gave the request.
+func GetRequestID(c *gin.Context) string {
+	return c.GetString("requestId")
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/eaglebank/shared/correlation"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestIDMiddleware())
+	r.GET("/", func(c *gin.Context) {
+		ids := correlation.FromContext(c.Request.Context())
+		c.String(http.StatusOK, ids.CorrelationID+" "+ids.CausationID+" "+c.GetHeader(correlation.Header))
+	})
+
+	tests := []struct {
+		name       string
+		header     string
+		expectedID string
+	}{
+		{name: "adopts the caller's ID", header: "req-abc_123.4:5", expectedID: "req-abc_123.4:5"},
+		{name: "assigns an ID when missing"},
+		{name: "replaces an ID that could forge log lines", header: "abc\n[GET] 200"},
+		{name: "replaces an overlong ID", header: strings.Repeat("a", 129)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(correlation.Header, tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			id := w.Header().Get(correlation.Header)
+			if tt.expectedID != "" && id != tt.expectedID {
+				t.Fatalf("[%s] expected ID %q got %q", tt.name, tt.expectedID, id)
+			}
+			if tt.expectedID == "" && (id == tt.header || !strings.HasPrefix(id, "req")) {
+				t.Fatalf("[%s] expected a generated ID, got %q", tt.name, id)
+			}
+			if got := w.Body.String(); got != id+" "+id+" "+id {
+				t.Errorf("[%s] expected the handler to see %q throughout, got %q", tt.name, id, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const tokenDenylistKeyPrefix = "auth:denylist:"
+
+// TokenDenylist records access tokens, by jti, that were revoked before they
+// expired.
+type TokenDenylist interface {
+	// Revoke denies the token until it would have expired anyway.
+	Revoke(ctx context.Context, jti string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+var tokenDenylist TokenDenylist
+
+// UseTokenDenylist makes AuthMiddleware reject tokens revoked in denylist. It
+// must be called at startup, before any requests are served.
+func UseTokenDenylist(denylist TokenDenylist) {
+	tokenDenylist = denylist
+}
+
+// RedisTokenDenylist keeps revoked jtis in Redis, each expiring with the token
+// it denies so the list never outgrows the set of live tokens.
+type RedisTokenDenylist struct {
+	client *goredis.Client
+}
+
+func NewRedisTokenDenylist(client *goredis.Client) *RedisTokenDenylist {
+	return &RedisTokenDenylist{client: client}
+}
+
+func (d *RedisTokenDenylist) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	if err := d.client.Set(ctx, tokenDenylistKeyPrefix+jti, 1, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	return nil
+}
+
+func (d *RedisTokenDenylist) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	n, err := d.client.Exists(ctx, tokenDenylistKeyPrefix+jti).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check token denylist: %w", err)
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestTracingMiddleware(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestIDMiddleware(), TracingMiddleware())
+	var handlerTrace trace.TraceID
+	r.GET("/v1/accounts/:accountNumber", func(c *gin.Context) {
+		handlerTrace = trace.SpanContextFromContext(c.Request.Context()).TraceID()
+		c.Status(http.StatusServiceUnavailable)
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/v1/accounts/01234567", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	ended := spans.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("expected one span, got %d", len(ended))
+	}
+	span := ended[0]
+	if span.Name() != "GET /v1/accounts/:accountNumber" || span.SpanKind() != trace.SpanKindServer {
+		t.Errorf("expected a server span named after the route, got %q (%s)", span.Name(), span.SpanKind())
+	}
+	if got := span.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" || handlerTrace.String() != got {
+		t.Errorf("expected the caller's trace to continue into the handler, got span %s handler %s", got, handlerTrace)
+	}
+	if span.Status().Code != codes.Error {
+		t.Errorf("expected a 503 to mark the span as an error, got %v", span.Status())
+	}
+}
@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/eaglebank/shared/money"
+)
 
 type Address struct {
 	Line1    string `json:"line1" validate:"required"`
@@ -11,6 +15,20 @@ type Address struct {
 	Postcode string `json:"postcode" validate:"required"`
 }
 
+// User statuses. A user is pending verification from registration until
+// they follow the link sent to their email address.
+const (
+	UserStatusPendingVerification = "pending_verification"
+	UserStatusActive              = "active"
+)
+
+// Account statuses. A frozen account accepts no transactions or transfers
+// until staff unfreeze it.
+const (
+	AccountStatusActive = "active"
+	AccountStatusFrozen = "frozen"
+)
+
 type User struct {
 	ID           string    `json:"id"`
 	Name         string    `json:"name"`
@@ -18,29 +36,127 @@ type User struct {
 	PasswordHash string    `json:"-"`
 	PhoneNumber  string    `json:"phoneNumber"`
 	Address      Address   `json:"address"`
+	Status       string    `json:"status"`
 	CreatedAt    time.Time `json:"createdTimestamp"`
 	UpdatedAt    time.Time `json:"updatedTimestamp"`
+	// LockedUntil is set while login is locked after repeated failures.
+	LockedUntil *time.Time `json:"-"`
 }
 
 type Account struct {
-	AccountNumber string    `json:"accountNumber"`
-	UserID        string    `json:"-"`
-	SortCode      string    `json:"sortCode"`
-	Name          string    `json:"name"`
-	AccountType   string    `json:"accountType"`
-	Balance       float64   `json:"balance"`
-	Currency      string    `json:"currency"`
-	CreatedAt     time.Time `json:"createdTimestamp"`
-	UpdatedAt     time.Time `json:"updatedTimestamp"`
+	AccountNumber string       `json:"accountNumber"`
+	UserID        string       `json:"-"`
+	SortCode      string       `json:"sortCode"`
+	Name          string       `json:"name"`
+	AccountType   string       `json:"accountType"`
+	Balance       money.Amount `json:"balance"`
+	Currency      string       `json:"currency"`
+	Status        string       `json:"status"`
+	CreatedAt     time.Time    `json:"createdTimestamp"`
+	UpdatedAt     time.Time    `json:"updatedTimestamp"`
 }
 
+// Transaction status values. A transaction is pending until account-service
+// posts it to the ledger or rejects it, e.g. for insufficient funds.
+const (
+	TransactionPending  = "pending"
+	TransactionPosted   = "posted"
+	TransactionRejected = "rejected"
+)
+
 type Transaction struct {
-	ID            string    `json:"id"`
-	AccountNumber string    `json:"-"`
-	UserID        string    `json:"userId"`
-	Amount        float64   `json:"amount"`
-	Currency      string    `json:"currency"`
-	Type          string    `json:"type"`
-	Reference     string    `json:"reference,omitempty"`
-	CreatedAt     time.Time `json:"createdTimestamp"`
+	ID            string       `json:"id"`
+	AccountNumber string       `json:"-"`
+	UserID        string       `json:"userId"`
+	Amount        money.Amount `json:"amount"`
+	Currency      string       `json:"currency"`
+	Type          string       `json:"type"`
+	Reference     string       `json:"reference,omitempty"`
+	TransferID    string       `json:"transferId,omitempty"`
+	Status        string       `json:"status"`
+	FailureReason string       `json:"failureReason,omitempty"`
+	CreatedAt     time.Time    `json:"createdTimestamp"`
+}
+
+// Transfer status values. A transfer moves pending -> debited -> completed on
+// the happy path; a rejected debit ends in failed, and a rejected credit is
+// compensated via reversing -> reversed (or reversal_failed).
+const (
+	TransferPending        = "pending"
+	TransferDebited        = "debited"
+	TransferCompleted      = "completed"
+	TransferFailed         = "failed"
+	TransferReversing      = "reversing"
+	TransferReversed       = "reversed"
+	TransferReversalFailed = "reversal_failed"
+)
+
+// Transfer moves money between two Eagle accounts. Each leg is recorded as a
+// Transaction carrying the transfer ID.
+type Transfer struct {
+	ID                string       `json:"id"`
+	FromAccountNumber string       `json:"fromAccountNumber"`
+	ToAccountNumber   string       `json:"toAccountNumber"`
+	UserID            string       `json:"-"`
+	ToUserID          string       `json:"-"`
+	Amount            money.Amount `json:"amount"`
+	Currency          string       `json:"currency"`
+	Reference         string       `json:"reference,omitempty"`
+	Status            string       `json:"status"`
+	FailureReason     string       `json:"failureReason,omitempty"`
+	CreatedAt         time.Time    `json:"createdTimestamp"`
+	UpdatedAt         time.Time    `json:"updatedTimestamp"`
+}
+
+// TokenPair is issued on login and refresh: a short-lived JWT access token and
+// an opaque refresh token that can be exchanged once for the next pair.
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration
+}
+
+// LoginResult is the outcome of a correct email and password. Users without
+// MFA get Tokens; users with MFA get an MFAToken challenge to exchange, with a
+// TOTP or recovery code, for tokens.
+type LoginResult struct {
+	Tokens       *TokenPair
+	MFAToken     string
+	MFAExpiresIn time.Duration
+}
+
+// APIClient is a machine caller, such as a batch job, that obtains access
+// tokens with the client_credentials grant. Its tokens carry Scopes and
+// act for no user.
+type APIClient struct {
+	ID         string     `json:"clientId"`
+	Name       string     `json:"name"`
+	SecretHash string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  string     `json:"createdBy"`
+	CreatedAt  time.Time  `json:"createdTimestamp"`
+	RevokedAt  *time.Time `json:"revokedTimestamp,omitempty"`
+}
+
+// APIClientCredentials is returned once, when a client is registered. The
+// secret cannot be retrieved again.
+type APIClientCredentials struct {
+	Client *APIClient
+	Secret string
+}
+
+// AccessToken is issued to an API client: a JWT access token with no
+// refresh token, and the space-separated scopes it was granted.
+type AccessToken struct {
+	AccessToken string
+	ExpiresIn   time.Duration
+	Scope       string
+}
+
+// MFAEnrolment is returned once, when a user starts TOTP enrolment. The
+// secret and recovery codes cannot be retrieved again.
+type MFAEnrolment struct {
+	Secret          string   `json:"secret"`
+	ProvisioningURI string   `json:"provisioningUri"`
+	RecoveryCodes   []string `json:"recoveryCodes"`
 }
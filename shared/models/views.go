@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/eaglebank/shared/money"
+)
 
 // UserView is the read-optimised projection of a user.
 // It never exposes PasswordHash and may be extended with derived/denormalised fields.
@@ -10,6 +14,7 @@ type UserView struct {
 	Email       string    `json:"email"`
 	PhoneNumber string    `json:"phoneNumber"`
 	Address     Address   `json:"address"`
+	Status      string    `json:"status"`
 	CreatedAt   time.Time `json:"createdTimestamp"`
 	UpdatedAt   time.Time `json:"updatedTimestamp"`
 }
@@ -17,26 +22,45 @@ type UserView struct {
 // AccountView is the read-optimised projection of an account.
 // UserID is populated for ownership checks but never serialised to the API response.
 type AccountView struct {
-	AccountNumber string    `json:"accountNumber"`
-	UserID        string    `json:"-"`
-	SortCode      string    `json:"sortCode"`
-	Name          string    `json:"name"`
-	AccountType   string    `json:"accountType"`
-	Balance       float64   `json:"balance"`
-	Currency      string    `json:"currency"`
-	CreatedAt     time.Time `json:"createdTimestamp"`
-	UpdatedAt     time.Time `json:"updatedTimestamp"`
+	AccountNumber string       `json:"accountNumber"`
+	UserID        string       `json:"-"`
+	SortCode      string       `json:"sortCode"`
+	Name          string       `json:"name"`
+	AccountType   string       `json:"accountType"`
+	Balance       money.Amount `json:"balance"`
+	Currency      string       `json:"currency"`
+	Status        string       `json:"status"`
+	CreatedAt     time.Time    `json:"createdTimestamp"`
+	UpdatedAt     time.Time    `json:"updatedTimestamp"`
+}
+
+// BalanceView is an account balance derived from the ledger as at a point in time.
+type BalanceView struct {
+	AccountNumber string       `json:"accountNumber"`
+	Balance       money.Amount `json:"balance"`
+	Currency      string       `json:"currency"`
+	AsAt          time.Time    `json:"asAtTimestamp"`
 }
 
 // TransactionView is the read-optimised projection of a transaction.
 // UserID is populated for ownership checks but never serialised to the API response.
 type TransactionView struct {
-	ID            string    `json:"id"`
-	AccountNumber string    `json:"accountNumber"`
-	UserID        string    `json:"-"`
-	Amount        float64   `json:"amount"`
-	Currency      string    `json:"currency"`
-	Type          string    `json:"type"`
-	Reference     string    `json:"reference,omitempty"`
-	CreatedAt     time.Time `json:"createdTimestamp"`
+	ID            string       `json:"id"`
+	AccountNumber string       `json:"accountNumber"`
+	UserID        string       `json:"-"`
+	Amount        money.Amount `json:"amount"`
+	Currency      string       `json:"currency"`
+	Type          string       `json:"type"`
+	Reference     string       `json:"reference,omitempty"`
+	TransferID    string       `json:"transferId,omitempty"`
+	Status        string       `json:"status"`
+	FailureReason string       `json:"failureReason,omitempty"`
+	CreatedAt     time.Time    `json:"createdTimestamp"`
+}
+
+// TransactionPage is one page of a transaction listing. NextCursor is empty
+// on the last page.
+type TransactionPage struct {
+	Transactions []TransactionView
+	NextCursor   string
 }
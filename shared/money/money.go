@@ -0,0 +1,264 @@
+// Package money provides exact monetary amounts stored as integer minor units.
+//
+// Amount is a count of pence (or cents) for a two-decimal currency. It encodes
+// to JSON as a decimal number with exactly two places (e.g. 1000.00) and to
+// SQL as a DECIMAL string, so neither the API wire format nor the Postgres
+// DECIMAL(10,2) columns change. Money pairs an Amount with its currency code
+// and refuses arithmetic across currencies.
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrOverflow         = errors.New("money: amount overflow")
+	ErrCurrencyMismatch = errors.New("money: currency mismatch")
+	ErrInvalidAmount    = errors.New("money: invalid amount")
+)
+
+// scale is the number of minor units per major unit.
+const scale = 100
+
+// Amount is an exact monetary value in minor units.
+type Amount int64
+
+// FromMinor returns the Amount for a count of minor units.
+func FromMinor(minor int64) Amount {
+	return Amount(minor)
+}
+
+// Parse converts a decimal string such as "1000", "1000.5" or "-12.34" to an
+// Amount. More than two decimal places or exponent notation are rejected
+// rather than rounded.
+func Parse(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, ErrInvalidAmount
+	}
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" {
+		return 0, ErrInvalidAmount
+	}
+	if len(frac) > 2 {
+		// Allow trailing zeros (e.g. "1.500") but never silently round.
+		if strings.TrimRight(frac[2:], "0") != "" {
+			return 0, fmt.Errorf("%w: more than two decimal places", ErrInvalidAmount)
+		}
+		frac = frac[:2]
+	}
+	if !isDigits(whole) || !isDigits(frac) {
+		return 0, ErrInvalidAmount
+	}
+	for len(frac) < 2 {
+		frac += "0"
+	}
+	if whole == "" {
+		whole = "0"
+	}
+
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, ErrOverflow
+	}
+	cents, _ := strconv.ParseInt(frac, 10, 64)
+	if units > (math.MaxInt64-cents)/scale {
+		return 0, ErrOverflow
+	}
+	minor := units*scale + cents
+	if negative {
+		minor = -minor
+	}
+	return Amount(minor), nil
+}
+
+// MustParse is like Parse but panics on error. Intended for constants and tests.
+func MustParse(s string) Amount {
+	a, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Minor returns the amount in minor units.
+func (a Amount) Minor() int64 {
+	return int64(a)
+}
+
+// Float64 returns the amount in major units as a float. It is approximate,
+// for reporting such as metrics, and must not be used for arithmetic.
+func (a Amount) Float64() float64 {
+	return float64(a) / scale
+}
+
+// Add returns a+b, or ErrOverflow if the result does not fit.
+func (a Amount) Add(b Amount) (Amount, error) {
+	sum := a + b
+	if (b > 0 && sum < a) || (b < 0 && sum > a) {
+		return 0, ErrOverflow
+	}
+	return sum, nil
+}
+
+// Sub returns a-b, or ErrOverflow if the result does not fit.
+func (a Amount) Sub(b Amount) (Amount, error) {
+	if b == math.MinInt64 {
+		return 0, ErrOverflow
+	}
+	return a.Add(-b)
+}
+
+// Neg returns -a.
+func (a Amount) Neg() Amount {
+	return -a
+}
+
+// Cmp returns -1, 0 or +1 depending on whether a is less than, equal to or
+// greater than b.
+func (a Amount) Cmp(b Amount) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (a Amount) IsZero() bool     { return a == 0 }
+func (a Amount) IsNegative() bool { return a < 0 }
+func (a Amount) IsPositive() bool { return a > 0 }
+
+// String formats the amount with exactly two decimal places, e.g. "-12.30".
+func (a Amount) String() string {
+	minor := int64(a)
+	sign := ""
+	var abs uint64
+	if minor < 0 {
+		sign = "-"
+		abs = uint64(-(minor + 1)) + 1
+	} else {
+		abs = uint64(minor)
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, abs/scale, abs%scale)
+}
+
+// MarshalJSON encodes the amount as a JSON number with two decimal places.
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a quoted decimal string.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	s = strings.Trim(s, `"`)
+	parsed, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// Value implements driver.Valuer, writing the amount as a DECIMAL literal.
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+// Scan implements sql.Scanner for DECIMAL/NUMERIC columns.
+func (a *Amount) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		return a.scanString(string(v))
+	case string:
+		return a.scanString(v)
+	case int64:
+		if v > math.MaxInt64/scale || v < math.MinInt64/scale {
+			return ErrOverflow
+		}
+		*a = Amount(v * scale)
+		return nil
+	case nil:
+		*a = 0
+		return nil
+	default:
+		return fmt.Errorf("money: cannot scan %T into Amount", src)
+	}
+}
+
+func (a *Amount) scanString(s string) error {
+	parsed, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// Money is an Amount in a specific currency.
+type Money struct {
+	Amount   Amount
+	Currency string
+}
+
+// New returns a Money value.
+func New(amount Amount, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Add returns m+o. Both values must share a currency.
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	sum, err := m.Amount.Add(o.Amount)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: sum, Currency: m.Currency}, nil
+}
+
+// Sub returns m-o. Both values must share a currency.
+func (m Money) Sub(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	diff, err := m.Amount.Sub(o.Amount)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: diff, Currency: m.Currency}, nil
+}
+
+// String formats the value as "GBP 12.30".
+func (m Money) String() string {
+	return m.Currency + " " + m.Amount.String()
+}
@@ -0,0 +1,115 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected Amount
+		wantErr  bool
+	}{
+		{name: "whole number", input: "1000", expected: 100000},
+		{name: "two decimal places", input: "1000.00", expected: 100000},
+		{name: "one decimal place", input: "0.5", expected: 50},
+		{name: "leading point", input: ".07", expected: 7},
+		{name: "negative", input: "-12.34", expected: -1234},
+		{name: "trailing zeros beyond two places", input: "1.500", expected: 150},
+		{name: "float drift value", input: "0.30", expected: 30},
+		{name: "too many decimal places", input: "1.005", wantErr: true},
+		{name: "exponent", input: "1e3", wantErr: true},
+		{name: "empty", input: "", wantErr: true},
+		{name: "sign only", input: "-", wantErr: true},
+		{name: "overflow", input: "92233720368547758.08", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("[%s] expected error, got %v", tt.name, got)
+				}
+				return
+			}
+			if err != nil || got != tt.expected {
+				t.Errorf("[%s] expected %d got %d (err %v)", tt.name, tt.expected, got, err)
+			}
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := map[Amount]string{
+		0:                "0.00",
+		5:                "0.05",
+		100000:           "1000.00",
+		-1234:            "-12.34",
+		math.MinInt64:    "-92233720368547758.08",
+		FromMinor(12345): "123.45",
+	}
+	for amount, expected := range tests {
+		if got := amount.String(); got != expected {
+			t.Errorf("expected %s got %s", expected, got)
+		}
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	var body struct {
+		Amount Amount `json:"amount"`
+	}
+	if err := json.Unmarshal([]byte(`{"amount": 1000.00}`), &body); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if body.Amount != 100000 {
+		t.Fatalf("expected 100000 minor units, got %d", body.Amount)
+	}
+	out, _ := json.Marshal(body)
+	if string(out) != `{"amount":1000.00}` {
+		t.Errorf("unexpected wire format: %s", out)
+	}
+	if err := json.Unmarshal([]byte(`{"amount": 0.001}`), &body); err == nil {
+		t.Errorf("expected sub-penny amount to be rejected")
+	}
+}
+
+func TestScan(t *testing.T) {
+	var a Amount
+	if err := a.Scan([]byte("10.10")); err != nil || a != 1010 {
+		t.Errorf("scan []byte: got %d err %v", a, err)
+	}
+	if err := a.Scan(int64(3)); err != nil || a != 300 {
+		t.Errorf("scan int64: got %d err %v", a, err)
+	}
+	if v, _ := Amount(1010).Value(); v != "10.10" {
+		t.Errorf("value: got %v", v)
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	// 0.1 + 0.2 is exact in minor units.
+	sum, err := MustParse("0.10").Add(MustParse("0.20"))
+	if err != nil || sum != MustParse("0.30") {
+		t.Errorf("expected 0.30 got %s (err %v)", sum, err)
+	}
+	if _, err := Amount(math.MaxInt64).Add(1); !errors.Is(err, ErrOverflow) {
+		t.Errorf("expected overflow on add, got %v", err)
+	}
+	if _, err := Amount(math.MinInt64).Sub(1); !errors.Is(err, ErrOverflow) {
+		t.Errorf("expected overflow on sub, got %v", err)
+	}
+
+	balance := New(MustParse("100.00"), "GBP")
+	after, err := balance.Sub(New(MustParse("100.01"), "GBP"))
+	if err != nil || !after.Amount.IsNegative() {
+		t.Errorf("expected negative balance, got %s (err %v)", after, err)
+	}
+	if _, err := balance.Add(New(1, "EUR")); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("expected currency mismatch, got %v", err)
+	}
+}
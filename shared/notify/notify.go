@@ -0,0 +1,131 @@
+// Package notify delivers messages such as password reset links to users.
+// Services depend on the Notifier interface; which implementation is used is
+// a deployment decision made by FromEnv.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Message is a plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier delivers a message to its recipient.
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// FromEnv returns the notifier selected by NOTIFIER:
+//
+//	file (default)  writes each message to NOTIFY_DIR (default ./mail)
+//	smtp            sends through SMTP_ADDR from MAIL_FROM, e.g. to MailHog
+//	log             logs messages, including their body
+func FromEnv() Notifier {
+	switch kind := os.Getenv("NOTIFIER"); kind {
+	case "", "file":
+		return NewFileNotifier(getEnv("NOTIFY_DIR", "mail"))
+	case "smtp":
+		return NewSMTPNotifier(getEnv("SMTP_ADDR", "localhost:1025"), getEnv("MAIL_FROM", "no-reply@eaglebank.local"))
+	case "log":
+		return LogNotifier{}
+	default:
+		log.Fatalf("Unknown NOTIFIER %q", kind)
+		return nil
+	}
+}
+
+// FileNotifier writes each message to its own .eml file in a directory, as
+// a stand-in for email during development.
+type FileNotifier struct {
+	dir string
+}
+
+func NewFileNotifier(dir string) *FileNotifier {
+	return &FileNotifier{dir: dir}
+}
+
+func (n *FileNotifier) Send(_ context.Context, msg Message) error {
+	if err := os.MkdirAll(n.dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create mail directory: %w", err)
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), sanitize(msg.To))
+	if err := os.WriteFile(filepath.Join(n.dir, name), format("", msg), 0o600); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
+}
+
+// SMTPNotifier sends messages through an unauthenticated SMTP relay.
+type SMTPNotifier struct {
+	addr string
+	from string
+}
+
+func NewSMTPNotifier(addr, from string) *SMTPNotifier {
+	return &SMTPNotifier{addr: addr, from: from}
+}
+
+func (n *SMTPNotifier) Send(_ context.Context, msg Message) error {
+	if err := smtp.SendMail(n.addr, nil, n.from, []string{msg.To}, format(n.from, msg)); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	return nil
+}
+
+// LogNotifier logs messages instead of sending them, for development only.
+// Logs are redacted, so the tokens in links such as password resets are
+// masked; use FileNotifier to follow them.
+type LogNotifier struct{}
+
+func (LogNotifier) Send(ctx context.Context, msg Message) error {
+	slog.InfoContext(ctx, "Notification", "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
+
+// format renders msg as an RFC 5322 message.
+func format(from string, msg Message) []byte {
+	var b strings.Builder
+	if from != "" {
+		fmt.Fprintf(&b, "From: %s\r\n", from)
+	}
+	fmt.Fprintf(&b, "To: %s\r\n", stripNewlines(msg.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", stripNewlines(msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().UTC().Format(time.RFC1123Z))
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// stripNewlines keeps header values on one line so they cannot inject
+// further headers.
+func stripNewlines(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '@' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,42 @@
+package notify
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileNotifier(t *testing.T) {
+	dir := t.TempDir()
+	n := NewFileNotifier(filepath.Join(dir, "mail"))
+	msg := Message{
+		To:      "alice@example.com",
+		Subject: "Reset\r\nBcc: mallory@example.com",
+		Body:    "line one\nline two",
+	}
+	if err := n.Send(context.Background(), msg); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "mail", "*alice@example.com.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected one message file, got %v (%v)", files, err)
+	}
+	raw, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	got := string(raw)
+	headers, body, _ := strings.Cut(got, "\r\n\r\n")
+	if !strings.Contains(headers, "To: alice@example.com\r\n") {
+		t.Errorf("missing To header in %q", headers)
+	}
+	if strings.Contains(headers, "\r\nBcc:") {
+		t.Errorf("subject injected a header: %q", headers)
+	}
+	if body != "line one\r\nline two" {
+		t.Errorf("unexpected body %q", body)
+	}
+}
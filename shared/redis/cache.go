@@ -3,23 +3,40 @@ package redis
 import (
 	"context"
 	"encoding/json"
-	"log"
+	"log/slog"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	goredis "github.com/redis/go-redis/v9"
 )
 
+var viewCacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "eagle",
+	Name:      "view_cache_lookups_total",
+	Help:      "Read model cache lookups, by view and result (hit or miss).",
+}, []string{"view", "result"})
+
 // ViewCache is a generic JSON-backed Redis cache for read model projections.
 // Bind it to a specific view type T; each instance holds a Redis client and an
-// optional TTL (pass 0 for keys that should not expire).
+// optional TTL (pass 0 for keys that should not expire). Lookups are counted
+// as hits or misses under the instance's view name.
 type ViewCache[T any] struct {
 	client *goredis.Client
 	ttl    time.Duration
+	hits   prometheus.Counter
+	misses prometheus.Counter
 }
 
-// NewViewCache creates a ViewCache backed by the provided Redis client.
-func NewViewCache[T any](client *goredis.Client, ttl time.Duration) *ViewCache[T] {
-	return &ViewCache[T]{client: client, ttl: ttl}
+// NewViewCache creates a ViewCache backed by the provided Redis client. view
+// names the cached view type in metrics, e.g. "account".
+func NewViewCache[T any](client *goredis.Client, view string, ttl time.Duration) *ViewCache[T] {
+	return &ViewCache[T]{
+		client: client,
+		ttl:    ttl,
+		hits:   viewCacheLookups.WithLabelValues(view, "hit"),
+		misses: viewCacheLookups.WithLabelValues(view, "miss"),
+	}
 }
 
 // Get retrieves and unmarshals a value from Redis.
@@ -27,12 +44,15 @@ func NewViewCache[T any](client *goredis.Client, ttl time.Duration) *ViewCache[T
 func (c *ViewCache[T]) Get(ctx context.Context, key string) (*T, bool) {
 	data, err := c.client.Get(ctx, key).Result()
 	if err != nil {
+		c.misses.Inc()
 		return nil, false
 	}
 	var v T
 	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		c.misses.Inc()
 		return nil, false
 	}
+	c.hits.Inc()
 	return &v, true
 }
 
@@ -41,17 +61,17 @@ func (c *ViewCache[T]) Get(ctx context.Context, key string) (*T, bool) {
 func (c *ViewCache[T]) Set(ctx context.Context, key string, value *T) {
 	data, err := json.Marshal(value)
 	if err != nil {
-		log.Printf("ViewCache: marshal error for key %s: %v", key, err)
+		slog.ErrorContext(ctx, "ViewCache: marshal error", "key", key, "err", err)
 		return
 	}
 	if err := c.client.Set(ctx, key, data, c.ttl).Err(); err != nil {
-		log.Printf("ViewCache: write error for key %s: %v", key, err)
+		slog.ErrorContext(ctx, "ViewCache: write error", "key", key, "err", err)
 	}
 }
 
 // Delete removes a key from Redis.
 func (c *ViewCache[T]) Delete(ctx context.Context, key string) {
 	if err := c.client.Del(ctx, key).Err(); err != nil {
-		log.Printf("ViewCache: delete error for key %s: %v", key, err)
+		slog.ErrorContext(ctx, "ViewCache: delete error", "key", key, "err", err)
 	}
 }
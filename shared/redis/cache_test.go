@@ -0,0 +1,43 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+type testView struct {
+	ID string `json:"id"`
+}
+
+func TestViewCacheCountsHitsAndMisses(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	cache := NewViewCache[testView](client, "test", time.Minute)
+
+	if _, ok := cache.Get(ctx, "view:1"); ok {
+		t.Fatal("expected a miss before the view is cached")
+	}
+	cache.Set(ctx, "view:1", &testView{ID: "1"})
+	v, ok := cache.Get(ctx, "view:1")
+	if !ok || v.ID != "1" {
+		t.Fatalf("expected the cached view, got %v %v", v, ok)
+	}
+	mr.Set("view:2", "not json")
+	if _, ok := cache.Get(ctx, "view:2"); ok {
+		t.Error("expected an undecodable value to be a miss")
+	}
+
+	if got := testutil.ToFloat64(viewCacheLookups.WithLabelValues("test", "hit")); got != 1 {
+		t.Errorf("expected 1 hit, got %v", got)
+	}
+	if got := testutil.ToFloat64(viewCacheLookups.WithLabelValues("test", "miss")); got != 2 {
+		t.Errorf("expected 2 misses, got %v", got)
+	}
+}
@@ -0,0 +1,140 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eaglebank/shared/money"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const fundsKeyPrefix = "account:funds:"
+
+// reserveScript atomically computes the available balance (ledger balance
+// minus unexpired holds) and places a hold if it covers the amount. A hold
+// expiring at 0 has been persisted and never lapses.
+//
+// KEYS[1] funds hash; ARGV: holdID, amount, nowMs, expiresAtMs, seedBalance.
+// Returns {1, available after hold} or {0, available}.
+var reserveScript = goredis.NewScript(`
+local key = KEYS[1]
+local hold = 'hold:' .. ARGV[1]
+local amount = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+redis.call('HSETNX', key, 'balance', ARGV[5])
+local balance = tonumber(redis.call('HGET', key, 'balance'))
+local held = 0
+local fields = redis.call('HGETALL', key)
+for i = 1, #fields, 2 do
+  local field = fields[i]
+  if string.sub(field, 1, 5) == 'hold:' then
+    local held_amount, expires = string.match(fields[i + 1], '^(%d+):(%d+)$')
+    if expires ~= '0' and tonumber(expires) <= now then
+      redis.call('HDEL', key, field)
+    elseif field ~= hold then
+      held = held + tonumber(held_amount)
+    end
+  end
+end
+local available = balance - held
+if available < amount then
+  return {0, available}
+end
+redis.call('HSET', key, hold, ARGV[2] .. ':' .. ARGV[4])
+return {1, available - amount}
+`)
+
+// settleScript records a new ledger balance and releases the hold of the
+// transaction that produced it in one step, so the held amount is never
+// counted twice or not at all.
+//
+// KEYS[1] funds hash; ARGV: balance, holdID ("" for none).
+var settleScript = goredis.NewScript(`
+redis.call('HSET', KEYS[1], 'balance', ARGV[1])
+if ARGV[2] ~= '' then
+  redis.call('HDEL', KEYS[1], 'hold:' .. ARGV[2])
+end
+return 1
+`)
+
+// persistScript makes an existing hold permanent, leaving a hold that was
+// already settled or released absent.
+//
+// KEYS[1] funds hash; ARGV: holdID.
+var persistScript = goredis.NewScript(`
+local hold = 'hold:' .. ARGV[1]
+local value = redis.call('HGET', KEYS[1], hold)
+if not value then
+  return 0
+end
+local amount = string.match(value, '^(%d+):')
+redis.call('HSET', KEYS[1], hold, amount .. ':0')
+return 1
+`)
+
+// Funds tracks each account's ledger balance and the holds placed against it
+// by in-flight debits. transaction-service reserves funds when it accepts a
+// debit and persists the hold once the debit is recorded; account-service
+// settles the hold when it posts the debit to the ledger, or releases it when
+// it rejects the debit. A hold that is never persisted lapses after its TTL,
+// so a debit that failed to be recorded does not hold funds for long.
+type Funds struct {
+	client *goredis.Client
+}
+
+func NewFunds(client *goredis.Client) *Funds {
+	return &Funds{client: client}
+}
+
+// Reserve places a hold of amount against accountNumber under holdID if the
+// available balance covers it, returning "insufficient funds" otherwise.
+// seedBalance is used as the ledger balance only if none is recorded yet.
+// Reserving the same holdID again replaces the earlier hold.
+func (f *Funds) Reserve(ctx context.Context, accountNumber, holdID string, amount, seedBalance money.Amount, ttl time.Duration) error {
+	now := time.Now()
+	result, err := reserveScript.Run(ctx, f.client, []string{fundsKeyPrefix + accountNumber},
+		holdID, amount.Minor(), now.UnixMilli(), now.Add(ttl).UnixMilli(), seedBalance.Minor(),
+	).Int64Slice()
+	if err != nil {
+		return fmt.Errorf("failed to reserve funds: %w", err)
+	}
+	if result[0] == 0 {
+		return fmt.Errorf("insufficient funds")
+	}
+	return nil
+}
+
+// Settle records the account's new ledger balance and releases holdID.
+func (f *Funds) Settle(ctx context.Context, accountNumber string, balance money.Amount, holdID string) error {
+	err := settleScript.Run(ctx, f.client, []string{fundsKeyPrefix + accountNumber},
+		balance.Minor(), holdID,
+	).Err()
+	if err != nil {
+		return fmt.Errorf("failed to settle funds: %w", err)
+	}
+	return nil
+}
+
+// Persist keeps holdID until it is settled or released, however long the
+// debit waits to be posted. A hold that no longer exists is left absent.
+func (f *Funds) Persist(ctx context.Context, accountNumber, holdID string) error {
+	if err := persistScript.Run(ctx, f.client, []string{fundsKeyPrefix + accountNumber}, holdID).Err(); err != nil {
+		return fmt.Errorf("failed to persist hold: %w", err)
+	}
+	return nil
+}
+
+// Release drops a hold without changing the balance, e.g. when the debit was
+// rejected or could not be recorded.
+func (f *Funds) Release(ctx context.Context, accountNumber, holdID string) error {
+	if err := f.client.HDel(ctx, fundsKeyPrefix+accountNumber, "hold:"+holdID).Err(); err != nil {
+		return fmt.Errorf("failed to release hold: %w", err)
+	}
+	return nil
+}
+
+// Forget removes all funds state for a closed account.
+func (f *Funds) Forget(ctx context.Context, accountNumber string) error {
+	return f.client.Del(ctx, fundsKeyPrefix+accountNumber).Err()
+}
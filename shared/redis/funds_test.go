@@ -0,0 +1,131 @@
+package redis
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/eaglebank/shared/money"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func newTestFunds(t *testing.T) (*Funds, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewFunds(client), mr
+}
+
+func TestFundsReserve(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		setup   func(f *Funds)
+		amount  money.Amount
+		wantErr bool
+	}{
+		{
+			name:   "within the seeded balance",
+			amount: money.MustParse("60.00"),
+		},
+		{
+			name:    "more than the balance",
+			amount:  money.MustParse("100.01"),
+			wantErr: true,
+		},
+		{
+			name: "existing hold reduces the available balance",
+			setup: func(f *Funds) {
+				f.Reserve(ctx, "12345678", "tan-1", money.MustParse("60.00"), money.MustParse("100.00"), time.Minute)
+			},
+			amount:  money.MustParse("60.00"),
+			wantErr: true,
+		},
+		{
+			name: "settled hold is released",
+			setup: func(f *Funds) {
+				f.Reserve(ctx, "12345678", "tan-1", money.MustParse("60.00"), money.MustParse("100.00"), time.Minute)
+				f.Settle(ctx, "12345678", money.MustParse("40.00"), "tan-1")
+			},
+			amount: money.MustParse("40.00"),
+		},
+		{
+			name: "expired hold no longer counts",
+			setup: func(f *Funds) {
+				f.Reserve(ctx, "12345678", "tan-1", money.MustParse("60.00"), money.MustParse("100.00"), time.Millisecond)
+				time.Sleep(5 * time.Millisecond)
+			},
+			amount: money.MustParse("60.00"),
+		},
+		{
+			name: "persisted hold does not expire",
+			setup: func(f *Funds) {
+				f.Reserve(ctx, "12345678", "tan-1", money.MustParse("60.00"), money.MustParse("100.00"), time.Millisecond)
+				f.Persist(ctx, "12345678", "tan-1")
+				time.Sleep(5 * time.Millisecond)
+			},
+			amount:  money.MustParse("60.00"),
+			wantErr: true,
+		},
+		{
+			name: "persisting a settled hold does not restore it",
+			setup: func(f *Funds) {
+				f.Reserve(ctx, "12345678", "tan-1", money.MustParse("60.00"), money.MustParse("100.00"), time.Minute)
+				f.Settle(ctx, "12345678", money.MustParse("100.00"), "tan-1")
+				f.Persist(ctx, "12345678", "tan-1")
+			},
+			amount: money.MustParse("100.00"),
+		},
+		{
+			name: "recorded balance wins over the seed",
+			setup: func(f *Funds) {
+				f.Settle(ctx, "12345678", money.MustParse("10.00"), "")
+			},
+			amount:  money.MustParse("60.00"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			funds, _ := newTestFunds(t)
+			if tt.setup != nil {
+				tt.setup(funds)
+			}
+			err := funds.Reserve(ctx, "12345678", "tan-2", tt.amount, money.MustParse("100.00"), time.Minute)
+			if tt.wantErr && (err == nil || err.Error() != "insufficient funds") {
+				t.Errorf("[%s] expected insufficient funds, got %v", tt.name, err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("[%s] unexpected error: %v", tt.name, err)
+			}
+		})
+	}
+}
+
+func TestFundsReserveConcurrent(t *testing.T) {
+	ctx := context.Background()
+	funds, _ := newTestFunds(t)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	accepted := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			holdID := "tan-" + string(rune('a'+i))
+			if err := funds.Reserve(ctx, "12345678", holdID, money.MustParse("30.00"), money.MustParse("100.00"), time.Minute); err == nil {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+	if accepted != 3 {
+		t.Errorf("expected exactly 3 of 10 concurrent £30 holds against £100 to succeed, got %d", accepted)
+	}
+}
@@ -0,0 +1,81 @@
+// Package secretbox encrypts small secrets for storage with AES-256-GCM.
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+)
+
+var ErrDecrypt = errors.New("secretbox: message authentication failed")
+
+// Box seals and opens secrets under one 256-bit key.
+type Box struct {
+	aead cipher.AEAD
+}
+
+// New returns a Box for a 32-byte key.
+func New(key []byte) (*Box, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("secretbox: key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{aead: aead}, nil
+}
+
+// MustFromEnv builds a Box from a base64-encoded 32-byte key in the named
+// environment variable. The process exits if it is missing or malformed so
+// the misconfiguration is caught at boot time.
+func MustFromEnv(name string) *Box {
+	encoded := os.Getenv(name)
+	if encoded == "" {
+		log.Fatalf("%s environment variable is not set", name)
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		log.Fatalf("%s is not valid base64: %v", name, err)
+	}
+	box, err := New(key)
+	if err != nil {
+		log.Fatalf("%s: %v", name, err)
+	}
+	return box
+}
+
+// Seal encrypts plaintext, binding it to context (e.g. the owning user's ID)
+// so a ciphertext copied to another row does not decrypt. The result is
+// base64 and carries its own random nonce.
+func (b *Box) Seal(plaintext, context string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("secretbox: failed to generate nonce: %w", err)
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(plaintext), []byte(context))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value produced by Seal with the same context.
+func (b *Box) Open(ciphertext, context string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil || len(data) < b.aead.NonceSize() {
+		return "", ErrDecrypt
+	}
+	nonce, sealed := data[:b.aead.NonceSize()], data[b.aead.NonceSize():]
+	plaintext, err := b.aead.Open(nil, nonce, sealed, []byte(context))
+	if err != nil {
+		return "", ErrDecrypt
+	}
+	return string(plaintext), nil
+}
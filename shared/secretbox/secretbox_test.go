@@ -0,0 +1,30 @@
+package secretbox
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSealOpen(t *testing.T) {
+	box, err := New(bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := box.Seal("JBSWY3DPEHPK3PXP", "usr-001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := box.Open(sealed, "usr-001"); err != nil || got != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("expected the secret back, got %q (err %v)", got, err)
+	}
+	if _, err := box.Open(sealed, "usr-002"); err != ErrDecrypt {
+		t.Errorf("expected a ciphertext bound to another user to be refused, got %v", err)
+	}
+	other, _ := New(bytes.Repeat([]byte{8}, 32))
+	if _, err := other.Open(sealed, "usr-001"); err != ErrDecrypt {
+		t.Errorf("expected a different key to be refused, got %v", err)
+	}
+	if _, err := New([]byte("short")); err == nil {
+		t.Error("expected a short key to be rejected")
+	}
+}
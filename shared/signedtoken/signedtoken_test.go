@@ -0,0 +1,101 @@
+package signedtoken
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestSigner(t *testing.T, fill byte) *Signer {
+	t.Helper()
+	s, err := New(bytes.Repeat([]byte{fill}, 32))
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	return s
+}
+
+func TestSignVerify(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	signer := newTestSigner(t, 1)
+	token := signer.Sign("verify-email", []string{"usr-001", "alice@example.com"}, now.Add(time.Hour))
+
+	tests := []struct {
+		name       string
+		signer     *Signer
+		purpose    string
+		token      string
+		now        time.Time
+		wantFields []string
+		wantErr    error
+	}{
+		{
+			name:       "valid",
+			signer:     signer,
+			purpose:    "verify-email",
+			token:      token,
+			now:        now,
+			wantFields: []string{"usr-001", "alice@example.com"},
+		},
+		{
+			name:    "expired",
+			signer:  signer,
+			purpose: "verify-email",
+			token:   token,
+			now:     now.Add(time.Hour),
+			wantErr: ErrExpired,
+		},
+		{
+			name:    "other purpose",
+			signer:  signer,
+			purpose: "reset-password",
+			token:   token,
+			now:     now,
+			wantErr: ErrInvalid,
+		},
+		{
+			name:    "other key",
+			signer:  newTestSigner(t, 2),
+			purpose: "verify-email",
+			token:   token,
+			now:     now,
+			wantErr: ErrInvalid,
+		},
+		{
+			name:    "tampered payload",
+			signer:  signer,
+			purpose: "verify-email",
+			token:   "x" + token,
+			now:     now,
+			wantErr: ErrInvalid,
+		},
+		{
+			name:    "no signature",
+			signer:  signer,
+			purpose: "verify-email",
+			token:   strings.Split(token, ".")[0],
+			now:     now,
+			wantErr: ErrInvalid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, err := tt.signer.Verify(tt.purpose, tt.token, tt.now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Errorf("expected fields %v, got %v", tt.wantFields, fields)
+			}
+		})
+	}
+}
+
+func TestNewRejectsShortKey(t *testing.T) {
+	if _, err := New(make([]byte, 16)); err == nil {
+		t.Error("expected a 16-byte key to be rejected")
+	}
+}
@@ -0,0 +1,76 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// RFC 6238 appendix B test vectors for SHA-1, truncated to six digits.
+func TestCodeRFC6238(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	vectors := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, v := range vectors {
+		got, err := Code(secret, Step(time.Unix(v.unix, 0)))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != v.code {
+			t.Errorf("at %d expected %s got %s", v.unix, v.code, got)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	current, _ := Code(secret, Step(now))
+	previous, _ := Code(secret, Step(now)-1)
+	stale, _ := Code(secret, Step(now)-3)
+
+	tests := []struct {
+		name     string
+		code     string
+		lastStep int64
+		valid    bool
+	}{
+		{name: "current step", code: current, lastStep: -1, valid: true},
+		{name: "previous step within skew", code: previous, lastStep: -1, valid: true},
+		{name: "outside the skew window", code: stale, lastStep: -1, valid: false},
+		{name: "replay of an accepted step", code: current, lastStep: Step(now), valid: false},
+		{name: "wrong length", code: "12345", lastStep: -1, valid: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := Validate(secret, tt.code, now, tt.lastStep); ok != tt.valid {
+				t.Errorf("[%s] expected valid=%v", tt.name, tt.valid)
+			}
+		})
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri, err := url.Parse(ProvisioningURI("Eagle Bank", "alice@example.com", "JBSWY3DPEHPK3PXP"))
+	if err != nil {
+		t.Fatalf("invalid URI: %v", err)
+	}
+	if uri.Scheme != "otpauth" || uri.Host != "totp" {
+		t.Errorf("unexpected URI %s", uri)
+	}
+	if got := uri.Query().Get("secret"); got != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("expected secret in URI, got %q", got)
+	}
+}
@@ -2,6 +2,9 @@ package utils
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"math/big"
 	"strings"
@@ -23,6 +26,23 @@ func GenerateID(prefix string) string {
 	return fmt.Sprintf("%s-%s", prefix, string(result))
 }
 
+// GenerateToken returns an opaque, URL-safe secret carrying 256 bits of
+// randomness, for bearer credentials such as refresh tokens.
+func GenerateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the SHA-256 hex digest under which an opaque token is
+// stored, so a database leak does not expose usable credentials.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 // GenerateAccountNumber generates an 8-digit account number starting with 01
 func GenerateAccountNumber() string {
 	num, _ := rand.Int(rand.Reader, big.NewInt(1000000))
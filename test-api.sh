@@ -55,6 +55,21 @@ USER_ID=$(echo $USER_RESPONSE | jq -r '.id')
 echo "User created with ID: $USER_ID"
 print_result "User registration"
 
+# Test 2a: Email Verification
+# The verification email is written to ./mail by the default file notifier.
+echo ""
+echo "2️⃣a  Verifying email address..."
+sleep 1
+VERIFY_MAIL=$(ls -t ${MAIL_DIR:-./mail}/*"$EMAIL".eml | head -n1)
+VERIFY_LINK=$(grep -o 'http[^[:space:]]*verify-email?token=[^[:space:]]*' "$VERIFY_MAIL" | head -n1)
+HTTP_CODE=$(curl -s -o /dev/null -w "%{http_code}" "$VERIFY_LINK")
+if [ "$HTTP_CODE" = "200" ]; then
+    echo "✅ Email address verified"
+else
+    echo "❌ Expected 200, got $HTTP_CODE"
+    exit 1
+fi
+
 # Test 2b: Duplicate User Registration
 echo ""
 echo "2️⃣b  Duplicate user registration (should fail)..."
@@ -109,12 +124,14 @@ else
 fi
 echo ""
 echo "3️⃣  Logging in..."
-TOKEN=$(curl -s -X POST "$BASE_URL/v1/auth/login" \
+LOGIN_RESPONSE=$(curl -s -X POST "$BASE_URL/v1/auth/login" \
   -H "Content-Type: application/json" \
   -d "{
     \"email\": \"$EMAIL\",
     \"password\": \"$PASSWORD\"
-  }" | jq -r '.token')
+  }")
+TOKEN=$(echo "$LOGIN_RESPONSE" | jq -r '.token')
+REFRESH_TOKEN=$(echo "$LOGIN_RESPONSE" | jq -r '.refreshToken')
 
 if [ -z "$TOKEN" ] || [ "$TOKEN" = "null" ]; then
     echo "❌ Failed to get token"
@@ -126,17 +143,39 @@ print_result "Login"
 # Test 3b: Token Refresh
 echo ""
 echo "3️⃣b  Refreshing token..."
-NEW_TOKEN=$(curl -s -X POST "$BASE_URL/v1/auth/refresh" \
+REFRESH_RESPONSE=$(curl -s -X POST "$BASE_URL/v1/auth/refresh" \
   -H "Content-Type: application/json" \
-  -d "{\"token\": \"$TOKEN\"}" | jq -r '.token')
+  -d "{\"refreshToken\": \"$REFRESH_TOKEN\"}")
+NEW_TOKEN=$(echo "$REFRESH_RESPONSE" | jq -r '.token')
 if [ -z "$NEW_TOKEN" ] || [ "$NEW_TOKEN" = "null" ]; then
     echo "❌ Failed to refresh token"
     exit 1
 fi
+OLD_REFRESH_TOKEN=$REFRESH_TOKEN
+REFRESH_TOKEN=$(echo "$REFRESH_RESPONSE" | jq -r '.refreshToken')
 TOKEN=$NEW_TOKEN
 echo "Refreshed token: ${TOKEN:0:20}..."
 print_result "Token refresh"
 
+# Test 3b2: Refresh tokens are single-use
+echo ""
+echo "3️⃣b2 Reusing a rotated refresh token (should fail)..."
+HTTP_CODE=$(curl -s -o /dev/null -w "%{http_code}" -X POST "$BASE_URL/v1/auth/refresh" \
+  -H "Content-Type: application/json" \
+  -d "{\"refreshToken\": \"$OLD_REFRESH_TOKEN\"}")
+if [ "$HTTP_CODE" = "401" ]; then
+    echo "✅ Reused refresh token correctly rejected"
+else
+    echo "❌ Expected 401, got $HTTP_CODE"
+    exit 1
+fi
+# Reuse revokes the whole family, including the tokens just issued
+LOGIN_RESPONSE=$(curl -s -X POST "$BASE_URL/v1/auth/login" \
+  -H "Content-Type: application/json" \
+  -d "{\"email\": \"$EMAIL\", \"password\": \"$PASSWORD\"}")
+TOKEN=$(echo "$LOGIN_RESPONSE" | jq -r '.token')
+REFRESH_TOKEN=$(echo "$LOGIN_RESPONSE" | jq -r '.refreshToken')
+
 # Test 3c: Unauthenticated Request
 echo ""
 echo "3️⃣c  Unauthenticated request (should fail)..."
@@ -0,0 +1,45 @@
+package command
+
+import (
+	"github.com/eaglebank/shared/ledger"
+	"github.com/eaglebank/shared/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	transactionsCreated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eagle",
+		Name:      "transactions_created_total",
+		Help:      "Transactions recorded, by type.",
+	}, []string{"type"})
+
+	transactionAmounts = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "eagle",
+		Name:      "transaction_amount",
+		Help:      "Amounts of recorded deposits and withdrawals, in major currency units.",
+		Buckets:   []float64{1, 5, 10, 50, 100, 500, 1000, 5000, 10000, 50000},
+	}, []string{"type", "currency"})
+
+	insufficientFunds = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "eagle",
+		Name:      "insufficient_funds_rejections_total",
+		Help:      "Debits refused because the available balance did not cover them, by type.",
+	}, []string{"type"})
+)
+
+// observeCreated counts a recorded transaction and, for deposits and
+// withdrawals, its amount.
+func observeCreated(t *models.Transaction) {
+	transactionsCreated.WithLabelValues(t.Type).Inc()
+	if t.Type == ledger.Deposit || t.Type == ledger.Withdrawal {
+		transactionAmounts.WithLabelValues(t.Type, t.Currency).Observe(t.Amount.Float64())
+	}
+}
+
+// observeReserveError counts err if it refused a debit for lack of funds.
+func observeReserveError(t *models.Transaction, err error) {
+	if err.Error() == "insufficient funds" {
+		insufficientFunds.WithLabelValues(t.Type).Inc()
+	}
+}
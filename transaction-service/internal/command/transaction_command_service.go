@@ -2,82 +2,199 @@ package command
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 
 	"github.com/eaglebank/shared/cqrs"
 	"github.com/eaglebank/shared/events"
+	"github.com/eaglebank/shared/ledger"
+	"github.com/eaglebank/shared/middleware"
 	"github.com/eaglebank/shared/models"
+	sharedredis "github.com/eaglebank/shared/redis"
 	"github.com/eaglebank/shared/utils"
 	"github.com/eaglebank/transaction-service/internal/repository"
 )
 
-// TransactionCommandService creates transactions. It checks account ownership and
-// balance against the Redis cache before writing to Postgres.
+// holdTTL bounds how long a debit's hold keeps funds reserved if the debit is
+// never recorded. Once it is, the hold is persisted and lasts until
+// account-service settles or releases it, however long the event waits.
+const holdTTL = time.Minute
+
+// TransactionCommandService creates transactions. It checks account ownership
+// against the Redis cache and, for debits, atomically places a hold on the
+// available balance before writing to Postgres, so concurrent withdrawals
+// cannot overdraw the account. Events are queued in the outbox alongside the
+// row and published by the outbox relay.
 type TransactionCommandService struct {
 	writeRepo   *repository.TransactionWriteRepository
 	readRepo    *repository.TransactionReadRepository
 	accountRepo *repository.AccountRepository
-	publisher   *events.Publisher
+	funds       *sharedredis.Funds
 }
 
 func NewTransactionCommandService(
 	writeRepo *repository.TransactionWriteRepository,
 	readRepo *repository.TransactionReadRepository,
 	accountRepo *repository.AccountRepository,
-	publisher *events.Publisher,
+	funds *sharedredis.Funds,
 ) *TransactionCommandService {
 	return &TransactionCommandService{
 		writeRepo:   writeRepo,
 		readRepo:    readRepo,
 		accountRepo: accountRepo,
-		publisher:   publisher,
+		funds:       funds,
 	}
 }
 
-func (s *TransactionCommandService) CreateTransaction(cmd cqrs.CreateTransactionCommand) (*models.Transaction, error) {
-	if cmd.Amount <= 0 {
+func (s *TransactionCommandService) CreateTransaction(ctx context.Context, cmd cqrs.CreateTransactionCommand) (*models.Transaction, error) {
+	if !cmd.Amount.IsPositive() {
 		return nil, fmt.Errorf("amount must be greater than zero")
 	}
-	ctx := context.Background()
 	account, err := s.accountRepo.GetAccount(ctx, cmd.AccountNumber)
 	if err != nil {
 		return nil, fmt.Errorf("account not found")
 	}
-	if account.UserID != cmd.UserID {
+	if !cmd.Principal.CanAccess(account.UserID, middleware.ScopeTransactionsAdjust) {
 		return nil, fmt.Errorf("forbidden")
 	}
-	if cmd.Type == "withdrawal" && account.Balance < cmd.Amount {
-		return nil, fmt.Errorf("insufficient funds")
+	if account.Frozen() {
+		return nil, fmt.Errorf("account frozen")
+	}
+	if account.Currency != cmd.Currency {
+		return nil, fmt.Errorf("currency mismatch")
 	}
-	transaction := &models.Transaction{
+	return s.record(ctx, account, &models.Transaction{
 		ID:            utils.GenerateID("tan"),
 		AccountNumber: cmd.AccountNumber,
-		UserID:        cmd.UserID,
+		UserID:        account.UserID,
 		Amount:        cmd.Amount,
 		Currency:      cmd.Currency,
 		Type:          cmd.Type,
 		Reference:     cmd.Reference,
 		CreatedAt:     time.Now().UTC(),
+	})
+}
+
+// PostAdjustment records a manual correction by staff against any account,
+// frozen or not. It belongs to the account owner like any other
+// transaction, with the reason as its reference. A debit adjustment still
+// needs the funds to cover it.
+func (s *TransactionCommandService) PostAdjustment(ctx context.Context, cmd cqrs.PostAdjustmentCommand) (*models.Transaction, error) {
+	if cmd.Type != ledger.AdjustmentCredit && cmd.Type != ledger.AdjustmentDebit {
+		return nil, fmt.Errorf("invalid adjustment type")
 	}
-	if err := s.writeRepo.Create(transaction); err != nil {
-		return nil, err
+	if !cmd.Amount.IsPositive() {
+		return nil, fmt.Errorf("amount must be greater than zero")
 	}
-	s.readRepo.CacheTransactionView(ctx, txToView(transaction))
-	if err := s.publisher.Publish(ctx, events.TransactionEventsStream, events.TransactionCreated, events.TransactionCreatedEvent{
-		TransactionID: transaction.ID,
+	account, err := s.accountRepo.GetAccount(ctx, cmd.AccountNumber)
+	if err != nil {
+		return nil, fmt.Errorf("account not found")
+	}
+	if account.Currency != cmd.Currency {
+		return nil, fmt.Errorf("currency mismatch")
+	}
+	transaction, err := s.record(ctx, account, &models.Transaction{
+		ID:            utils.GenerateID("tan"),
 		AccountNumber: cmd.AccountNumber,
-		UserID:        cmd.UserID,
+		UserID:        account.UserID,
 		Amount:        cmd.Amount,
-		Type:          cmd.Type,
 		Currency:      cmd.Currency,
-	}); err != nil {
-		log.Printf("Failed to publish transaction.created event: %v", err)
+		Type:          cmd.Type,
+		Reference:     cmd.Reason,
+		CreatedAt:     time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
 	}
+	slog.InfoContext(ctx, "Adjustment posted", "transaction_id", transaction.ID, "type", transaction.Type, "account_number", cmd.AccountNumber, "actor_id", cmd.ActorID)
 	return transaction, nil
 }
 
+// record holds the funds for a debit, then writes the transaction with its
+// transaction.created event. The transaction stays pending until
+// account-service reports its outcome.
+func (s *TransactionCommandService) record(ctx context.Context, account *repository.Account, transaction *models.Transaction) (*models.Transaction, error) {
+	transaction.Status = models.TransactionPending
+	created := events.NewOutboxEvent(ctx, events.TransactionEventsStream, events.TransactionCreated, events.TransactionCreatedEvent{
+		TransactionID: transaction.ID,
+		AccountNumber: transaction.AccountNumber,
+		UserID:        transaction.UserID,
+		Amount:        transaction.Amount,
+		Type:          transaction.Type,
+		Currency:      transaction.Currency,
+	})
+	if ledger.IsDebit(transaction.Type) {
+		if err := s.funds.Reserve(ctx, transaction.AccountNumber, transaction.ID, transaction.Amount, account.Balance, holdTTL); err != nil {
+			observeReserveError(transaction, err)
+			return nil, err
+		}
+	}
+	if err := s.writeRepo.Create(ctx, transaction, created); err != nil {
+		releaseHold(ctx, s.funds, transaction)
+		return nil, err
+	}
+	persistHold(ctx, s.funds, transaction)
+	observeCreated(transaction)
+	s.readRepo.CacheTransactionView(ctx, txToView(transaction))
+	return transaction, nil
+}
+
+// HandleAccountEvent records the outcome account-service reports for each
+// transaction on transaction.posted and transaction.rejected. Redelivered
+// events find the transaction no longer pending and are no-ops.
+func (s *TransactionCommandService) HandleAccountEvent(ctx context.Context, event events.Event) error {
+	status := models.TransactionPosted
+	switch event.Type {
+	case events.TransactionPosted:
+	case events.TransactionRejected:
+		status = models.TransactionRejected
+	default:
+		return nil
+	}
+	dataBytes, _ := json.Marshal(event.Data)
+	var data events.TransactionOutcomeEvent
+	if err := json.Unmarshal(dataBytes, &data); err != nil {
+		return fmt.Errorf("failed to unmarshal %s event: %w", event.Type, err)
+	}
+	changed, err := s.writeRepo.SetOutcome(ctx, data.TransactionID, status, data.Reason)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		slog.InfoContext(ctx, "Transaction outcome already recorded, skipping duplicate event", "transaction_id", data.TransactionID)
+		return nil
+	}
+	s.readRepo.InvalidateTransactionView(ctx, data.AccountNumber, data.TransactionID)
+	if status == models.TransactionRejected {
+		slog.WarnContext(ctx, "Transaction rejected", "transaction_id", data.TransactionID, "type", data.Type, "reason", data.Reason)
+	}
+	return nil
+}
+
+// persistHold keeps the hold for a recorded debit until account-service
+// settles or releases it. If this fails the hold lapses after holdTTL, which
+// only matters if the event is still waiting by then.
+func persistHold(ctx context.Context, funds *sharedredis.Funds, t *models.Transaction) {
+	if !ledger.IsDebit(t.Type) {
+		return
+	}
+	if err := funds.Persist(ctx, t.AccountNumber, t.ID); err != nil {
+		slog.ErrorContext(ctx, "Failed to persist hold", "transaction_id", t.ID, "err", err)
+	}
+}
+
+// releaseHold drops the hold for a debit that was not recorded.
+func releaseHold(ctx context.Context, funds *sharedredis.Funds, t *models.Transaction) {
+	if !ledger.IsDebit(t.Type) {
+		return
+	}
+	if err := funds.Release(ctx, t.AccountNumber, t.ID); err != nil {
+		slog.ErrorContext(ctx, "Failed to release hold", "transaction_id", t.ID, "err", err)
+	}
+}
+
 // txToView converts the write model to a read view model.
 func txToView(t *models.Transaction) *models.TransactionView {
 	return &models.TransactionView{
@@ -88,6 +205,9 @@ func txToView(t *models.Transaction) *models.TransactionView {
 		Currency:      t.Currency,
 		Type:          t.Type,
 		Reference:     t.Reference,
+		TransferID:    t.TransferID,
+		Status:        t.Status,
+		FailureReason: t.FailureReason,
 		CreatedAt:     t.CreatedAt,
 	}
 }
@@ -0,0 +1,195 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/eaglebank/shared/cqrs"
+	"github.com/eaglebank/shared/events"
+	"github.com/eaglebank/shared/ledger"
+	"github.com/eaglebank/shared/middleware"
+	"github.com/eaglebank/shared/models"
+	sharedredis "github.com/eaglebank/shared/redis"
+	"github.com/eaglebank/shared/utils"
+	"github.com/eaglebank/transaction-service/internal/repository"
+)
+
+// TransferCommandService orchestrates the transfer saga. A transfer is
+// created with its debit leg; each leg is applied by account-service, which
+// reports back on transfer.events, and the next leg (credit, or a reversal if
+// the credit is rejected) is only issued once the previous one has settled.
+type TransferCommandService struct {
+	transferRepo *repository.TransferWriteRepository
+	readRepo     *repository.TransactionReadRepository
+	accountRepo  *repository.AccountRepository
+	funds        *sharedredis.Funds
+}
+
+func NewTransferCommandService(
+	transferRepo *repository.TransferWriteRepository,
+	readRepo *repository.TransactionReadRepository,
+	accountRepo *repository.AccountRepository,
+	funds *sharedredis.Funds,
+) *TransferCommandService {
+	return &TransferCommandService{
+		transferRepo: transferRepo,
+		readRepo:     readRepo,
+		accountRepo:  accountRepo,
+		funds:        funds,
+	}
+}
+
+// CreateTransfer validates the request against the account cache, holds the
+// amount against the source account's available balance and records the
+// transfer with its debit leg.
+func (s *TransferCommandService) CreateTransfer(ctx context.Context, cmd cqrs.CreateTransferCommand) (*models.Transfer, error) {
+	if !cmd.Amount.IsPositive() {
+		return nil, fmt.Errorf("amount must be greater than zero")
+	}
+	if cmd.AccountNumber == cmd.ToAccountNumber {
+		return nil, fmt.Errorf("same account")
+	}
+	source, err := s.accountRepo.GetAccount(ctx, cmd.AccountNumber)
+	if err != nil {
+		return nil, fmt.Errorf("account not found")
+	}
+	if !cmd.Principal.CanAccess(source.UserID, middleware.ScopeTransactionsAdjust) {
+		return nil, fmt.Errorf("forbidden")
+	}
+	if source.Frozen() {
+		return nil, fmt.Errorf("account frozen")
+	}
+	destination, err := s.accountRepo.GetAccount(ctx, cmd.ToAccountNumber)
+	if err != nil {
+		return nil, fmt.Errorf("destination account not found")
+	}
+	if destination.Frozen() {
+		return nil, fmt.Errorf("destination account frozen")
+	}
+	if source.Currency != cmd.Currency || destination.Currency != cmd.Currency {
+		return nil, fmt.Errorf("currency mismatch")
+	}
+
+	now := time.Now().UTC()
+	transfer := &models.Transfer{
+		ID:                utils.GenerateID("trf"),
+		FromAccountNumber: cmd.AccountNumber,
+		ToAccountNumber:   cmd.ToAccountNumber,
+		UserID:            source.UserID,
+		ToUserID:          destination.UserID,
+		Amount:            cmd.Amount,
+		Currency:          cmd.Currency,
+		Reference:         cmd.Reference,
+		Status:            models.TransferPending,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	debit := transferLeg(transfer, ledger.TransferOut, transfer.FromAccountNumber, transfer.UserID)
+	if err := s.funds.Reserve(ctx, debit.AccountNumber, debit.ID, debit.Amount, source.Balance, holdTTL); err != nil {
+		observeReserveError(debit, err)
+		return nil, err
+	}
+	if err := s.transferRepo.Create(ctx, transfer, debit, transactionCreated(ctx, debit)); err != nil {
+		releaseHold(ctx, s.funds, debit)
+		return nil, err
+	}
+	persistHold(ctx, s.funds, debit)
+	observeCreated(debit)
+	s.readRepo.CacheTransactionView(ctx, txToView(debit))
+	return transfer, nil
+}
+
+// HandleTransferEvent advances the saga on transfer.leg_applied and
+// transfer.leg_failed events. Every step is a guarded status transition, so
+// redelivered events are no-ops.
+func (s *TransferCommandService) HandleTransferEvent(ctx context.Context, event events.Event) error {
+	if event.Type != events.TransferLegApplied && event.Type != events.TransferLegFailed {
+		return nil
+	}
+	dataBytes, _ := json.Marshal(event.Data)
+	var leg events.TransferLegEvent
+	if err := json.Unmarshal(dataBytes, &leg); err != nil {
+		return fmt.Errorf("failed to unmarshal %s event: %w", event.Type, err)
+	}
+	transfer, err := s.transferRepo.GetByID(leg.TransferID)
+	if err != nil {
+		return fmt.Errorf("failed to get transfer %s: %w", leg.TransferID, err)
+	}
+	applied := event.Type == events.TransferLegApplied
+
+	var advanced bool
+	switch {
+	case leg.Type == ledger.TransferOut && applied:
+		credit := transferLeg(transfer, ledger.TransferIn, transfer.ToAccountNumber, transfer.ToUserID)
+		advanced, err = s.transferRepo.RecordDebit(ctx, transfer.ID, credit, transactionCreated(ctx, credit))
+		if advanced {
+			observeCreated(credit)
+			s.readRepo.CacheTransactionView(ctx, txToView(credit))
+		}
+	case leg.Type == ledger.TransferOut:
+		advanced, err = s.transferRepo.Fail(ctx, transfer.ID, leg.TransactionID, leg.Reason)
+		if advanced {
+			s.readRepo.InvalidateTransactionView(ctx, leg.AccountNumber, leg.TransactionID)
+		}
+	case leg.Type == ledger.TransferIn && applied:
+		advanced, err = s.transferRepo.Complete(ctx, transfer.ID)
+	case leg.Type == ledger.TransferIn:
+		reversal := transferLeg(transfer, ledger.TransferReversal, transfer.FromAccountNumber, transfer.UserID)
+		advanced, err = s.transferRepo.Compensate(ctx, transfer.ID, leg.TransactionID, leg.Reason, reversal, transactionCreated(ctx, reversal))
+		if advanced {
+			s.readRepo.InvalidateTransactionView(ctx, leg.AccountNumber, leg.TransactionID)
+			observeCreated(reversal)
+			s.readRepo.CacheTransactionView(ctx, txToView(reversal))
+		}
+	case leg.Type == ledger.TransferReversal && applied:
+		advanced, err = s.transferRepo.CompleteReversal(ctx, transfer.ID)
+	case leg.Type == ledger.TransferReversal:
+		advanced, err = s.transferRepo.FailReversal(ctx, transfer.ID, leg.Reason)
+		if advanced {
+			slog.ErrorContext(ctx, "Transfer reversal rejected, manual resolution required", "transfer_id", transfer.ID, "reason", leg.Reason)
+		}
+	default:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to advance transfer %s: %w", transfer.ID, err)
+	}
+	if !advanced {
+		slog.InfoContext(ctx, "Transfer already advanced, skipping duplicate event", "transfer_id", transfer.ID, "leg", leg.Type)
+		return nil
+	}
+	slog.InfoContext(ctx, "Transfer advanced", "transfer_id", transfer.ID, "leg", leg.Type)
+	return nil
+}
+
+// transferLeg builds one leg of a transfer as a Transaction linked by TransferID.
+func transferLeg(transfer *models.Transfer, legType, accountNumber, userID string) *models.Transaction {
+	return &models.Transaction{
+		ID:            utils.GenerateID("tan"),
+		AccountNumber: accountNumber,
+		UserID:        userID,
+		Amount:        transfer.Amount,
+		Currency:      transfer.Currency,
+		Type:          legType,
+		Reference:     transfer.Reference,
+		TransferID:    transfer.ID,
+		Status:        models.TransactionPending,
+		CreatedAt:     time.Now().UTC(),
+	}
+}
+
+// transactionCreated is the outbox event that asks account-service to apply t.
+func transactionCreated(ctx context.Context, t *models.Transaction) events.OutboxEvent {
+	return events.NewOutboxEvent(ctx, events.TransactionEventsStream, events.TransactionCreated, events.TransactionCreatedEvent{
+		TransactionID: t.ID,
+		AccountNumber: t.AccountNumber,
+		UserID:        t.UserID,
+		Amount:        t.Amount,
+		Type:          t.Type,
+		Currency:      t.Currency,
+		TransferID:    t.TransferID,
+	})
+}
@@ -1,23 +1,34 @@
 package handler
 
 import (
+	"context"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"time"
 
+	"github.com/eaglebank/shared/admin"
 	"github.com/eaglebank/shared/cqrs"
+	"github.com/eaglebank/shared/ledger"
 	"github.com/eaglebank/shared/middleware"
 	"github.com/eaglebank/shared/models"
+	"github.com/eaglebank/shared/money"
+	"github.com/eaglebank/transaction-service/internal/statement"
 	"github.com/gin-gonic/gin"
 )
 
 // TransactionCommander defines the write-side operations used by TransactionHandler.
 type TransactionCommander interface {
-	CreateTransaction(cqrs.CreateTransactionCommand) (*models.Transaction, error)
+	CreateTransaction(context.Context, cqrs.CreateTransactionCommand) (*models.Transaction, error)
+	PostAdjustment(context.Context, cqrs.PostAdjustmentCommand) (*models.Transaction, error)
 }
 
 // TransactionQuerier defines the read-side operations used by TransactionHandler.
 type TransactionQuerier interface {
-	GetTransaction(cqrs.GetTransactionQuery) (*models.TransactionView, error)
-	ListTransactions(cqrs.ListTransactionsQuery) ([]models.TransactionView, error)
+	GetTransaction(context.Context, cqrs.GetTransactionQuery) (*models.TransactionView, error)
+	ListTransactions(context.Context, cqrs.ListTransactionsQuery) (*models.TransactionPage, error)
+	GetStatement(context.Context, cqrs.GetStatementQuery) (*statement.Header, error)
+	StreamStatement(context.Context, cqrs.GetStatementQuery, func(models.TransactionView) error) error
 }
 
 type TransactionHandler struct {
@@ -26,23 +37,59 @@ type TransactionHandler struct {
 }
 
 type CreateTransactionRequest struct {
-	Amount    float64 `json:"amount" validate:"required,gt=0"`
-	Currency  string  `json:"currency" validate:"required,oneof=GBP"`
-	Type      string  `json:"type" validate:"required,oneof=deposit withdrawal"`
-	Reference string  `json:"reference"`
+	Amount    money.Amount `json:"amount" validate:"required,gt=0"`
+	Currency  string       `json:"currency" validate:"required,oneof=GBP"`
+	Type      string       `json:"type" validate:"required,oneof=deposit withdrawal"`
+	Reference string       `json:"reference"`
+}
+
+// AdjustmentRequest is a manual correction posted by staff. Direction says
+// whether it credits or debits the account.
+type AdjustmentRequest struct {
+	Amount    money.Amount `json:"amount" validate:"required,gt=0"`
+	Currency  string       `json:"currency" validate:"required,oneof=GBP"`
+	Direction string       `json:"direction" validate:"required,oneof=credit debit"`
+	Reason    string       `json:"reason" validate:"required,max=255"`
 }
 
 type ListTransactionsResponse struct {
-	Transactions []any `json:"transactions"`
+	Transactions []any  `json:"transactions"`
+	NextCursor   string `json:"nextCursor,omitempty"`
+}
+
+// ListTransactionsParams are the query parameters accepted when listing
+// transactions. Dates are RFC 3339 timestamps or YYYY-MM-DD; "to" is exclusive.
+type ListTransactionsParams struct {
+	Limit     int    `form:"limit" validate:"omitempty,min=1,max=100"`
+	Cursor    string `form:"cursor"`
+	Sort      string `form:"sort" validate:"omitempty,oneof=asc desc"`
+	From      string `form:"from"`
+	To        string `form:"to"`
+	Type      string `form:"type" validate:"omitempty,oneof=deposit withdrawal transfer_out transfer_in transfer_reversal adjustment_credit adjustment_debit"`
+	MinAmount string `form:"minAmount"`
+	MaxAmount string `form:"maxAmount"`
+	Reference string `form:"reference" validate:"max=255"`
+}
+
+const defaultListLimit = 20
+
+// StatementParams are the query parameters accepted when exporting a
+// statement. from and to are required; a YYYY-MM-DD "to" includes that day.
+type StatementParams struct {
+	From   string `form:"from" validate:"required"`
+	To     string `form:"to" validate:"required"`
+	Format string `form:"format" validate:"omitempty,oneof=csv ofx camt053"`
 }
 
+// maxStatementPeriod bounds a single export; longer histories are exported in parts.
+const maxStatementPeriod = 366 * 24 * time.Hour
+
 func NewTransactionHandler(commands TransactionCommander, queries TransactionQuerier) *TransactionHandler {
 	return &TransactionHandler{commands: commands, queries: queries}
 }
 
 func (h *TransactionHandler) CreateTransaction(c *gin.Context) {
 	accountNumber := c.Param("accountNumber")
-	userID, _ := middleware.GetUserID(c)
 
 	var req CreateTransactionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -54,9 +101,9 @@ func (h *TransactionHandler) CreateTransaction(c *gin.Context) {
 		return
 	}
 
-	transaction, err := h.commands.CreateTransaction(cqrs.CreateTransactionCommand{
+	transaction, err := h.commands.CreateTransaction(c.Request.Context(), cqrs.CreateTransactionCommand{
 		AccountNumber: accountNumber,
-		UserID:        userID,
+		Principal:     middleware.GetPrincipal(c),
 		Amount:        req.Amount,
 		Currency:      req.Currency,
 		Type:          req.Type,
@@ -68,8 +115,12 @@ func (h *TransactionHandler) CreateTransaction(c *gin.Context) {
 			middleware.RespondWithError(c, http.StatusNotFound, "Account not found")
 		case "forbidden":
 			middleware.RespondWithError(c, http.StatusForbidden, "You can only create transactions for your own accounts")
+		case "account frozen":
+			middleware.RespondWithError(c, http.StatusConflict, "Account is frozen")
 		case "insufficient funds":
 			middleware.RespondWithError(c, http.StatusUnprocessableEntity, "Insufficient funds")
+		case "currency mismatch":
+			middleware.RespondWithError(c, http.StatusUnprocessableEntity, "Currency does not match the account currency")
 		default:
 			middleware.RespondWithError(c, http.StatusInternalServerError, "Failed to create transaction")
 		}
@@ -79,42 +130,155 @@ func (h *TransactionHandler) CreateTransaction(c *gin.Context) {
 	c.JSON(http.StatusCreated, transaction)
 }
 
+// PostAdjustment is the back-office action that posts a manual correction
+// to any account.
+func (h *TransactionHandler) PostAdjustment(c *gin.Context) {
+	var req AdjustmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.RespondWithError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if validationErrors := middleware.ValidateRequest(req); validationErrors != nil {
+		middleware.RespondWithValidationError(c, validationErrors)
+		return
+	}
+	admin.AuditDetail(c, "reason", req.Reason)
+
+	transactionType := ledger.AdjustmentCredit
+	if req.Direction == "debit" {
+		transactionType = ledger.AdjustmentDebit
+	}
+	transaction, err := h.commands.PostAdjustment(c.Request.Context(), cqrs.PostAdjustmentCommand{
+		AccountNumber: c.Param("accountNumber"),
+		ActorID:       middleware.GetPrincipal(c).Subject(),
+		Type:          transactionType,
+		Amount:        req.Amount,
+		Currency:      req.Currency,
+		Reason:        req.Reason,
+	})
+	if err != nil {
+		switch err.Error() {
+		case "account not found":
+			middleware.RespondWithError(c, http.StatusNotFound, "Account not found")
+		case "insufficient funds":
+			middleware.RespondWithError(c, http.StatusUnprocessableEntity, "Insufficient funds")
+		case "currency mismatch":
+			middleware.RespondWithError(c, http.StatusUnprocessableEntity, "Currency does not match the account currency")
+		default:
+			middleware.RespondWithError(c, http.StatusInternalServerError, "Failed to post adjustment")
+		}
+		return
+	}
+	admin.AuditDetail(c, "transactionId", transaction.ID)
+
+	c.JSON(http.StatusCreated, transaction)
+}
+
 func (h *TransactionHandler) ListTransactions(c *gin.Context) {
 	accountNumber := c.Param("accountNumber")
-	userID, _ := middleware.GetUserID(c)
 
-	views, err := h.queries.ListTransactions(cqrs.ListTransactionsQuery{
-		AccountNumber: accountNumber,
-		UserID:        userID,
-	})
+	var params ListTransactionsParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		middleware.RespondWithError(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+	if validationErrors := middleware.ValidateRequest(params); validationErrors != nil {
+		middleware.RespondWithValidationError(c, validationErrors)
+		return
+	}
+	query, err := params.toQuery()
+	if err != nil {
+		middleware.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	query.AccountNumber = accountNumber
+	query.Principal = middleware.GetPrincipal(c)
+
+	page, err := h.queries.ListTransactions(c.Request.Context(), query)
 	if err != nil {
 		switch err.Error() {
 		case "account not found":
 			middleware.RespondWithError(c, http.StatusNotFound, "Account not found")
 		case "forbidden":
 			middleware.RespondWithError(c, http.StatusForbidden, "You can only view transactions for your own accounts")
+		case "invalid cursor":
+			middleware.RespondWithError(c, http.StatusBadRequest, "Invalid cursor")
 		default:
 			middleware.RespondWithError(c, http.StatusInternalServerError, "Failed to list transactions")
 		}
 		return
 	}
 
-	transactionsAny := make([]any, len(views))
-	for i, v := range views {
+	transactionsAny := make([]any, len(page.Transactions))
+	for i, v := range page.Transactions {
 		transactionsAny[i] = v
 	}
-	c.JSON(http.StatusOK, ListTransactionsResponse{Transactions: transactionsAny})
+	c.JSON(http.StatusOK, ListTransactionsResponse{Transactions: transactionsAny, NextCursor: page.NextCursor})
+}
+
+// toQuery parses the dates and amounts; the error message is client-facing.
+func (p ListTransactionsParams) toQuery() (cqrs.ListTransactionsQuery, error) {
+	q := cqrs.ListTransactionsQuery{
+		Limit:     p.Limit,
+		Cursor:    p.Cursor,
+		Ascending: p.Sort == "asc",
+		Type:      p.Type,
+		Reference: p.Reference,
+	}
+	if q.Limit == 0 {
+		q.Limit = defaultListLimit
+	}
+	var err error
+	if q.From, err = parseDateParam(p.From); err != nil {
+		return q, fmt.Errorf("from must be an RFC 3339 timestamp or YYYY-MM-DD date")
+	}
+	if q.To, err = parseDateParam(p.To); err != nil {
+		return q, fmt.Errorf("to must be an RFC 3339 timestamp or YYYY-MM-DD date")
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && !q.From.Before(q.To) {
+		return q, fmt.Errorf("from must be before to")
+	}
+	if q.MinAmount, err = parseAmountParam(p.MinAmount); err != nil {
+		return q, fmt.Errorf("minAmount must be a decimal amount")
+	}
+	if q.MaxAmount, err = parseAmountParam(p.MaxAmount); err != nil {
+		return q, fmt.Errorf("maxAmount must be a decimal amount")
+	}
+	if q.MinAmount != nil && q.MaxAmount != nil && q.MinAmount.Cmp(*q.MaxAmount) > 0 {
+		return q, fmt.Errorf("minAmount must not exceed maxAmount")
+	}
+	return q, nil
+}
+
+func parseDateParam(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+func parseAmountParam(value string) (*money.Amount, error) {
+	if value == "" {
+		return nil, nil
+	}
+	amount, err := money.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+	return &amount, nil
 }
 
 func (h *TransactionHandler) GetTransaction(c *gin.Context) {
 	accountNumber := c.Param("accountNumber")
 	transactionID := c.Param("transactionId")
-	userID, _ := middleware.GetUserID(c)
 
-	view, err := h.queries.GetTransaction(cqrs.GetTransactionQuery{
+	view, err := h.queries.GetTransaction(c.Request.Context(), cqrs.GetTransactionQuery{
 		TransactionID: transactionID,
 		AccountNumber: accountNumber,
-		UserID:        userID,
+		Principal:     middleware.GetPrincipal(c),
 	})
 	if err != nil {
 		switch err.Error() {
@@ -132,3 +296,85 @@ func (h *TransactionHandler) GetTransaction(c *gin.Context) {
 
 	c.JSON(http.StatusOK, view)
 }
+
+// GetStatement streams an account statement for a period as CSV (the
+// default), OFX or camt.053. Errors found before the first byte is written get
+// the usual JSON error response; a failure mid-stream can only be logged and
+// leaves the download truncated.
+func (h *TransactionHandler) GetStatement(c *gin.Context) {
+	accountNumber := c.Param("accountNumber")
+
+	var params StatementParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		middleware.RespondWithError(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+	if validationErrors := middleware.ValidateRequest(params); validationErrors != nil {
+		middleware.RespondWithValidationError(c, validationErrors)
+		return
+	}
+	query, err := params.toQuery()
+	if err != nil {
+		middleware.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	query.AccountNumber = accountNumber
+	query.Principal = middleware.GetPrincipal(c)
+
+	header, err := h.queries.GetStatement(c.Request.Context(), query)
+	if err != nil {
+		switch err.Error() {
+		case "account not found":
+			middleware.RespondWithError(c, http.StatusNotFound, "Account not found")
+		case "forbidden":
+			middleware.RespondWithError(c, http.StatusForbidden, "You can only view statements for your own accounts")
+		default:
+			middleware.RespondWithError(c, http.StatusInternalServerError, "Failed to generate statement")
+		}
+		return
+	}
+
+	format := params.Format
+	if format == "" {
+		format = statement.CSV
+	}
+	contentType, ext := statement.ContentType(format)
+	filename := fmt.Sprintf("statement-%s-%s-%s.%s", accountNumber,
+		query.From.Format("20060102"), query.To.Add(-time.Nanosecond).Format("20060102"), ext)
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+
+	err = statement.Render(c.Writer, format, *header, func(emit func(models.TransactionView) error) error {
+		return h.queries.StreamStatement(c.Request.Context(), query, emit)
+	})
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to stream statement", "account_number", accountNumber, "err", err)
+	}
+}
+
+func (p StatementParams) toQuery() (cqrs.GetStatementQuery, error) {
+	var q cqrs.GetStatementQuery
+	var err error
+	if q.From, err = parseDateParam(p.From); err != nil {
+		return q, fmt.Errorf("from must be an RFC 3339 timestamp or YYYY-MM-DD date")
+	}
+	if q.To, err = parseDateParam(p.To); err != nil {
+		return q, fmt.Errorf("to must be an RFC 3339 timestamp or YYYY-MM-DD date")
+	}
+	if isDateOnly(p.To) {
+		q.To = q.To.AddDate(0, 0, 1)
+	}
+	if !q.From.Before(q.To) {
+		return q, fmt.Errorf("from must be before to")
+	}
+	if q.To.Sub(q.From) > maxStatementPeriod {
+		return q, fmt.Errorf("statement period must not exceed one year")
+	}
+	return q, nil
+}
+
+func isDateOnly(value string) bool {
+	_, err := time.Parse("2006-01-02", value)
+	return err == nil
+}
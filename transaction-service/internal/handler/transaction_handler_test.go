@@ -1,7 +1,10 @@
 package handler
 
 import (
+	"context"
+	"encoding/csv"
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -10,41 +13,73 @@ import (
 	"time"
 
 	"github.com/eaglebank/shared/cqrs"
+	"github.com/eaglebank/shared/ledger"
 	"github.com/eaglebank/shared/models"
+	"github.com/eaglebank/shared/money"
+	"github.com/eaglebank/transaction-service/internal/statement"
 	"github.com/gin-gonic/gin"
 )
 
 // ---- mock implementations ----
 
 type mockTransactionCommander struct {
-	createFn func(cqrs.CreateTransactionCommand) (*models.Transaction, error)
+	createFn     func(cqrs.CreateTransactionCommand) (*models.Transaction, error)
+	adjustmentFn func(cqrs.PostAdjustmentCommand) (*models.Transaction, error)
 }
 
-func (m *mockTransactionCommander) CreateTransaction(cmd cqrs.CreateTransactionCommand) (*models.Transaction, error) {
+func (m *mockTransactionCommander) CreateTransaction(_ context.Context, cmd cqrs.CreateTransactionCommand) (*models.Transaction, error) {
 	if m.createFn != nil {
 		return m.createFn(cmd)
 	}
 	return nil, fmt.Errorf("not configured")
 }
+func (m *mockTransactionCommander) PostAdjustment(_ context.Context, cmd cqrs.PostAdjustmentCommand) (*models.Transaction, error) {
+	if m.adjustmentFn != nil {
+		return m.adjustmentFn(cmd)
+	}
+	return nil, fmt.Errorf("not configured")
+}
 
 type mockTransactionQuerier struct {
 	getFn  func(cqrs.GetTransactionQuery) (*models.TransactionView, error)
-	listFn func(cqrs.ListTransactionsQuery) ([]models.TransactionView, error)
+	listFn func(cqrs.ListTransactionsQuery) (*models.TransactionPage, error)
+	// statementFn returns the header and the lines StreamStatement emits.
+	statementFn func(cqrs.GetStatementQuery) (*statement.Header, []models.TransactionView, error)
 }
 
-func (m *mockTransactionQuerier) GetTransaction(q cqrs.GetTransactionQuery) (*models.TransactionView, error) {
+func (m *mockTransactionQuerier) GetTransaction(_ context.Context, q cqrs.GetTransactionQuery) (*models.TransactionView, error) {
 	if m.getFn != nil {
 		return m.getFn(q)
 	}
 	return nil, fmt.Errorf("not configured")
 }
-func (m *mockTransactionQuerier) ListTransactions(q cqrs.ListTransactionsQuery) ([]models.TransactionView, error) {
+func (m *mockTransactionQuerier) ListTransactions(_ context.Context, q cqrs.ListTransactionsQuery) (*models.TransactionPage, error) {
 	if m.listFn != nil {
 		return m.listFn(q)
 	}
 	return nil, fmt.Errorf("not configured")
 }
 
+func (m *mockTransactionQuerier) GetStatement(_ context.Context, q cqrs.GetStatementQuery) (*statement.Header, error) {
+	if m.statementFn != nil {
+		header, _, err := m.statementFn(q)
+		return header, err
+	}
+	return nil, fmt.Errorf("not configured")
+}
+func (m *mockTransactionQuerier) StreamStatement(_ context.Context, q cqrs.GetStatementQuery, emit func(models.TransactionView) error) error {
+	if m.statementFn == nil {
+		return fmt.Errorf("not configured")
+	}
+	_, lines, _ := m.statementFn(q)
+	for _, l := range lines {
+		if err := emit(l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // ---- helpers ----
 
 func fakeAuthTx(userID string) gin.HandlerFunc {
@@ -63,6 +98,8 @@ func newTxTestRouter(cmds TransactionCommander, qrys TransactionQuerier, authUse
 	v1.POST("", h.CreateTransaction)
 	v1.GET("", h.ListTransactions)
 	v1.GET("/:transactionId", h.GetTransaction)
+	r.GET("/v1/accounts/:accountNumber/statements", h.GetStatement)
+	r.POST("/admin/v1/accounts/:accountNumber/adjustments", h.PostAdjustment)
 	return r
 }
 
@@ -82,13 +119,13 @@ func txDoRequest(router *gin.Engine, method, url string, body interface{}) *http
 
 var txTestTransaction = &models.Transaction{
 	ID: "tan-001", AccountNumber: "12345678", UserID: "usr-001",
-	Amount: 50.00, Currency: "GBP", Type: "deposit",
+	Amount: money.MustParse("50.00"), Currency: "GBP", Type: "deposit",
 	CreatedAt: time.Now(),
 }
 
 var txTestView = &models.TransactionView{
 	ID: "tan-001", AccountNumber: "12345678", UserID: "usr-001",
-	Amount: 50.00, Currency: "GBP", Type: "deposit",
+	Amount: money.MustParse("50.00"), Currency: "GBP", Type: "deposit",
 	CreatedAt: time.Now(),
 }
 
@@ -145,6 +182,13 @@ func TestCreateTransaction(t *testing.T) {
 			createFn:       func(cmd cqrs.CreateTransactionCommand) (*models.Transaction, error) { return nil, fmt.Errorf("account not found") },
 			expectedStatus: http.StatusNotFound,
 		},
+		{
+			name:           "conflict - account is frozen",
+			accountNum:     "12345678",
+			body:           txDepositBody(),
+			createFn:       func(cmd cqrs.CreateTransactionCommand) (*models.Transaction, error) { return nil, fmt.Errorf("account frozen") },
+			expectedStatus: http.StatusConflict,
+		},
 		{
 			name: "bad request - missing required fields",
 			accountNum: "12345678",
@@ -159,6 +203,25 @@ func TestCreateTransaction(t *testing.T) {
 			createFn:       nil,
 			expectedStatus: http.StatusBadRequest,
 		},
+		{
+			name: "bad request - amount has more than two decimal places",
+			accountNum: "12345678",
+			body:           map[string]interface{}{"amount": 10.005, "currency": "GBP", "type": "deposit"},
+			createFn:       nil,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "success - amount is passed through exactly",
+			accountNum: "12345678",
+			body:           map[string]interface{}{"amount": 0.1, "currency": "GBP", "type": "deposit"},
+			createFn: func(cmd cqrs.CreateTransactionCommand) (*models.Transaction, error) {
+				if cmd.Amount != money.FromMinor(10) {
+					return nil, fmt.Errorf("unexpected amount %s", cmd.Amount)
+				}
+				return txTestTransaction, nil
+			},
+			expectedStatus: http.StatusCreated,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -173,25 +236,75 @@ func TestCreateTransaction(t *testing.T) {
 	}
 }
 
+func TestPostAdjustment(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           interface{}
+		adjustmentFn   func(cqrs.PostAdjustmentCommand) (*models.Transaction, error)
+		expectedStatus int
+	}{
+		{
+			name: "success - debit maps to an adjustment debit by the acting staff member",
+			body: map[string]interface{}{"amount": 5, "currency": "GBP", "direction": "debit", "reason": "duplicate refund"},
+			adjustmentFn: func(cmd cqrs.PostAdjustmentCommand) (*models.Transaction, error) {
+				if cmd.Type != ledger.AdjustmentDebit || cmd.ActorID != "usr-001" || cmd.Reason != "duplicate refund" {
+					return nil, fmt.Errorf("unexpected command %+v", cmd)
+				}
+				return txTestTransaction, nil
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "bad request - reason is required",
+			body:           map[string]interface{}{"amount": 5, "currency": "GBP", "direction": "credit"},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "bad request - unknown direction",
+			body:           map[string]interface{}{"amount": 5, "currency": "GBP", "direction": "sideways", "reason": "x"},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "unprocessable entity - insufficient funds",
+			body: map[string]interface{}{"amount": 5, "currency": "GBP", "direction": "debit", "reason": "x"},
+			adjustmentFn: func(cmd cqrs.PostAdjustmentCommand) (*models.Transaction, error) {
+				return nil, fmt.Errorf("insufficient funds")
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmds := &mockTransactionCommander{adjustmentFn: tt.adjustmentFn}
+			router := newTxTestRouter(cmds, &mockTransactionQuerier{}, "usr-001")
+			w := txDoRequest(router, http.MethodPost, "/admin/v1/accounts/12345678/adjustments", tt.body)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("[%s] expected %d got %d; body: %s", tt.name, tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
 func TestListTransactions(t *testing.T) {
 	tests := []struct {
 		name           string
 		accountNum     string
-		listFn         func(cqrs.ListTransactionsQuery) ([]models.TransactionView, error)
+		url            string
+		listFn         func(cqrs.ListTransactionsQuery) (*models.TransactionPage, error)
 		expectedStatus int
 	}{
 		{
 			name: "success - list transactions on own account",
 			accountNum: "12345678",
-			listFn: func(q cqrs.ListTransactionsQuery) ([]models.TransactionView, error) {
-				return []models.TransactionView{*txTestView}, nil
+			listFn: func(q cqrs.ListTransactionsQuery) (*models.TransactionPage, error) {
+				return &models.TransactionPage{Transactions: []models.TransactionView{*txTestView}}, nil
 			},
 			expectedStatus: http.StatusOK,
 		},
 		{
 			name: "forbidden - list transactions on another user's account",
 			accountNum: "99999999",
-			listFn: func(q cqrs.ListTransactionsQuery) ([]models.TransactionView, error) {
+			listFn: func(q cqrs.ListTransactionsQuery) (*models.TransactionPage, error) {
 				return nil, fmt.Errorf("forbidden")
 			},
 			expectedStatus: http.StatusForbidden,
@@ -199,16 +312,76 @@ func TestListTransactions(t *testing.T) {
 		{
 			name: "not found - account does not exist",
 			accountNum: "00000000",
-			listFn: func(q cqrs.ListTransactionsQuery) ([]models.TransactionView, error) {
+			listFn: func(q cqrs.ListTransactionsQuery) (*models.TransactionPage, error) {
 				return nil, fmt.Errorf("account not found")
 			},
 			expectedStatus: http.StatusNotFound,
 		},
+		{
+			name:       "success - filters and paging are passed through",
+			accountNum: "12345678",
+			url:        "?limit=5&cursor=abc&sort=asc&from=2024-01-01&to=2024-02-01T00:00:00Z&type=withdrawal&minAmount=10&maxAmount=99.99&reference=rent",
+			listFn: func(q cqrs.ListTransactionsQuery) (*models.TransactionPage, error) {
+				from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+				to := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+				if q.Limit != 5 || q.Cursor != "abc" || !q.Ascending || !q.From.Equal(from) || !q.To.Equal(to) ||
+					q.Type != "withdrawal" || *q.MinAmount != money.FromMinor(1000) || *q.MaxAmount != money.FromMinor(9999) ||
+					q.Reference != "rent" {
+					return nil, fmt.Errorf("unexpected query %+v", q)
+				}
+				return &models.TransactionPage{Transactions: []models.TransactionView{*txTestView}, NextCursor: "next"}, nil
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:       "success - default page size",
+			accountNum: "12345678",
+			listFn: func(q cqrs.ListTransactionsQuery) (*models.TransactionPage, error) {
+				if q.Limit != defaultListLimit || q.Ascending {
+					return nil, fmt.Errorf("unexpected query %+v", q)
+				}
+				return &models.TransactionPage{}, nil
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "bad request - limit out of range",
+			accountNum:     "12345678",
+			url:            "?limit=1000",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "bad request - malformed date",
+			accountNum:     "12345678",
+			url:            "?from=last-week",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "bad request - amount range inverted",
+			accountNum:     "12345678",
+			url:            "?minAmount=50&maxAmount=10",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "bad request - unknown type",
+			accountNum:     "12345678",
+			url:            "?type=refund",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "bad request - cursor rejected by the store",
+			accountNum: "12345678",
+			url:        "?cursor=garbage",
+			listFn: func(q cqrs.ListTransactionsQuery) (*models.TransactionPage, error) {
+				return nil, fmt.Errorf("invalid cursor")
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			router := newTxTestRouter(&mockTransactionCommander{}, &mockTransactionQuerier{listFn: tt.listFn}, "usr-001")
-			url := "/v1/accounts/" + tt.accountNum + "/transactions"
+			url := "/v1/accounts/" + tt.accountNum + "/transactions" + tt.url
 			w := txDoRequest(router, http.MethodGet, url, nil)
 			if w.Code != tt.expectedStatus {
 				t.Errorf("[%s] expected %d got %d; body: %s", tt.name, tt.expectedStatus, w.Code, w.Body.String())
@@ -267,3 +440,144 @@ func TestGetTransaction(t *testing.T) {
 		})
 	}
 }
+
+func txStatementFixture(q cqrs.GetStatementQuery) (*statement.Header, []models.TransactionView, error) {
+	header := &statement.Header{
+		AccountNumber: "12345678", SortCode: "10-10-10", AccountName: "Personal", Currency: "GBP",
+		From: q.From, To: q.To,
+		OpeningBalance: money.MustParse("100.00"), ClosingBalance: money.MustParse("125.00"),
+		GeneratedAt: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+	}
+	lines := []models.TransactionView{
+		{ID: "tan-001", AccountNumber: "12345678", Amount: money.MustParse("50.00"), Currency: "GBP", Type: "deposit",
+			Reference: "salary", CreatedAt: time.Date(2024, 1, 5, 9, 0, 0, 0, time.UTC)},
+		{ID: "tan-002", AccountNumber: "12345678", Amount: money.MustParse("25.00"), Currency: "GBP", Type: "transfer_out",
+			Reference: "rent", CreatedAt: time.Date(2024, 1, 6, 9, 0, 0, 0, time.UTC)},
+	}
+	return header, lines, nil
+}
+
+func TestGetStatement(t *testing.T) {
+	tests := []struct {
+		name                string
+		query               string
+		statementFn         func(cqrs.GetStatementQuery) (*statement.Header, []models.TransactionView, error)
+		expectedStatus      int
+		expectedContentType string
+	}{
+		{
+			name:                "success - csv by default",
+			query:               "from=2024-01-01&to=2024-01-31",
+			statementFn:         txStatementFixture,
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/csv; charset=utf-8",
+		},
+		{
+			name:                "success - ofx",
+			query:               "from=2024-01-01&to=2024-01-31&format=ofx",
+			statementFn:         txStatementFixture,
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/x-ofx",
+		},
+		{
+			name:                "success - camt053",
+			query:               "from=2024-01-01&to=2024-01-31&format=camt053",
+			statementFn:         txStatementFixture,
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/xml",
+		},
+		{
+			name:           "bad request - unsupported format",
+			query:          "from=2024-01-01&to=2024-01-31&format=pdf",
+			statementFn:    txStatementFixture,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "bad request - missing period",
+			query:          "format=csv",
+			statementFn:    txStatementFixture,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "bad request - from after to",
+			query:          "from=2024-02-01&to=2024-01-01",
+			statementFn:    txStatementFixture,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "bad request - period longer than a year",
+			query:          "from=2022-01-01&to=2024-01-01",
+			statementFn:    txStatementFixture,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "forbidden - another user's account",
+			query: "from=2024-01-01&to=2024-01-31",
+			statementFn: func(q cqrs.GetStatementQuery) (*statement.Header, []models.TransactionView, error) {
+				return nil, nil, fmt.Errorf("forbidden")
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:  "not found - account does not exist",
+			query: "from=2024-01-01&to=2024-01-31",
+			statementFn: func(q cqrs.GetStatementQuery) (*statement.Header, []models.TransactionView, error) {
+				return nil, nil, fmt.Errorf("account not found")
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newTxTestRouter(&mockTransactionCommander{}, &mockTransactionQuerier{statementFn: tt.statementFn}, "usr-001")
+			w := txDoRequest(router, http.MethodGet, "/v1/accounts/12345678/statements?"+tt.query, nil)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("[%s] expected %d got %d; body: %s", tt.name, tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedContentType == "" {
+				return
+			}
+			if ct := w.Header().Get("Content-Type"); ct != tt.expectedContentType {
+				t.Errorf("[%s] expected Content-Type %q got %q", tt.name, tt.expectedContentType, ct)
+			}
+			if cd := w.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, "attachment;") {
+				t.Errorf("[%s] expected an attachment, got Content-Disposition %q", tt.name, cd)
+			}
+			if strings.HasPrefix(tt.expectedContentType, "application/") {
+				if err := xml.Unmarshal(w.Body.Bytes(), new(struct{})); err != nil {
+					t.Errorf("[%s] body is not well-formed XML: %v", tt.name, err)
+				}
+			}
+		})
+	}
+}
+
+func TestGetStatementCSVRunningBalance(t *testing.T) {
+	var got cqrs.GetStatementQuery
+	querier := &mockTransactionQuerier{statementFn: func(q cqrs.GetStatementQuery) (*statement.Header, []models.TransactionView, error) {
+		got = q
+		return txStatementFixture(q)
+	}}
+	router := newTxTestRouter(&mockTransactionCommander{}, querier, "usr-001")
+	w := txDoRequest(router, http.MethodGet, "/v1/accounts/12345678/statements?from=2024-01-01&to=2024-01-31", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d; body: %s", w.Code, w.Body.String())
+	}
+	if want := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC); !got.To.Equal(want) {
+		t.Errorf("expected a date-only to to include that day (%v), got %v", want, got.To)
+	}
+
+	r := csv.NewReader(w.Body)
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("body is not valid CSV: %v", err)
+	}
+	rows := records[len(records)-2:]
+	expected := [][2]string{{"50.00", "150.00"}, {"-25.00", "125.00"}}
+	for i, row := range rows {
+		if row[4] != expected[i][0] || row[5] != expected[i][1] {
+			t.Errorf("row %d: expected amount %s balance %s, got %v", i, expected[i][0], expected[i][1], row)
+		}
+	}
+}
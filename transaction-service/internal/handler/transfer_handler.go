@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/eaglebank/shared/cqrs"
+	"github.com/eaglebank/shared/middleware"
+	"github.com/eaglebank/shared/models"
+	"github.com/eaglebank/shared/money"
+	"github.com/gin-gonic/gin"
+)
+
+// TransferCommander defines the write-side operations used by TransferHandler.
+type TransferCommander interface {
+	CreateTransfer(context.Context, cqrs.CreateTransferCommand) (*models.Transfer, error)
+}
+
+// TransferQuerier defines the read-side operations used by TransferHandler.
+type TransferQuerier interface {
+	GetTransfer(context.Context, cqrs.GetTransferQuery) (*models.Transfer, error)
+}
+
+type TransferHandler struct {
+	commands TransferCommander
+	queries  TransferQuerier
+}
+
+type CreateTransferRequest struct {
+	ToAccountNumber string       `json:"toAccountNumber" validate:"required,len=8"`
+	Amount          money.Amount `json:"amount" validate:"required,gt=0"`
+	Currency        string       `json:"currency" validate:"required,oneof=GBP"`
+	Reference       string       `json:"reference"`
+}
+
+func NewTransferHandler(commands TransferCommander, queries TransferQuerier) *TransferHandler {
+	return &TransferHandler{commands: commands, queries: queries}
+}
+
+// CreateTransfer starts a transfer. The legs settle asynchronously, so the
+// response is 202 with the transfer in its initial status; poll GetTransfer
+// for the outcome.
+func (h *TransferHandler) CreateTransfer(c *gin.Context) {
+	accountNumber := c.Param("accountNumber")
+
+	var req CreateTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.RespondWithError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if validationErrors := middleware.ValidateRequest(req); validationErrors != nil {
+		middleware.RespondWithValidationError(c, validationErrors)
+		return
+	}
+
+	transfer, err := h.commands.CreateTransfer(c.Request.Context(), cqrs.CreateTransferCommand{
+		AccountNumber:   accountNumber,
+		Principal:       middleware.GetPrincipal(c),
+		ToAccountNumber: req.ToAccountNumber,
+		Amount:          req.Amount,
+		Currency:        req.Currency,
+		Reference:       req.Reference,
+	})
+	if err != nil {
+		switch err.Error() {
+		case "account not found":
+			middleware.RespondWithError(c, http.StatusNotFound, "Account not found")
+		case "destination account not found":
+			middleware.RespondWithError(c, http.StatusUnprocessableEntity, "Destination account not found")
+		case "forbidden":
+			middleware.RespondWithError(c, http.StatusForbidden, "You can only transfer from your own accounts")
+		case "account frozen":
+			middleware.RespondWithError(c, http.StatusConflict, "Account is frozen")
+		case "destination account frozen":
+			middleware.RespondWithError(c, http.StatusUnprocessableEntity, "Destination account is frozen")
+		case "same account":
+			middleware.RespondWithError(c, http.StatusUnprocessableEntity, "Cannot transfer to the same account")
+		case "insufficient funds":
+			middleware.RespondWithError(c, http.StatusUnprocessableEntity, "Insufficient funds")
+		case "currency mismatch":
+			middleware.RespondWithError(c, http.StatusUnprocessableEntity, "Currency does not match the account currency")
+		default:
+			middleware.RespondWithError(c, http.StatusInternalServerError, "Failed to create transfer")
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, transfer)
+}
+
+func (h *TransferHandler) GetTransfer(c *gin.Context) {
+	accountNumber := c.Param("accountNumber")
+	transferID := c.Param("transferId")
+
+	transfer, err := h.queries.GetTransfer(c.Request.Context(), cqrs.GetTransferQuery{
+		TransferID:    transferID,
+		AccountNumber: accountNumber,
+		Principal:     middleware.GetPrincipal(c),
+	})
+	if err != nil {
+		switch err.Error() {
+		case "account not found":
+			middleware.RespondWithError(c, http.StatusNotFound, "Account not found")
+		case "transfer not found":
+			middleware.RespondWithError(c, http.StatusNotFound, "Transfer not found")
+		case "forbidden":
+			middleware.RespondWithError(c, http.StatusForbidden, "You can only view your own transfers")
+		default:
+			middleware.RespondWithError(c, http.StatusInternalServerError, "Failed to get transfer")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, transfer)
+}
@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/eaglebank/shared/cqrs"
+	"github.com/eaglebank/shared/models"
+	"github.com/eaglebank/shared/money"
+	"github.com/gin-gonic/gin"
+)
+
+// ---- mock implementations ----
+
+type mockTransferCommander struct {
+	createFn func(cqrs.CreateTransferCommand) (*models.Transfer, error)
+}
+
+func (m *mockTransferCommander) CreateTransfer(_ context.Context, cmd cqrs.CreateTransferCommand) (*models.Transfer, error) {
+	if m.createFn != nil {
+		return m.createFn(cmd)
+	}
+	return nil, fmt.Errorf("not configured")
+}
+
+type mockTransferQuerier struct {
+	getFn func(cqrs.GetTransferQuery) (*models.Transfer, error)
+}
+
+func (m *mockTransferQuerier) GetTransfer(_ context.Context, q cqrs.GetTransferQuery) (*models.Transfer, error) {
+	if m.getFn != nil {
+		return m.getFn(q)
+	}
+	return nil, fmt.Errorf("not configured")
+}
+
+// ---- helpers ----
+
+func newTransferTestRouter(cmds TransferCommander, qrys TransferQuerier, authUserID string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(fakeAuthTx(authUserID))
+	h := NewTransferHandler(cmds, qrys)
+	v1 := r.Group("/v1/accounts/:accountNumber/transfers")
+	v1.POST("", h.CreateTransfer)
+	v1.GET("/:transferId", h.GetTransfer)
+	return r
+}
+
+// ---- test data ----
+
+var testTransfer = &models.Transfer{
+	ID: "trf-001", FromAccountNumber: "12345678", ToAccountNumber: "87654321",
+	Amount: money.MustParse("25.00"), Currency: "GBP", Status: models.TransferPending,
+	CreatedAt: time.Now(), UpdatedAt: time.Now(),
+}
+
+func transferBody() map[string]interface{} {
+	return map[string]interface{}{"toAccountNumber": "87654321", "amount": 25.0, "currency": "GBP", "reference": "Rent"}
+}
+
+// ---- tests ----
+
+func TestCreateTransfer(t *testing.T) {
+	tests := []struct {
+		name           string
+		accountNum     string
+		body           interface{}
+		createFn       func(cqrs.CreateTransferCommand) (*models.Transfer, error)
+		expectedStatus int
+	}{
+		{
+			name:       "accepted - transfer to another user's account",
+			accountNum: "12345678",
+			body:       transferBody(),
+			createFn: func(cmd cqrs.CreateTransferCommand) (*models.Transfer, error) {
+				if cmd.ToAccountNumber != "87654321" || cmd.Amount != money.FromMinor(2500) || cmd.Principal.UserID != "usr-001" {
+					return nil, fmt.Errorf("unexpected command %+v", cmd)
+				}
+				return testTransfer, nil
+			},
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:       "unprocessable entity - insufficient funds",
+			accountNum: "12345678",
+			body:       transferBody(),
+			createFn: func(cmd cqrs.CreateTransferCommand) (*models.Transfer, error) {
+				return nil, fmt.Errorf("insufficient funds")
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "unprocessable entity - destination account does not exist",
+			accountNum: "12345678",
+			body:       transferBody(),
+			createFn: func(cmd cqrs.CreateTransferCommand) (*models.Transfer, error) {
+				return nil, fmt.Errorf("destination account not found")
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "unprocessable entity - transfer to the same account",
+			accountNum:     "87654321",
+			body:           transferBody(),
+			createFn:       func(cmd cqrs.CreateTransferCommand) (*models.Transfer, error) { return nil, fmt.Errorf("same account") },
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:           "forbidden - transfer from another user's account",
+			accountNum:     "99999999",
+			body:           transferBody(),
+			createFn:       func(cmd cqrs.CreateTransferCommand) (*models.Transfer, error) { return nil, fmt.Errorf("forbidden") },
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:       "not found - source account does not exist",
+			accountNum: "00000000",
+			body:       transferBody(),
+			createFn: func(cmd cqrs.CreateTransferCommand) (*models.Transfer, error) {
+				return nil, fmt.Errorf("account not found")
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "bad request - destination account number malformed",
+			accountNum:     "12345678",
+			body:           map[string]interface{}{"toAccountNumber": "123", "amount": 25.0, "currency": "GBP"},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newTransferTestRouter(&mockTransferCommander{createFn: tt.createFn}, &mockTransferQuerier{}, "usr-001")
+			url := "/v1/accounts/" + tt.accountNum + "/transfers"
+			w := txDoRequest(router, http.MethodPost, url, tt.body)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("[%s] expected %d got %d; body: %s", tt.name, tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetTransfer(t *testing.T) {
+	tests := []struct {
+		name           string
+		accountNum     string
+		transferID     string
+		getFn          func(cqrs.GetTransferQuery) (*models.Transfer, error)
+		expectedStatus int
+	}{
+		{
+			name:       "success - fetch transfer from own account",
+			accountNum: "12345678", transferID: "trf-001",
+			getFn:          func(q cqrs.GetTransferQuery) (*models.Transfer, error) { return testTransfer, nil },
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:       "forbidden - fetch transfer on another user's account",
+			accountNum: "99999999", transferID: "trf-001",
+			getFn:          func(q cqrs.GetTransferQuery) (*models.Transfer, error) { return nil, fmt.Errorf("forbidden") },
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:       "not found - transfer does not exist",
+			accountNum: "12345678", transferID: "trf-999",
+			getFn:          func(q cqrs.GetTransferQuery) (*models.Transfer, error) { return nil, fmt.Errorf("transfer not found") },
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newTransferTestRouter(&mockTransferCommander{}, &mockTransferQuerier{getFn: tt.getFn}, "usr-001")
+			url := "/v1/accounts/" + tt.accountNum + "/transfers/" + tt.transferID
+			w := txDoRequest(router, http.MethodGet, url, nil)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("[%s] expected %d got %d; body: %s", tt.name, tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
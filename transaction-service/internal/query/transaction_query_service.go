@@ -3,14 +3,18 @@ package query
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/eaglebank/shared/cqrs"
+	"github.com/eaglebank/shared/middleware"
 	"github.com/eaglebank/shared/models"
 	"github.com/eaglebank/transaction-service/internal/repository"
+	"github.com/eaglebank/transaction-service/internal/statement"
 )
 
-// TransactionQueryService serves transaction reads. Ownership is always checked
-// against the account cache before returning results.
+// TransactionQueryService serves transaction reads. Access is always checked
+// against the account owner in the account cache before returning results:
+// callers other than the owner need middleware.ScopeTransactionsRead.
 type TransactionQueryService struct {
 	readRepo    *repository.TransactionReadRepository
 	accountRepo *repository.AccountRepository
@@ -20,13 +24,12 @@ func NewTransactionQueryService(readRepo *repository.TransactionReadRepository,
 	return &TransactionQueryService{readRepo: readRepo, accountRepo: accountRepo}
 }
 
-func (s *TransactionQueryService) GetTransaction(q cqrs.GetTransactionQuery) (*models.TransactionView, error) {
-	ctx := context.Background()
+func (s *TransactionQueryService) GetTransaction(ctx context.Context, q cqrs.GetTransactionQuery) (*models.TransactionView, error) {
 	account, err := s.accountRepo.GetAccount(ctx, q.AccountNumber)
 	if err != nil {
 		return nil, fmt.Errorf("account not found")
 	}
-	if account.UserID != q.UserID {
+	if !q.Principal.CanAccess(account.UserID, middleware.ScopeTransactionsRead) {
 		return nil, fmt.Errorf("forbidden")
 	}
 	view, err := s.readRepo.GetByID(ctx, q.TransactionID, q.AccountNumber)
@@ -36,15 +39,61 @@ func (s *TransactionQueryService) GetTransaction(q cqrs.GetTransactionQuery) (*m
 	return view, nil
 }
 
-// ListTransactions returns all transactions for an account. Ownership is verified via the account cache.
-func (s *TransactionQueryService) ListTransactions(q cqrs.ListTransactionsQuery) ([]models.TransactionView, error) {
-	ctx := context.Background()
+// ListTransactions returns one page of an account's transactions. Access is verified via the account cache.
+func (s *TransactionQueryService) ListTransactions(ctx context.Context, q cqrs.ListTransactionsQuery) (*models.TransactionPage, error) {
 	account, err := s.accountRepo.GetAccount(ctx, q.AccountNumber)
 	if err != nil {
 		return nil, fmt.Errorf("account not found")
 	}
-	if account.UserID != q.UserID {
+	if !q.Principal.CanAccess(account.UserID, middleware.ScopeTransactionsRead) {
 		return nil, fmt.Errorf("forbidden")
 	}
-	return s.readRepo.ListByAccountNumber(ctx, q.AccountNumber)
+	views, nextCursor, err := s.readRepo.ListByAccountNumber(ctx, q.AccountNumber, repository.TransactionFilter{
+		Limit:     q.Limit,
+		Cursor:    q.Cursor,
+		Ascending: q.Ascending,
+		From:      q.From,
+		To:        q.To,
+		Type:      q.Type,
+		MinAmount: q.MinAmount,
+		MaxAmount: q.MaxAmount,
+		Reference: q.Reference,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &models.TransactionPage{Transactions: views, NextCursor: nextCursor}, nil
+}
+
+// GetStatement returns the header of an account's statement: the account
+// details and its opening and closing balances for the period.
+func (s *TransactionQueryService) GetStatement(ctx context.Context, q cqrs.GetStatementQuery) (*statement.Header, error) {
+	account, err := s.accountRepo.GetAccount(ctx, q.AccountNumber)
+	if err != nil {
+		return nil, fmt.Errorf("account not found")
+	}
+	if !q.Principal.CanAccess(account.UserID, middleware.ScopeTransactionsRead) {
+		return nil, fmt.Errorf("forbidden")
+	}
+	opening, closing, err := s.readRepo.StatementBalances(ctx, q.AccountNumber, q.From, q.To)
+	if err != nil {
+		return nil, err
+	}
+	return &statement.Header{
+		AccountNumber:  account.AccountNumber,
+		SortCode:       account.SortCode,
+		AccountName:    account.Name,
+		Currency:       account.Currency,
+		From:           q.From,
+		To:             q.To,
+		OpeningBalance: opening,
+		ClosingBalance: closing,
+		GeneratedAt:    time.Now().UTC(),
+	}, nil
+}
+
+// StreamStatement calls emit for each posted transaction in the statement period,
+// oldest first. Callers must have checked access with GetStatement.
+func (s *TransactionQueryService) StreamStatement(ctx context.Context, q cqrs.GetStatementQuery, emit func(models.TransactionView) error) error {
+	return s.readRepo.StreamPostedByAccountNumber(ctx, q.AccountNumber, q.From, q.To, emit)
 }
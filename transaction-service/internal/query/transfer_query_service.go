@@ -0,0 +1,40 @@
+package query
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eaglebank/shared/cqrs"
+	"github.com/eaglebank/shared/middleware"
+	"github.com/eaglebank/shared/models"
+	"github.com/eaglebank/transaction-service/internal/repository"
+)
+
+// TransferQueryService serves transfer reads to the owner of the source
+// account and to staff holding middleware.ScopeTransactionsRead.
+type TransferQueryService struct {
+	transferRepo *repository.TransferReadRepository
+	accountRepo  *repository.AccountRepository
+}
+
+func NewTransferQueryService(transferRepo *repository.TransferReadRepository, accountRepo *repository.AccountRepository) *TransferQueryService {
+	return &TransferQueryService{transferRepo: transferRepo, accountRepo: accountRepo}
+}
+
+func (s *TransferQueryService) GetTransfer(ctx context.Context, q cqrs.GetTransferQuery) (*models.Transfer, error) {
+	account, err := s.accountRepo.GetAccount(ctx, q.AccountNumber)
+	if err != nil {
+		return nil, fmt.Errorf("account not found")
+	}
+	if !q.Principal.CanAccess(account.UserID, middleware.ScopeTransactionsRead) {
+		return nil, fmt.Errorf("forbidden")
+	}
+	transfer, err := s.transferRepo.GetByID(ctx, q.TransferID)
+	if err != nil {
+		return nil, err
+	}
+	if transfer.FromAccountNumber != q.AccountNumber {
+		return nil, fmt.Errorf("transfer not found")
+	}
+	return transfer, nil
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/eaglebank/shared/models"
+	"github.com/eaglebank/shared/money"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -16,10 +18,18 @@ type AccountRepository struct {
 }
 
 type Account struct {
-	AccountNumber string  `json:"accountNumber"`
-	UserID        string  `json:"userId"`
-	Balance       float64 `json:"balance"`
-	Currency      string  `json:"currency"`
+	AccountNumber string       `json:"accountNumber"`
+	UserID        string       `json:"userId"`
+	SortCode      string       `json:"sortCode"`
+	Name          string       `json:"name"`
+	Balance       money.Amount `json:"balance"`
+	Currency      string       `json:"currency"`
+	Status        string       `json:"status"`
+}
+
+// Frozen reports whether staff have frozen the account.
+func (a *Account) Frozen() bool {
+	return a.Status == models.AccountStatusFrozen
 }
 
 func NewAccountRepository(db interface{}, redis *redis.Client) *AccountRepository {
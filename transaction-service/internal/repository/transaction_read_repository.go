@@ -3,10 +3,17 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/eaglebank/shared/ledger"
 	"github.com/eaglebank/shared/models"
+	"github.com/eaglebank/shared/money"
 	sharedredis "github.com/eaglebank/shared/redis"
+	"github.com/lib/pq"
 	goredis "github.com/redis/go-redis/v9"
 )
 
@@ -22,7 +29,7 @@ type TransactionReadRepository struct {
 func NewTransactionReadRepository(db *sql.DB, redisClient *goredis.Client) *TransactionReadRepository {
 	return &TransactionReadRepository{
 		db:    db,
-		cache: sharedredis.NewViewCache[models.TransactionView](redisClient, 0),
+		cache: sharedredis.NewViewCache[models.TransactionView](redisClient, "transaction", 0),
 	}
 }
 
@@ -35,17 +42,17 @@ func (r *TransactionReadRepository) GetByID(ctx context.Context, id, accountNumb
 
 	// Fallback: PostgreSQL
 	query := `
-		SELECT id, account_number, user_id, amount, currency, type, reference, created_at
+		SELECT id, account_number, user_id, amount, currency, type, reference, transfer_id, status, failure_reason, created_at
 		FROM transactions
 		WHERE id = $1 AND account_number = $2
 	`
 	var view models.TransactionView
-	var reference sql.NullString
+	var reference, transferID, failureReason sql.NullString
 
 	pgErr := r.db.QueryRow(query, id, accountNumber).Scan(
 		&view.ID, &view.AccountNumber, &view.UserID,
 		&view.Amount, &view.Currency, &view.Type,
-		&reference, &view.CreatedAt,
+		&reference, &transferID, &view.Status, &failureReason, &view.CreatedAt,
 	)
 	if pgErr == sql.ErrNoRows {
 		return nil, fmt.Errorf("transaction not found")
@@ -56,49 +63,206 @@ func (r *TransactionReadRepository) GetByID(ctx context.Context, id, accountNumb
 	if reference.Valid {
 		view.Reference = reference.String
 	}
+	view.TransferID = transferID.String
+	view.FailureReason = failureReason.String
 
 	// Warm the cache
 	r.CacheTransactionView(ctx, &view)
 	return &view, nil
 }
 
-// ListByAccountNumber returns all TransactionViews for an account from PostgreSQL.
-func (r *TransactionReadRepository) ListByAccountNumber(ctx context.Context, accountNumber string) ([]models.TransactionView, error) {
-	query := `
-		SELECT id, account_number, user_id, amount, currency, type, reference, created_at
+// TransactionFilter narrows and pages a transaction listing. Zero-valued
+// fields are not applied.
+type TransactionFilter struct {
+	Limit     int
+	Cursor    string
+	Ascending bool
+	From      time.Time
+	To        time.Time
+	Type      string
+	MinAmount *money.Amount
+	MaxAmount *money.Amount
+	Reference string
+}
+
+// listCursor is the keyset position after the last row of a page. It is
+// handed to clients base64-encoded and treated as opaque by them.
+type listCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+}
+
+func encodeCursor(view models.TransactionView) string {
+	data, _ := json.Marshal(listCursor{CreatedAt: view.CreatedAt, ID: view.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(cursor string) (*listCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	var c listCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	return &c, nil
+}
+
+// ListByAccountNumber returns one page of TransactionViews for an account from
+// PostgreSQL, ordered by (created_at, id), plus the cursor for the next page.
+func (r *TransactionReadRepository) ListByAccountNumber(ctx context.Context, accountNumber string, filter TransactionFilter) ([]models.TransactionView, string, error) {
+	conditions := []string{"account_number = $1"}
+	args := []any{accountNumber}
+	where := func(format string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(format, len(args)))
+	}
+
+	direction, keyset := "DESC", "<"
+	if filter.Ascending {
+		direction, keyset = "ASC", ">"
+	}
+	if filter.Cursor != "" {
+		cursor, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		args = append(args, cursor.CreatedAt, cursor.ID)
+		conditions = append(conditions, fmt.Sprintf("(created_at, id) %s ($%d, $%d)", keyset, len(args)-1, len(args)))
+	}
+	if !filter.From.IsZero() {
+		where("created_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		where("created_at < $%d", filter.To)
+	}
+	if filter.Type != "" {
+		where("type = $%d", filter.Type)
+	}
+	if filter.MinAmount != nil {
+		where("amount >= $%d", *filter.MinAmount)
+	}
+	if filter.MaxAmount != nil {
+		where("amount <= $%d", *filter.MaxAmount)
+	}
+	if filter.Reference != "" {
+		where(`reference ILIKE $%d ESCAPE '\'`, "%"+likeEscaper.Replace(filter.Reference)+"%")
+	}
+	args = append(args, filter.Limit+1)
+
+	query := fmt.Sprintf(`
+		SELECT id, account_number, user_id, amount, currency, type, reference, transfer_id, status, failure_reason, created_at
 		FROM transactions
-		WHERE account_number = $1
-		ORDER BY created_at DESC
-	`
-	rows, err := r.db.Query(query, accountNumber)
+		WHERE %s
+		ORDER BY created_at %s, id %s
+		LIMIT $%d
+	`, strings.Join(conditions, " AND "), direction, direction, len(args))
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list transactions: %w", err)
+		return nil, "", fmt.Errorf("failed to list transactions: %w", err)
 	}
 	defer rows.Close()
 
-	var views []models.TransactionView
+	views := []models.TransactionView{}
 	for rows.Next() {
 		var view models.TransactionView
-		var reference sql.NullString
+		var reference, transferID, failureReason sql.NullString
 
 		if err := rows.Scan(
 			&view.ID, &view.AccountNumber, &view.UserID,
 			&view.Amount, &view.Currency, &view.Type,
-			&reference, &view.CreatedAt,
+			&reference, &transferID, &view.Status, &failureReason, &view.CreatedAt,
 		); err != nil {
-			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+			return nil, "", fmt.Errorf("failed to scan transaction: %w", err)
 		}
 		if reference.Valid {
 			view.Reference = reference.String
 		}
+		view.TransferID = transferID.String
+		view.FailureReason = failureReason.String
 		views = append(views, view)
 	}
-	return views, nil
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to list transactions: %w", err)
+	}
+
+	nextCursor := ""
+	if len(views) > filter.Limit {
+		views = views[:filter.Limit]
+		nextCursor = encodeCursor(views[len(views)-1])
+	}
+	return views, nextCursor, nil
+}
+
+// StatementBalances returns the account's balance before from (opening) and
+// before to (closing), derived by signing each posted transaction. Pending
+// and rejected transactions have not moved money and are left out.
+func (r *TransactionReadRepository) StatementBalances(ctx context.Context, accountNumber string, from, to time.Time) (money.Amount, money.Amount, error) {
+	query := `
+		SELECT
+			COALESCE(SUM(CASE WHEN type = ANY($4) THEN -amount ELSE amount END) FILTER (WHERE created_at < $2), 0),
+			COALESCE(SUM(CASE WHEN type = ANY($4) THEN -amount ELSE amount END) FILTER (WHERE created_at < $3), 0)
+		FROM transactions
+		WHERE account_number = $1 AND created_at < $3 AND status = $5
+	`
+	var opening, closing money.Amount
+	err := r.db.QueryRowContext(ctx, query, accountNumber, from, to, pq.Array(ledger.DebitTypes()), models.TransactionPosted).Scan(&opening, &closing)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to compute statement balances: %w", err)
+	}
+	return opening, closing, nil
 }
 
+// StreamPostedByAccountNumber calls fn for each posted transaction in
+// [from, to), oldest first, reading rows from PostgreSQL one at a time rather
+// than loading the whole period. Iteration stops at the first error from fn.
+func (r *TransactionReadRepository) StreamPostedByAccountNumber(ctx context.Context, accountNumber string, from, to time.Time, fn func(models.TransactionView) error) error {
+	query := `
+		SELECT id, account_number, user_id, amount, currency, type, reference, transfer_id, status, failure_reason, created_at
+		FROM transactions
+		WHERE account_number = $1 AND created_at >= $2 AND created_at < $3 AND status = $4
+		ORDER BY created_at ASC, id ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, accountNumber, from, to, models.TransactionPosted)
+	if err != nil {
+		return fmt.Errorf("failed to stream transactions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var view models.TransactionView
+		var reference, transferID, failureReason sql.NullString
+		if err := rows.Scan(
+			&view.ID, &view.AccountNumber, &view.UserID,
+			&view.Amount, &view.Currency, &view.Type,
+			&reference, &transferID, &view.Status, &failureReason, &view.CreatedAt,
+		); err != nil {
+			return fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		view.Reference = reference.String
+		view.TransferID = transferID.String
+		view.FailureReason = failureReason.String
+		if err := fn(view); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
 // CacheTransactionView stores the read model for a transaction in Redis.
 // Called by the command service immediately after a successful Create.
 func (r *TransactionReadRepository) CacheTransactionView(ctx context.Context, view *models.TransactionView) {
 	cacheKey := fmt.Sprintf("%s%s:%s", transactionViewKeyPrefix, view.AccountNumber, view.ID)
 	r.cache.Set(ctx, cacheKey, view)
 }
+
+// InvalidateTransactionView removes a cached transaction view. Used when a
+// transfer leg that was never applied is withdrawn, or a transaction's status
+// changes.
+func (r *TransactionReadRepository) InvalidateTransactionView(ctx context.Context, accountNumber, id string) {
+	cacheKey := fmt.Sprintf("%s%s:%s", transactionViewKeyPrefix, accountNumber, id)
+	r.cache.Delete(ctx, cacheKey)
+}
@@ -1,9 +1,11 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 
+	"github.com/eaglebank/shared/events"
 	"github.com/eaglebank/shared/models"
 )
 
@@ -17,15 +19,50 @@ func NewTransactionWriteRepository(db *sql.DB) *TransactionWriteRepository {
 	return &TransactionWriteRepository{db: db}
 }
 
-func (r *TransactionWriteRepository) Create(transaction *models.Transaction) error {
+// Create inserts the transaction and its outbox events in a single SQL
+// transaction, so an event is queued if and only if the row is committed.
+func (r *TransactionWriteRepository) Create(ctx context.Context, transaction *models.Transaction, outbox ...events.OutboxEvent) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertTransaction(ctx, tx, transaction); err != nil {
+		return err
+	}
+	return commitWithOutbox(tx, outbox)
+}
+
+// SetOutcome moves a pending transaction to status (posted or rejected),
+// recording reason for a rejection. It reports false, writing nothing, if
+// the transaction is no longer pending or no longer exists.
+func (r *TransactionWriteRepository) SetOutcome(ctx context.Context, id, status, reason string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE transactions SET status = $2, failure_reason = $3
+		WHERE id = $1 AND status = $4`,
+		id, status, nullString(reason), models.TransactionPending,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to update transaction status: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	return rows > 0, nil
+}
+
+func insertTransaction(ctx context.Context, tx *sql.Tx, transaction *models.Transaction) error {
 	query := `
-		INSERT INTO transactions (id, account_number, user_id, amount, currency, type, reference, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO transactions (id, account_number, user_id, amount, currency, type, reference, transfer_id, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 	`
-	_, err := r.db.Exec(query,
+	_, err := tx.ExecContext(ctx, query,
 		transaction.ID, transaction.AccountNumber, transaction.UserID,
 		transaction.Amount, transaction.Currency, transaction.Type,
-		nullString(transaction.Reference), transaction.CreatedAt,
+		nullString(transaction.Reference), nullString(transaction.TransferID),
+		transaction.Status, transaction.CreatedAt,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create transaction: %w", err)
@@ -33,6 +70,17 @@ func (r *TransactionWriteRepository) Create(transaction *models.Transaction) err
 	return nil
 }
 
+// commitWithOutbox queues the outbox events on tx and commits it.
+func commitWithOutbox(tx *sql.Tx, outbox []events.OutboxEvent) error {
+	if err := events.WriteOutbox(tx, outbox...); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
 func nullString(s string) sql.NullString {
 	if s == "" {
 		return sql.NullString{Valid: false}
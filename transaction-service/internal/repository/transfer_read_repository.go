@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/eaglebank/shared/models"
+)
+
+// TransferReadRepository serves transfer reads. Transfers change state as the
+// saga progresses, so they are always read from PostgreSQL rather than cached.
+type TransferReadRepository struct {
+	db *sql.DB
+}
+
+func NewTransferReadRepository(db *sql.DB) *TransferReadRepository {
+	return &TransferReadRepository{db: db}
+}
+
+// GetByID returns a transfer by ID.
+func (r *TransferReadRepository) GetByID(ctx context.Context, id string) (*models.Transfer, error) {
+	return getTransfer(r.db, id)
+}
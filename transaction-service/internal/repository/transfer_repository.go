@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/eaglebank/shared/events"
+	"github.com/eaglebank/shared/models"
+)
+
+// TransferWriteRepository persists transfers and their saga state transitions.
+// Every transition is guarded by the expected current status, so replaying a
+// saga event after the transfer has moved on is a no-op (applied=false).
+type TransferWriteRepository struct {
+	db *sql.DB
+}
+
+func NewTransferWriteRepository(db *sql.DB) *TransferWriteRepository {
+	return &TransferWriteRepository{db: db}
+}
+
+// Create inserts the transfer, its debit leg and the outbox events in one SQL transaction.
+func (r *TransferWriteRepository) Create(ctx context.Context, transfer *models.Transfer, debit *models.Transaction, outbox ...events.OutboxEvent) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO transfers (id, from_account_number, to_account_number, user_id, to_user_id,
+			amount, currency, reference, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+	_, err = tx.ExecContext(ctx, query,
+		transfer.ID, transfer.FromAccountNumber, transfer.ToAccountNumber, transfer.UserID, transfer.ToUserID,
+		transfer.Amount, transfer.Currency, nullString(transfer.Reference), transfer.Status,
+		transfer.CreatedAt, transfer.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create transfer: %w", err)
+	}
+	if err := insertTransaction(ctx, tx, debit); err != nil {
+		return err
+	}
+	return commitWithOutbox(tx, outbox)
+}
+
+// GetByID fetches a transfer from PostgreSQL.
+func (r *TransferWriteRepository) GetByID(id string) (*models.Transfer, error) {
+	return getTransfer(r.db, id)
+}
+
+// RecordDebit moves a pending transfer to debited and inserts the credit leg.
+func (r *TransferWriteRepository) RecordDebit(ctx context.Context, transferID string, credit *models.Transaction, outbox ...events.OutboxEvent) (bool, error) {
+	return r.advance(ctx, transferID, models.TransferPending, models.TransferDebited, "", "", credit, outbox)
+}
+
+// Fail marks a pending transfer failed and its debit leg, which was never
+// applied to the balance, rejected with reason.
+func (r *TransferWriteRepository) Fail(ctx context.Context, transferID, debitTransactionID, reason string) (bool, error) {
+	return r.advance(ctx, transferID, models.TransferPending, models.TransferFailed, reason, debitTransactionID, nil, nil)
+}
+
+// Complete marks a debited transfer completed once its credit leg is applied.
+func (r *TransferWriteRepository) Complete(ctx context.Context, transferID string) (bool, error) {
+	return r.advance(ctx, transferID, models.TransferDebited, models.TransferCompleted, "", "", nil, nil)
+}
+
+// Compensate starts reversing a debited transfer whose credit leg was
+// rejected: the credit leg is marked rejected with reason and a reversal
+// crediting the source account is inserted.
+func (r *TransferWriteRepository) Compensate(ctx context.Context, transferID, creditTransactionID, reason string, reversal *models.Transaction, outbox ...events.OutboxEvent) (bool, error) {
+	return r.advance(ctx, transferID, models.TransferDebited, models.TransferReversing, reason, creditTransactionID, reversal, outbox)
+}
+
+// CompleteReversal marks a reversing transfer reversed.
+func (r *TransferWriteRepository) CompleteReversal(ctx context.Context, transferID string) (bool, error) {
+	return r.advance(ctx, transferID, models.TransferReversing, models.TransferReversed, "", "", nil, nil)
+}
+
+// FailReversal marks a reversing transfer whose reversal was rejected. Money
+// is held on neither account at this point and needs manual resolution.
+func (r *TransferWriteRepository) FailReversal(ctx context.Context, transferID, reason string) (bool, error) {
+	return r.advance(ctx, transferID, models.TransferReversing, models.TransferReversalFailed, reason, "", nil, nil)
+}
+
+// advance performs one guarded saga transition: status from -> to, optionally
+// rejecting an unapplied leg and inserting the next one, plus outbox events.
+// A rejected leg is kept so the transfer's history stays complete.
+func (r *TransferWriteRepository) advance(
+	ctx context.Context,
+	transferID, from, to, reason, rejectTransactionID string,
+	insert *models.Transaction,
+	outbox []events.OutboxEvent,
+) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE transfers
+		SET status = $3, failure_reason = COALESCE($4, failure_reason), updated_at = NOW()
+		WHERE id = $1 AND status = $2
+	`
+	result, err := tx.ExecContext(ctx, query, transferID, from, to, nullString(reason))
+	if err != nil {
+		return false, fmt.Errorf("failed to update transfer: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rows == 0 {
+		return false, nil
+	}
+
+	// The leg may already be rejected by its transaction.rejected event.
+	if rejectTransactionID != "" {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE transactions SET status = $3, failure_reason = $4
+			WHERE id = $1 AND transfer_id = $2 AND status = $5`,
+			rejectTransactionID, transferID, models.TransactionRejected, nullString(reason), models.TransactionPending,
+		)
+		if err != nil {
+			return false, fmt.Errorf("failed to reject transfer leg: %w", err)
+		}
+	}
+	if insert != nil {
+		if err := insertTransaction(ctx, tx, insert); err != nil {
+			return false, err
+		}
+	}
+	if err := commitWithOutbox(tx, outbox); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// getTransfer is shared by the write and read repositories.
+func getTransfer(db *sql.DB, id string) (*models.Transfer, error) {
+	query := `
+		SELECT id, from_account_number, to_account_number, user_id, to_user_id,
+			   amount, currency, reference, status, failure_reason, created_at, updated_at
+		FROM transfers
+		WHERE id = $1
+	`
+	var t models.Transfer
+	var reference, reason sql.NullString
+	err := db.QueryRow(query, id).Scan(
+		&t.ID, &t.FromAccountNumber, &t.ToAccountNumber, &t.UserID, &t.ToUserID,
+		&t.Amount, &t.Currency, &reference, &t.Status, &reason, &t.CreatedAt, &t.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("transfer not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transfer: %w", err)
+	}
+	t.Reference = reference.String
+	t.FailureReason = reason.String
+	return &t, nil
+}
@@ -0,0 +1,234 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eaglebank/shared/events"
+	"github.com/eaglebank/shared/ledger"
+	"github.com/eaglebank/shared/models"
+	"github.com/eaglebank/shared/money"
+)
+
+// fakeSagaStore holds the transfers and transactions rows advance touches.
+// It understands only the statements the saga transitions issue; a rolled
+// back transaction restores the snapshot taken when it began.
+type fakeSagaStore struct {
+	transfers    map[string]*fakeTransferRow
+	transactions map[string]*fakeTransactionRow
+	outbox       int
+}
+
+type fakeTransferRow struct{ status, reason string }
+
+type fakeTransactionRow struct{ transferID, status, reason string }
+
+func (s *fakeSagaStore) snapshot() fakeSagaStore {
+	snap := fakeSagaStore{
+		transfers:    map[string]*fakeTransferRow{},
+		transactions: map[string]*fakeTransactionRow{},
+		outbox:       s.outbox,
+	}
+	for id, row := range s.transfers {
+		copied := *row
+		snap.transfers[id] = &copied
+	}
+	for id, row := range s.transactions {
+		copied := *row
+		snap.transactions[id] = &copied
+	}
+	return snap
+}
+
+type fakeSagaConnector struct{ store *fakeSagaStore }
+
+func (c fakeSagaConnector) Connect(context.Context) (driver.Conn, error) {
+	return &fakeSagaConn{store: c.store}, nil
+}
+
+func (c fakeSagaConnector) Driver() driver.Driver { return nil }
+
+type fakeSagaConn struct {
+	store    *fakeSagaStore
+	rollback *fakeSagaStore
+}
+
+func (c *fakeSagaConn) Prepare(string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("fake saga store: prepared statements are not supported")
+}
+
+func (c *fakeSagaConn) Close() error { return nil }
+
+func (c *fakeSagaConn) Begin() (driver.Tx, error) {
+	snap := c.store.snapshot()
+	c.rollback = &snap
+	return fakeSagaTx{conn: c}, nil
+}
+
+type fakeSagaTx struct{ conn *fakeSagaConn }
+
+func (tx fakeSagaTx) Commit() error {
+	tx.conn.rollback = nil
+	return nil
+}
+
+func (tx fakeSagaTx) Rollback() error {
+	if tx.conn.rollback != nil {
+		*tx.conn.store = *tx.conn.rollback
+		tx.conn.rollback = nil
+	}
+	return nil
+}
+
+func stringArg(v driver.Value) string {
+	s, _ := v.(string)
+	return s
+}
+
+func (c *fakeSagaConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	store := c.store
+	switch {
+	case strings.Contains(query, "UPDATE transfers"):
+		row := store.transfers[stringArg(args[0].Value)]
+		if row == nil || row.status != stringArg(args[1].Value) {
+			return driver.RowsAffected(0), nil
+		}
+		row.status = stringArg(args[2].Value)
+		if reason := stringArg(args[3].Value); reason != "" {
+			row.reason = reason
+		}
+		return driver.RowsAffected(1), nil
+	case strings.Contains(query, "UPDATE transactions"):
+		row := store.transactions[stringArg(args[0].Value)]
+		if row == nil || row.transferID != stringArg(args[1].Value) || row.status != stringArg(args[4].Value) {
+			return driver.RowsAffected(0), nil
+		}
+		row.status, row.reason = stringArg(args[2].Value), stringArg(args[3].Value)
+		return driver.RowsAffected(1), nil
+	case strings.Contains(query, "INSERT INTO transactions"):
+		store.transactions[stringArg(args[0].Value)] = &fakeTransactionRow{
+			transferID: stringArg(args[7].Value),
+			status:     stringArg(args[8].Value),
+		}
+		return driver.RowsAffected(1), nil
+	case strings.Contains(query, "INSERT INTO outbox"):
+		store.outbox++
+		return driver.RowsAffected(1), nil
+	}
+	return nil, fmt.Errorf("fake saga store: unexpected statement %q", query)
+}
+
+func newFakeSagaRepository(t *testing.T, store *fakeSagaStore) *TransferWriteRepository {
+	t.Helper()
+	db := sql.OpenDB(fakeSagaConnector{store: store})
+	t.Cleanup(func() { db.Close() })
+	return NewTransferWriteRepository(db)
+}
+
+func TestTransferRejectedLegIsKept(t *testing.T) {
+	ctx := context.Background()
+	reversal := &models.Transaction{
+		ID: "tan-reversal", AccountNumber: "01234567", UserID: "usr-001",
+		Amount: money.MustParse("25.00"), Currency: "GBP", Type: ledger.TransferReversal,
+		TransferID: "trf-001", Status: models.TransactionPending, CreatedAt: time.Now().UTC(),
+	}
+	reversed := events.NewOutboxEvent(ctx, events.TransactionEventsStream, events.TransactionCreated, map[string]string{})
+
+	tests := []struct {
+		name             string
+		transferStatus   string
+		legStatus        string
+		legReason        string
+		advance          func(*TransferWriteRepository) (bool, error)
+		expectedAdvanced bool
+		expectedTransfer string
+		expectedLeg      string
+		expectedReason   string
+		expectedReversal bool
+	}{
+		{
+			name:           "failed debit leg is rejected with the reason",
+			transferStatus: models.TransferPending,
+			legStatus:      models.TransactionPending,
+			advance: func(r *TransferWriteRepository) (bool, error) {
+				return r.Fail(ctx, "trf-001", "tan-leg", "insufficient funds")
+			},
+			expectedAdvanced: true,
+			expectedTransfer: models.TransferFailed,
+			expectedLeg:      models.TransactionRejected,
+			expectedReason:   "insufficient funds",
+		},
+		{
+			name:           "leg already rejected by its outcome event keeps that reason",
+			transferStatus: models.TransferPending,
+			legStatus:      models.TransactionRejected,
+			legReason:      "account not found",
+			advance: func(r *TransferWriteRepository) (bool, error) {
+				return r.Fail(ctx, "trf-001", "tan-leg", "account missing")
+			},
+			expectedAdvanced: true,
+			expectedTransfer: models.TransferFailed,
+			expectedLeg:      models.TransactionRejected,
+			expectedReason:   "account not found",
+		},
+		{
+			name:           "rejected credit leg is kept alongside the reversal",
+			transferStatus: models.TransferDebited,
+			legStatus:      models.TransactionPending,
+			advance: func(r *TransferWriteRepository) (bool, error) {
+				return r.Compensate(ctx, "trf-001", "tan-leg", "account frozen", reversal, reversed)
+			},
+			expectedAdvanced: true,
+			expectedTransfer: models.TransferReversing,
+			expectedLeg:      models.TransactionRejected,
+			expectedReason:   "account frozen",
+			expectedReversal: true,
+		},
+		{
+			name:           "replayed failure leaves the leg untouched",
+			transferStatus: models.TransferFailed,
+			legStatus:      models.TransactionPending,
+			advance: func(r *TransferWriteRepository) (bool, error) {
+				return r.Fail(ctx, "trf-001", "tan-leg", "insufficient funds")
+			},
+			expectedAdvanced: false,
+			expectedTransfer: models.TransferFailed,
+			expectedLeg:      models.TransactionPending,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeSagaStore{
+				transfers: map[string]*fakeTransferRow{"trf-001": {status: tt.transferStatus}},
+				transactions: map[string]*fakeTransactionRow{
+					"tan-leg": {transferID: "trf-001", status: tt.legStatus, reason: tt.legReason},
+				},
+			}
+			advanced, err := tt.advance(newFakeSagaRepository(t, store))
+			if err != nil {
+				t.Fatalf("[%s] unexpected error: %v", tt.name, err)
+			}
+			if advanced != tt.expectedAdvanced {
+				t.Errorf("[%s] expected advanced=%v got %v", tt.name, tt.expectedAdvanced, advanced)
+			}
+			if got := store.transfers["trf-001"].status; got != tt.expectedTransfer {
+				t.Errorf("[%s] expected transfer %s got %s", tt.name, tt.expectedTransfer, got)
+			}
+			leg := store.transactions["tan-leg"]
+			if leg == nil {
+				t.Fatalf("[%s] expected the leg row to be kept", tt.name)
+			}
+			if leg.status != tt.expectedLeg || leg.reason != tt.expectedReason {
+				t.Errorf("[%s] expected leg %s %q got %s %q", tt.name, tt.expectedLeg, tt.expectedReason, leg.status, leg.reason)
+			}
+			if _, ok := store.transactions[reversal.ID]; ok != tt.expectedReversal {
+				t.Errorf("[%s] expected reversal inserted=%v", tt.name, tt.expectedReversal)
+			}
+		})
+	}
+}
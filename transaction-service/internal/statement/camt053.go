@@ -0,0 +1,131 @@
+package statement
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/eaglebank/shared/money"
+)
+
+const camtNamespace = "urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"
+
+// camtWriter emits an ISO 20022 camt.053.001.02 bank-to-customer statement
+// with opening (OPBD) and closing (CLBD) booked balances. camt.053 has no
+// per-entry balance element, so the running balance is given in each entry's
+// AddtlNtryInf.
+type camtWriter struct {
+	w io.Writer
+	x *xmlStream
+}
+
+type camtAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type camtDateTime struct {
+	DateTime string `xml:"DtTm"`
+}
+
+type camtBalance struct {
+	Type   string       `xml:"Tp>CdOrPrtry>Cd"`
+	Amount camtAmount   `xml:"Amt"`
+	Sign   string       `xml:"CdtDbtInd"`
+	Date   camtDateTime `xml:"Dt"`
+}
+
+type camtEntry struct {
+	Reference      string       `xml:"NtryRef"`
+	Amount         camtAmount   `xml:"Amt"`
+	Sign           string       `xml:"CdtDbtInd"`
+	Status         string       `xml:"Sts"`
+	BookingDate    camtDateTime `xml:"BookgDt"`
+	ValueDate      camtDateTime `xml:"ValDt"`
+	BankTxCode     string       `xml:"BkTxCd>Prtry>Cd"`
+	EndToEndID     string       `xml:"NtryDtls>TxDtls>Refs>EndToEndId"`
+	Remittance     string       `xml:"NtryDtls>TxDtls>RmtInf>Ustrd,omitempty"`
+	AdditionalInfo string       `xml:"AddtlNtryInf"`
+}
+
+type camtAccount struct {
+	ID       string `xml:"Id>Othr>Id"`
+	Scheme   string `xml:"Id>Othr>SchmeNm>Prtry"`
+	Currency string `xml:"Ccy"`
+	Name     string `xml:"Nm,omitempty"`
+}
+
+func newCamtWriter(w io.Writer) *camtWriter {
+	return &camtWriter{w: w, x: newXMLStream(w)}
+}
+
+func camtTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05Z")
+}
+
+// camtSigned splits an amount into its absolute value and CRDT/DBIT indicator.
+func camtSigned(a money.Amount, currency string) (camtAmount, string) {
+	if a.IsNegative() {
+		return camtAmount{Currency: currency, Value: a.Neg().String()}, "DBIT"
+	}
+	return camtAmount{Currency: currency, Value: a.String()}, "CRDT"
+}
+
+func (c *camtWriter) balance(code string, amount money.Amount, currency string, at time.Time) {
+	amt, sign := camtSigned(amount, currency)
+	c.x.element("Bal", camtBalance{Type: code, Amount: amt, Sign: sign, Date: camtDateTime{camtTime(at)}})
+}
+
+func (c *camtWriter) Begin(h Header) error {
+	if _, err := io.WriteString(c.w, xml.Header); err != nil {
+		return err
+	}
+	id := "STMT-" + h.AccountNumber + "-" + h.From.UTC().Format("20060102") + "-" + h.To.Add(-time.Nanosecond).UTC().Format("20060102")
+
+	c.x.start("Document", xml.Attr{Name: xml.Name{Local: "xmlns"}, Value: camtNamespace})
+	c.x.start("BkToCstmrStmt")
+	c.x.start("GrpHdr")
+	c.x.element("MsgId", id)
+	c.x.element("CreDtTm", camtTime(h.GeneratedAt))
+	c.x.end() // GrpHdr
+	c.x.start("Stmt")
+	c.x.element("Id", id)
+	c.x.element("CreDtTm", camtTime(h.GeneratedAt))
+	c.x.element("FrToDt", struct {
+		From string `xml:"FrDtTm"`
+		To   string `xml:"ToDtTm"`
+	}{camtTime(h.From), camtTime(h.To)})
+	c.x.element("Acct", camtAccount{
+		ID:       strings.ReplaceAll(h.SortCode, "-", "") + h.AccountNumber,
+		Scheme:   "SortCodeAccountNumber",
+		Currency: h.Currency,
+		Name:     h.AccountName,
+	})
+	c.balance("OPBD", h.OpeningBalance, h.Currency, h.From)
+	c.balance("CLBD", h.ClosingBalance, h.Currency, h.To)
+	return c.x.flush()
+}
+
+func (c *camtWriter) Line(l Line) error {
+	t := l.Transaction
+	amt, sign := camtSigned(l.Amount, t.Currency)
+	booked := camtDateTime{camtTime(t.CreatedAt)}
+	c.x.element("Ntry", camtEntry{
+		Reference:      t.ID,
+		Amount:         amt,
+		Sign:           sign,
+		Status:         "BOOK",
+		BookingDate:    booked,
+		ValueDate:      booked,
+		BankTxCode:     t.Type,
+		EndToEndID:     t.ID,
+		Remittance:     t.Reference,
+		AdditionalInfo: "Balance after entry: " + l.Balance.String(),
+	})
+	return c.x.flush()
+}
+
+func (c *camtWriter) End() error {
+	return c.x.closeAll()
+}
@@ -0,0 +1,65 @@
+package statement
+
+import (
+	"encoding/csv"
+	"io"
+	"time"
+)
+
+// csvFlushEvery bounds how many rows are buffered before being sent.
+const csvFlushEvery = 100
+
+// csvWriter emits a block of account details followed by one row per
+// transaction with its running balance.
+type csvWriter struct {
+	w    *csv.Writer
+	rows int
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (c *csvWriter) Begin(h Header) error {
+	records := [][]string{
+		{"Account Number", h.AccountNumber},
+		{"Sort Code", h.SortCode},
+		{"Account Name", h.AccountName},
+		{"Currency", h.Currency},
+		{"Period Start", h.From.Format(time.RFC3339)},
+		{"Period End", h.To.Format(time.RFC3339)},
+		{"Opening Balance", h.OpeningBalance.String()},
+		{"Closing Balance", h.ClosingBalance.String()},
+		{},
+		{"Date", "Transaction ID", "Type", "Reference", "Amount", "Balance"},
+	}
+	if err := c.w.WriteAll(records); err != nil {
+		return err
+	}
+	return c.w.Error()
+}
+
+func (c *csvWriter) Line(l Line) error {
+	t := l.Transaction
+	if err := c.w.Write([]string{
+		t.CreatedAt.UTC().Format(time.RFC3339),
+		t.ID,
+		t.Type,
+		t.Reference,
+		l.Amount.String(),
+		l.Balance.String(),
+	}); err != nil {
+		return err
+	}
+	c.rows++
+	if c.rows%csvFlushEvery == 0 {
+		c.w.Flush()
+		return c.w.Error()
+	}
+	return nil
+}
+
+func (c *csvWriter) End() error {
+	c.w.Flush()
+	return c.w.Error()
+}
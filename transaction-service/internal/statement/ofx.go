@@ -0,0 +1,112 @@
+package statement
+
+import (
+	"io"
+	"strings"
+	"time"
+)
+
+const ofxHeader = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+`
+
+// ofxWriter emits an OFX 2.2 bank statement response. OFX has no
+// per-transaction balance element, so importers reconcile the lines against
+// LEDGERBAL, the closing balance.
+type ofxWriter struct {
+	w      io.Writer
+	x      *xmlStream
+	header Header
+}
+
+type ofxStatus struct {
+	Code     int    `xml:"CODE"`
+	Severity string `xml:"SEVERITY"`
+}
+
+type ofxAccount struct {
+	BankID   string `xml:"BANKID"`
+	AcctID   string `xml:"ACCTID"`
+	AcctType string `xml:"ACCTTYPE"`
+}
+
+type ofxTransaction struct {
+	Type   string `xml:"TRNTYPE"`
+	Posted string `xml:"DTPOSTED"`
+	Amount string `xml:"TRNAMT"`
+	FITID  string `xml:"FITID"`
+	Name   string `xml:"NAME"`
+	Memo   string `xml:"MEMO,omitempty"`
+}
+
+type ofxBalance struct {
+	Amount string `xml:"BALAMT"`
+	AsOf   string `xml:"DTASOF"`
+}
+
+func newOFXWriter(w io.Writer) *ofxWriter {
+	return &ofxWriter{w: w, x: newXMLStream(w)}
+}
+
+func ofxTime(t time.Time) string {
+	return t.UTC().Format("20060102150405.000") + "[0:GMT]"
+}
+
+func (o *ofxWriter) Begin(h Header) error {
+	o.header = h
+	if _, err := io.WriteString(o.w, ofxHeader); err != nil {
+		return err
+	}
+	ok := ofxStatus{Code: 0, Severity: "INFO"}
+
+	o.x.start("OFX")
+	o.x.start("SIGNONMSGSRSV1")
+	o.x.start("SONRS")
+	o.x.element("STATUS", ok)
+	o.x.element("DTSERVER", ofxTime(h.GeneratedAt))
+	o.x.element("LANGUAGE", "ENG")
+	o.x.end() // SONRS
+	o.x.end() // SIGNONMSGSRSV1
+
+	o.x.start("BANKMSGSRSV1")
+	o.x.start("STMTTRNRS")
+	o.x.element("TRNUID", "0")
+	o.x.element("STATUS", ok)
+	o.x.start("STMTRS")
+	o.x.element("CURDEF", h.Currency)
+	o.x.element("BANKACCTFROM", ofxAccount{
+		BankID:   strings.ReplaceAll(h.SortCode, "-", ""),
+		AcctID:   h.AccountNumber,
+		AcctType: "CHECKING",
+	})
+	o.x.start("BANKTRANLIST")
+	o.x.element("DTSTART", ofxTime(h.From))
+	o.x.element("DTEND", ofxTime(h.To))
+	return o.x.flush()
+}
+
+func (o *ofxWriter) Line(l Line) error {
+	t := l.Transaction
+	trnType := "CREDIT"
+	if l.Amount.IsNegative() {
+		trnType = "DEBIT"
+	}
+	o.x.element("STMTTRN", ofxTransaction{
+		Type:   trnType,
+		Posted: ofxTime(t.CreatedAt),
+		Amount: l.Amount.String(),
+		FITID:  t.ID,
+		Name:   t.Type,
+		Memo:   t.Reference,
+	})
+	return o.x.flush()
+}
+
+func (o *ofxWriter) End() error {
+	o.x.end() // BANKTRANLIST
+	o.x.element("LEDGERBAL", ofxBalance{
+		Amount: o.header.ClosingBalance.String(),
+		AsOf:   ofxTime(o.header.To),
+	})
+	return o.x.closeAll()
+}
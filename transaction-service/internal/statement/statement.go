@@ -0,0 +1,103 @@
+// Package statement renders account statements as CSV, OFX or ISO 20022
+// camt.053. Lines are written as they are produced, so a statement for a long
+// period is streamed to the client rather than built in memory.
+package statement
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/eaglebank/shared/ledger"
+	"github.com/eaglebank/shared/models"
+	"github.com/eaglebank/shared/money"
+)
+
+// Supported formats.
+const (
+	CSV     = "csv"
+	OFX     = "ofx"
+	Camt053 = "camt053"
+)
+
+// Header describes the account and period a statement covers. To is exclusive.
+type Header struct {
+	AccountNumber  string
+	SortCode       string
+	AccountName    string
+	Currency       string
+	From           time.Time
+	To             time.Time
+	OpeningBalance money.Amount
+	ClosingBalance money.Amount
+	GeneratedAt    time.Time
+}
+
+// Line is one transaction with its signed amount and the balance after it.
+type Line struct {
+	Transaction models.TransactionView
+	// Amount is positive for credits and negative for debits.
+	Amount  money.Amount
+	Balance money.Amount
+}
+
+// Writer renders one statement format.
+type Writer interface {
+	Begin(Header) error
+	Line(Line) error
+	End() error
+}
+
+// ContentType returns the MIME type and file extension for format.
+func ContentType(format string) (string, string) {
+	switch format {
+	case OFX:
+		return "application/x-ofx", "ofx"
+	case Camt053:
+		return "application/xml", "xml"
+	default:
+		return "text/csv; charset=utf-8", "csv"
+	}
+}
+
+func newWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case CSV:
+		return newCSVWriter(w), nil
+	case OFX:
+		return newOFXWriter(w), nil
+	case Camt053:
+		return newCamtWriter(w), nil
+	default:
+		return nil, fmt.Errorf("unsupported statement format %q", format)
+	}
+}
+
+// Render writes a statement in format to w. lines is called once and must
+// emit the period's transactions oldest first; the running balance is
+// computed from the header's opening balance as they arrive.
+func Render(w io.Writer, format string, header Header, lines func(emit func(models.TransactionView) error) error) error {
+	sw, err := newWriter(format, w)
+	if err != nil {
+		return err
+	}
+	if err := sw.Begin(header); err != nil {
+		return err
+	}
+	balance := header.OpeningBalance
+	err = lines(func(t models.TransactionView) error {
+		amount := t.Amount
+		if ledger.IsDebit(t.Type) {
+			amount = amount.Neg()
+		}
+		var err error
+		if balance, err = balance.Add(amount); err != nil {
+			return err
+		}
+		return sw.Line(Line{Transaction: t, Amount: amount, Balance: balance})
+	})
+	if err != nil {
+		return err
+	}
+	return sw.End()
+}
@@ -0,0 +1,155 @@
+package statement
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eaglebank/shared/models"
+	"github.com/eaglebank/shared/money"
+)
+
+var testHeader = Header{
+	AccountNumber:  "01234567",
+	SortCode:       "10-10-10",
+	AccountName:    `Smith & Sons "Main"`,
+	Currency:       "GBP",
+	From:           time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	To:             time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+	OpeningBalance: money.MustParse("100.00"),
+	ClosingBalance: money.MustParse("130.25"),
+	GeneratedAt:    time.Date(2024, 2, 1, 9, 30, 0, 0, time.UTC),
+}
+
+var testTransactions = []models.TransactionView{
+	{
+		ID:        "tan-1",
+		Amount:    money.MustParse("50.50"),
+		Currency:  "GBP",
+		Type:      "deposit",
+		Reference: `Rent, "Jan" <flat 2> & bills`,
+		CreatedAt: time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC),
+	},
+	{
+		ID:        "tan-2",
+		Amount:    money.MustParse("20.25"),
+		Currency:  "GBP",
+		Type:      "withdrawal",
+		CreatedAt: time.Date(2024, 1, 20, 16, 45, 0, 0, time.UTC),
+	},
+}
+
+// betweenTags matches the indentation between XML elements, so expectations
+// need not depend on nesting depth.
+var betweenTags = regexp.MustCompile(`>\s+<`)
+
+func renderTestStatement(t *testing.T, format string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	err := Render(&buf, format, testHeader, func(emit func(models.TransactionView) error) error {
+		for _, tx := range testTransactions {
+			if err := emit(tx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("render %s: %v", format, err)
+	}
+	return buf.String()
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		want   []string
+	}{
+		{
+			name:   "csv - header block, quoted fields and running balance",
+			format: CSV,
+			want: []string{
+				`Account Name,"Smith & Sons ""Main"""`,
+				"Opening Balance,100.00\n",
+				"Closing Balance,130.25\n",
+				"Date,Transaction ID,Type,Reference,Amount,Balance\n",
+				`2024-01-05T10:00:00Z,tan-1,deposit,"Rent, ""Jan"" <flat 2> & bills",50.50,150.50`,
+				"2024-01-20T16:45:00Z,tan-2,withdrawal,,-20.25,130.25\n",
+			},
+		},
+		{
+			name:   "ofx - signed amounts, escaped memo and closing ledger balance",
+			format: OFX,
+			want: []string{
+				`<?OFX OFXHEADER="200" VERSION="220"`,
+				"<BANKID>101010</BANKID>",
+				"<ACCTID>01234567</ACCTID>",
+				"<DTSTART>20240101000000.000[0:GMT]</DTSTART>",
+				"<TRNTYPE>CREDIT</TRNTYPE>",
+				"<TRNAMT>50.50</TRNAMT>",
+				"<MEMO>Rent, &#34;Jan&#34; &lt;flat 2&gt; &amp; bills</MEMO>",
+				"<TRNTYPE>DEBIT</TRNTYPE>",
+				"<TRNAMT>-20.25</TRNAMT>",
+				"<LEDGERBAL><BALAMT>130.25</BALAMT>",
+			},
+		},
+		{
+			name:   "camt.053 - opening and closing balances, unsigned amounts with indicators",
+			format: Camt053,
+			want: []string{
+				`<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">`,
+				"<MsgId>STMT-01234567-20240101-20240131</MsgId>",
+				"<Nm>Smith &amp; Sons &#34;Main&#34;</Nm>",
+				`<Cd>OPBD</Cd></CdOrPrtry></Tp><Amt Ccy="GBP">100.00</Amt><CdtDbtInd>CRDT</CdtDbtInd>`,
+				`<Cd>CLBD</Cd></CdOrPrtry></Tp><Amt Ccy="GBP">130.25</Amt><CdtDbtInd>CRDT</CdtDbtInd>`,
+				`<Amt Ccy="GBP">50.50</Amt><CdtDbtInd>CRDT</CdtDbtInd>`,
+				`<Amt Ccy="GBP">20.25</Amt><CdtDbtInd>DBIT</CdtDbtInd>`,
+				"<Ustrd>Rent, &#34;Jan&#34; &lt;flat 2&gt; &amp; bills</Ustrd>",
+				"<AddtlNtryInf>Balance after entry: 150.50</AddtlNtryInf>",
+				"<AddtlNtryInf>Balance after entry: 130.25</AddtlNtryInf>",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := renderTestStatement(t, tt.format)
+			if tt.format != CSV {
+				out = betweenTags.ReplaceAllString(out, "><")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("[%s] expected output to contain %q; got:\n%s", tt.name, want, out)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderWellFormedXML(t *testing.T) {
+	for _, format := range []string{OFX, Camt053} {
+		out := renderTestStatement(t, format)
+		dec := xml.NewDecoder(strings.NewReader(out))
+		for {
+			_, err := dec.Token()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				t.Fatalf("[%s] output is not well-formed XML: %v", format, err)
+			}
+		}
+	}
+}
+
+func TestRenderUnsupportedFormat(t *testing.T) {
+	err := Render(io.Discard, "qif", testHeader, func(func(models.TransactionView) error) error { return nil })
+	if err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
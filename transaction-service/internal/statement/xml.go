@@ -0,0 +1,63 @@
+package statement
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+// xmlStream writes an XML document element by element so that repeated
+// entries can be flushed as they are produced. The first encoding error is
+// kept and returned by flush, so a run of writes needs only one check.
+type xmlStream struct {
+	enc  *xml.Encoder
+	open []xml.Name
+	err  error
+}
+
+func newXMLStream(w io.Writer) *xmlStream {
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return &xmlStream{enc: enc}
+}
+
+// start opens an element that stays open until the matching end.
+func (x *xmlStream) start(name string, attrs ...xml.Attr) {
+	el := xml.StartElement{Name: xml.Name{Local: name}, Attr: attrs}
+	x.open = append(x.open, el.Name)
+	x.token(el)
+}
+
+// end closes the most recently opened element.
+func (x *xmlStream) end() {
+	name := x.open[len(x.open)-1]
+	x.open = x.open[:len(x.open)-1]
+	x.token(xml.EndElement{Name: name})
+}
+
+// element writes a complete element from a value or struct.
+func (x *xmlStream) element(name string, v any, attrs ...xml.Attr) {
+	if x.err == nil {
+		x.err = x.enc.EncodeElement(v, xml.StartElement{Name: xml.Name{Local: name}, Attr: attrs})
+	}
+}
+
+func (x *xmlStream) token(t xml.Token) {
+	if x.err == nil {
+		x.err = x.enc.EncodeToken(t)
+	}
+}
+
+func (x *xmlStream) flush() error {
+	if x.err != nil {
+		return x.err
+	}
+	return x.enc.Flush()
+}
+
+// closeAll closes any elements still open and flushes.
+func (x *xmlStream) closeAll() error {
+	for len(x.open) > 0 {
+		x.end()
+	}
+	return x.flush()
+}
@@ -24,3 +24,7 @@ IF NOT EXISTS transactions
 CREATE INDEX idx_transactions_account ON transactions(account_number);
 CREATE INDEX idx_transactions_user ON transactions(user_id);
 CREATE INDEX idx_transactions_created_at ON transactions(created_at);
+
+-- Keyset pagination: newest-first listing per account, optionally by type.
+CREATE INDEX idx_transactions_account_created ON transactions(account_number, created_at DESC, id DESC);
+CREATE INDEX idx_transactions_account_type_created ON transactions(account_number, type, created_at DESC, id DESC);
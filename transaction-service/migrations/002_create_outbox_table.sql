@@ -0,0 +1,16 @@
+CREATE TABLE
+IF NOT EXISTS outbox
+(
+    id BIGSERIAL PRIMARY KEY,
+    stream VARCHAR
+(100) NOT NULL,
+    event_type VARCHAR
+(100) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW
+(),
+    published_at TIMESTAMP
+);
+
+CREATE INDEX idx_outbox_unpublished ON outbox(id) WHERE published_at IS NULL;
+CREATE INDEX idx_outbox_published_at ON outbox(published_at);
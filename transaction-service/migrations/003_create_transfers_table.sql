@@ -0,0 +1,42 @@
+CREATE TABLE
+IF NOT EXISTS transfers
+(
+    id VARCHAR
+(50) PRIMARY KEY,
+    from_account_number VARCHAR
+(8) NOT NULL,
+    to_account_number VARCHAR
+(8) NOT NULL,
+    user_id VARCHAR
+(50) NOT NULL,
+    to_user_id VARCHAR
+(50) NOT NULL,
+    amount DECIMAL
+(10,2) NOT NULL,
+    currency VARCHAR
+(3) DEFAULT 'GBP',
+    reference VARCHAR
+(255),
+    status VARCHAR
+(20) NOT NULL,
+    failure_reason VARCHAR
+(255),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW
+(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW
+(),
+    CONSTRAINT transfer_amount_positive CHECK
+(amount > 0),
+    CONSTRAINT transfer_distinct_accounts CHECK
+(from_account_number <> to_account_number)
+);
+
+CREATE INDEX idx_transfers_from_account ON transfers(from_account_number);
+CREATE INDEX idx_transfers_status ON transfers(status);
+
+ALTER TABLE transactions ADD COLUMN
+IF NOT EXISTS transfer_id VARCHAR
+(50) REFERENCES transfers
+(id);
+
+CREATE INDEX idx_transactions_transfer ON transactions(transfer_id) WHERE transfer_id IS NOT NULL;
@@ -0,0 +1,11 @@
+-- status is 'pending' until account-service posts the transaction to the
+-- ledger ('posted') or refuses it ('rejected', with failure_reason).
+-- Transactions recorded before statuses existed were all posted.
+ALTER TABLE transactions ADD COLUMN
+IF NOT EXISTS status VARCHAR
+(20) NOT NULL DEFAULT 'posted';
+ALTER TABLE transactions ALTER COLUMN status SET DEFAULT 'pending';
+
+ALTER TABLE transactions ADD COLUMN
+IF NOT EXISTS failure_reason VARCHAR
+(255);
@@ -4,37 +4,58 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 
 	"github.com/eaglebank/shared/cqrs"
 	"github.com/eaglebank/shared/events"
+	"github.com/eaglebank/shared/middleware"
 	"github.com/eaglebank/shared/models"
+	"github.com/eaglebank/shared/notify"
+	"github.com/eaglebank/shared/secretbox"
+	"github.com/eaglebank/shared/signedtoken"
+	"github.com/eaglebank/shared/totp"
 	"github.com/eaglebank/shared/utils"
 	"github.com/eaglebank/user-service/internal/repository"
 )
 
 // UserCommandService writes user state to PostgreSQL and keeps the Redis
-// read model up to date.
+// read model up to date. Domain events go through the transactional outbox.
 type UserCommandService struct {
 	writeRepo *repository.UserWriteRepository
 	readRepo  *repository.UserReadRepository
-	publisher *events.Publisher
+	// mfaBox encrypts TOTP secrets at rest; auth-service decrypts them.
+	mfaBox *secretbox.Box
+	// denylist denies the access tokens of sessions ended by a password change.
+	denylist middleware.TokenDenylist
+	// verifier signs the links that verify a user's email address, sent by
+	// notifier to verifyURL?token=....
+	verifier  *signedtoken.Signer
+	notifier  notify.Notifier
+	verifyURL string
 }
 
 func NewUserCommandService(
 	writeRepo *repository.UserWriteRepository,
 	readRepo *repository.UserReadRepository,
-	publisher *events.Publisher,
+	mfaBox *secretbox.Box,
+	denylist middleware.TokenDenylist,
+	verifier *signedtoken.Signer,
+	notifier notify.Notifier,
+	verifyURL string,
 ) *UserCommandService {
 	return &UserCommandService{
 		writeRepo: writeRepo,
 		readRepo:  readRepo,
-		publisher: publisher,
+		mfaBox:    mfaBox,
+		denylist:  denylist,
+		verifier:  verifier,
+		notifier:  notifier,
+		verifyURL: verifyURL,
 	}
 }
 
-func (s *UserCommandService) CreateUser(cmd cqrs.CreateUserCommand) (*models.User, error) {
+func (s *UserCommandService) CreateUser(ctx context.Context, cmd cqrs.CreateUserCommand) (*models.User, error) {
 	passwordHash, err := utils.HashPassword(cmd.Password)
 	if err != nil {
 		return nil, fmt.Errorf("failed to hash password: %w", err)
@@ -46,71 +67,260 @@ func (s *UserCommandService) CreateUser(cmd cqrs.CreateUserCommand) (*models.Use
 		PasswordHash: passwordHash,
 		PhoneNumber:  cmd.PhoneNumber,
 		Address:      cmd.Address,
+		Status:       models.UserStatusPendingVerification,
 		CreatedAt:    time.Now().UTC(),
 		UpdatedAt:    time.Now().UTC(),
 	}
-	if err := s.writeRepo.Create(user); err != nil {
+	created := events.NewOutboxEvent(ctx, events.UserEventsStream, events.UserCreated, events.UserCreatedEvent{
+		UserID: user.ID,
+		Email:  user.Email,
+		Name:   user.Name,
+	})
+	if err := s.writeRepo.Create(user, created); err != nil {
 		return nil, err
 	}
-	ctx := context.Background()
 	s.readRepo.CacheUserView(ctx, userToView(user))
-	if err := s.publisher.Publish(ctx, events.UserEventsStream, events.UserCreated, events.UserCreatedEvent{
+	// The user can ask for another link, so a failed send does not fail
+	// registration.
+	if err := s.sendVerification(ctx, user); err != nil {
+		slog.ErrorContext(ctx, "Failed to send verification email", "user_id", user.ID, "err", err)
+	}
+	return user, nil
+}
+
+// verifyEmailPurpose scopes verification tokens so no other signed token can
+// stand in for one.
+const verifyEmailPurpose = "verify-email"
+
+const verificationTTL = 24 * time.Hour
+
+// sendVerification emails the user a link that verifies their current
+// address. The token names the address, so it stops working if the user
+// changes it.
+func (s *UserCommandService) sendVerification(ctx context.Context, user *models.User) error {
+	token := s.verifier.Sign(verifyEmailPurpose, []string{user.ID, user.Email}, time.Now().Add(verificationTTL))
+	return s.notifier.Send(ctx, notify.Message{
+		To:      user.Email,
+		Subject: "Verify your Eagle Bank email address",
+		Body: fmt.Sprintf("Welcome to Eagle Bank, %s.\n\nFollow this link within %d hours to verify your email address:\n\n%s?token=%s\n",
+			user.Name, int(verificationTTL.Hours()), s.verifyURL, token),
+	})
+}
+
+// VerifyEmail activates the user named in the token and publishes
+// user.verified. Verifying an already active user succeeds without effect.
+func (s *UserCommandService) VerifyEmail(ctx context.Context, cmd cqrs.VerifyEmailCommand) error {
+	fields, err := s.verifier.Verify(verifyEmailPurpose, cmd.Token, time.Now())
+	if err != nil || len(fields) != 2 {
+		return fmt.Errorf("invalid verification token")
+	}
+	userID, email := fields[0], fields[1]
+	user, err := s.writeRepo.GetByID(userID)
+	if err != nil {
+		if err.Error() == "user not found" {
+			return fmt.Errorf("invalid verification token")
+		}
+		return err
+	}
+	if user.Email != email {
+		return fmt.Errorf("invalid verification token")
+	}
+	if user.Status == models.UserStatusActive {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	verified := events.NewOutboxEvent(ctx, events.UserEventsStream, events.UserVerified, events.UserVerifiedEvent{
 		UserID: user.ID,
 		Email:  user.Email,
-		Name:   user.Name,
-	}); err != nil {
-		log.Printf("Failed to publish user.created event: %v", err)
+	})
+	if err := s.writeRepo.Verify(user.ID, email, now, verified); err != nil {
+		return err
 	}
-	return user, nil
+	user.Status = models.UserStatusActive
+	user.UpdatedAt = now
+	s.readRepo.CacheUserView(ctx, userToView(user))
+	return nil
 }
 
-func (s *UserCommandService) UpdateUser(cmd cqrs.UpdateUserCommand) (*models.UserView, error) {
+// ResendVerification sends a new verification link to a pending user.
+func (s *UserCommandService) ResendVerification(ctx context.Context, cmd cqrs.ResendVerificationCommand) error {
+	user, err := s.writeRepo.GetByID(cmd.UserID)
+	if err != nil {
+		return err
+	}
+	if user.Status != models.UserStatusPendingVerification {
+		return fmt.Errorf("user already verified")
+	}
+	return s.sendVerification(ctx, user)
+}
+
+// UpdateUser saves the user's profile. Changing the email address puts the
+// user back into pending_verification and publishes user.email_changed, on
+// which HandleUserEvent sends a link to verify the new address.
+func (s *UserCommandService) UpdateUser(ctx context.Context, cmd cqrs.UpdateUserCommand) (*models.UserView, error) {
 	user, err := s.writeRepo.GetByID(cmd.UserID)
 	if err != nil {
 		return nil, err
 	}
+	emailChanged := user.Email != cmd.Email
 	user.Name = cmd.Name
 	user.Email = cmd.Email
 	user.PhoneNumber = cmd.PhoneNumber
 	user.Address = cmd.Address
 	user.UpdatedAt = time.Now().UTC()
-	if err := s.writeRepo.Update(user); err != nil {
+	outbox := []events.OutboxEvent{
+		events.NewOutboxEvent(ctx, events.UserEventsStream, events.UserUpdated, events.UserUpdatedEvent{
+			UserID: user.ID,
+			Email:  user.Email,
+			Name:   user.Name,
+		}),
+	}
+	if emailChanged {
+		user.Status = models.UserStatusPendingVerification
+		outbox = append(outbox, events.NewOutboxEvent(ctx, events.UserEventsStream, events.UserEmailChanged, events.UserEmailChangedEvent{
+			UserID: user.ID,
+			Email:  user.Email,
+		}))
+	}
+	if err := s.writeRepo.Update(user, outbox...); err != nil {
 		return nil, err
 	}
 	view := userToView(user)
-	s.readRepo.CacheUserView(context.Background(), view)
-	if err := s.publisher.Publish(context.Background(), events.UserEventsStream, events.UserUpdated, events.UserUpdatedEvent{
-		UserID: user.ID,
-		Email:  user.Email,
-		Name:   user.Name,
-	}); err != nil {
-		log.Printf("Failed to publish user.updated event: %v", err)
-	}
+	s.readRepo.CacheUserView(ctx, view)
 	return view, nil
 }
 
 // DeleteUser rejects the operation if the user still has open accounts.
-func (s *UserCommandService) DeleteUser(cmd cqrs.DeleteUserCommand) error {
-	if s.readRepo.HasActiveAccounts(context.Background(), cmd.UserID) {
+func (s *UserCommandService) DeleteUser(ctx context.Context, cmd cqrs.DeleteUserCommand) error {
+	if s.readRepo.HasActiveAccounts(ctx, cmd.UserID) {
 		return fmt.Errorf("user has active accounts")
 	}
-	if err := s.writeRepo.Delete(cmd.UserID); err != nil {
+	deleted := events.NewOutboxEvent(ctx, events.UserEventsStream, events.UserDeleted, events.UserDeletedEvent{
+		UserID: cmd.UserID,
+	})
+	if err := s.writeRepo.Delete(cmd.UserID, deleted); err != nil {
 		return err
 	}
-	s.readRepo.InvalidateUserView(context.Background(), cmd.UserID)
-	if err := s.publisher.Publish(context.Background(), events.UserEventsStream, events.UserDeleted, events.UserDeletedEvent{
-		UserID: cmd.UserID,
-	}); err != nil {
-		log.Printf("Failed to publish user.deleted event: %v", err)
+	s.readRepo.InvalidateUserView(ctx, cmd.UserID)
+	return nil
+}
+
+// ChangePassword replaces the user's password if CurrentPassword is right,
+// then signs out every session, including the caller's.
+func (s *UserCommandService) ChangePassword(ctx context.Context, cmd cqrs.ChangePasswordCommand) error {
+	user, err := s.writeRepo.GetByID(cmd.UserID)
+	if err != nil {
+		return err
+	}
+	if !utils.CheckPassword(cmd.CurrentPassword, user.PasswordHash) {
+		return fmt.Errorf("invalid current password")
+	}
+	passwordHash, err := utils.HashPassword(cmd.NewPassword)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	revoked, err := s.writeRepo.ChangePassword(user.ID, passwordHash)
+	if err != nil {
+		return err
+	}
+	for _, session := range revoked {
+		if err := s.denylist.Revoke(ctx, session.AccessTokenID, session.AccessExpiresAt); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// mfaIssuer labels the account in authenticator apps.
+const mfaIssuer = "Eagle Bank"
+
+const recoveryCodeCount = 10
+
+// EnrolMFA generates a TOTP secret and recovery codes for the user. The
+// enrolment stays pending, and login unaffected, until ConfirmMFA. Enrolling
+// again before confirming replaces the secret and codes.
+func (s *UserCommandService) EnrolMFA(ctx context.Context, cmd cqrs.EnrolMFACommand) (*models.MFAEnrolment, error) {
+	user, err := s.writeRepo.GetByID(cmd.UserID)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := s.mfaBox.Seal(secret, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		if codes[i], err = totp.GenerateRecoveryCode(); err != nil {
+			return nil, err
+		}
+		hashes[i] = utils.HashToken(totp.NormalizeRecoveryCode(codes[i]))
+	}
+	if err := s.writeRepo.SaveMFAEnrolment(user.ID, ciphertext, hashes); err != nil {
+		return nil, err
+	}
+	return &models.MFAEnrolment{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(mfaIssuer, user.Email, secret),
+		RecoveryCodes:   codes,
+	}, nil
+}
+
+// ConfirmMFA enables the pending enrolment if Code is valid for its secret.
+func (s *UserCommandService) ConfirmMFA(ctx context.Context, cmd cqrs.ConfirmMFACommand) error {
+	enrolment, err := s.writeRepo.GetMFAEnrolment(cmd.UserID)
+	if err != nil {
+		return err
+	}
+	if enrolment.Confirmed {
+		return fmt.Errorf("mfa already enabled")
+	}
+	secret, err := s.mfaBox.Open(enrolment.SecretCiphertext, cmd.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt mfa secret: %w", err)
+	}
+	step, ok := totp.Validate(secret, cmd.Code, time.Now(), -1)
+	if !ok {
+		return fmt.Errorf("invalid mfa code")
+	}
+	return s.writeRepo.ConfirmMFA(cmd.UserID, step)
+}
+
+// HandleUserEvent sends a verification link to the new address on
+// user.email_changed. A link is only sent while that address is still the
+// user's and unverified, so a redelivered or superseded event sends nothing.
+func (s *UserCommandService) HandleUserEvent(ctx context.Context, event events.Event) error {
+	if event.Type != events.UserEmailChanged {
+		return nil
+	}
+	dataBytes, _ := json.Marshal(event.Data)
+	var data events.UserEmailChangedEvent
+	if err := json.Unmarshal(dataBytes, &data); err != nil {
+		return fmt.Errorf("failed to unmarshal user.email_changed event: %w", err)
+	}
+	user, err := s.writeRepo.GetByID(data.UserID)
+	if err != nil {
+		if err.Error() == "user not found" {
+			return nil
+		}
+		return err
+	}
+	if user.Email != data.Email || user.Status != models.UserStatusPendingVerification {
+		return nil
+	}
+	return s.sendVerification(ctx, user)
+}
+
 // HandleAccountEvent is the Redis stream subscriber handler.
 // It reacts to account.created / account.deleted events to keep user-side
 // metadata and logs current.
 func (s *UserCommandService) HandleAccountEvent(ctx context.Context, event events.Event) error {
-	log.Printf("Received account event: %s", event.Type)
+	slog.InfoContext(ctx, "Received account event")
 	switch event.Type {
 	case events.AccountCreated:
 		dataBytes, _ := json.Marshal(event.Data)
@@ -118,7 +328,7 @@ func (s *UserCommandService) HandleAccountEvent(ctx context.Context, event event
 		if err := json.Unmarshal(dataBytes, &data); err != nil {
 			return fmt.Errorf("failed to unmarshal account.created event: %w", err)
 		}
-		log.Printf("User %s created account %s", data.UserID, data.AccountNumber)
+		slog.InfoContext(ctx, "User created account", "user_id", data.UserID, "account_number", data.AccountNumber)
 		s.readRepo.IncrAccountCount(ctx, data.UserID)
 	case events.AccountDeleted:
 		dataBytes, _ := json.Marshal(event.Data)
@@ -126,7 +336,7 @@ func (s *UserCommandService) HandleAccountEvent(ctx context.Context, event event
 		if err := json.Unmarshal(dataBytes, &data); err != nil {
 			return fmt.Errorf("failed to unmarshal account.deleted event: %w", err)
 		}
-		log.Printf("User %s deleted account %s", data.UserID, data.AccountNumber)
+		slog.InfoContext(ctx, "User deleted account", "user_id", data.UserID, "account_number", data.AccountNumber)
 		s.readRepo.DecrAccountCount(ctx, data.UserID)
 	}
 	return nil
@@ -139,6 +349,7 @@ func userToView(u *models.User) *models.UserView {
 		Email:       u.Email,
 		PhoneNumber: u.PhoneNumber,
 		Address:     u.Address,
+		Status:      u.Status,
 		CreatedAt:   u.CreatedAt,
 		UpdatedAt:   u.UpdatedAt,
 	}
@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"context"
 	"net/http"
+	"strconv"
 
 	"github.com/eaglebank/shared/cqrs"
 	"github.com/eaglebank/shared/middleware"
@@ -11,14 +13,20 @@ import (
 
 // UserCommander defines the write-side operations used by UserHandler.
 type UserCommander interface {
-	CreateUser(cqrs.CreateUserCommand) (*models.User, error)
-	UpdateUser(cqrs.UpdateUserCommand) (*models.UserView, error)
-	DeleteUser(cqrs.DeleteUserCommand) error
+	CreateUser(context.Context, cqrs.CreateUserCommand) (*models.User, error)
+	UpdateUser(context.Context, cqrs.UpdateUserCommand) (*models.UserView, error)
+	DeleteUser(context.Context, cqrs.DeleteUserCommand) error
+	EnrolMFA(context.Context, cqrs.EnrolMFACommand) (*models.MFAEnrolment, error)
+	ConfirmMFA(context.Context, cqrs.ConfirmMFACommand) error
+	ChangePassword(context.Context, cqrs.ChangePasswordCommand) error
+	VerifyEmail(context.Context, cqrs.VerifyEmailCommand) error
+	ResendVerification(context.Context, cqrs.ResendVerificationCommand) error
 }
 
 // UserQuerier defines the read-side operations used by UserHandler.
 type UserQuerier interface {
-	GetUser(cqrs.GetUserQuery) (*models.UserView, error)
+	GetUser(context.Context, cqrs.GetUserQuery) (*models.UserView, error)
+	SearchUsers(context.Context, cqrs.SearchUsersQuery) ([]models.UserView, error)
 }
 
 // UserHandler routes requests to the command or query service as appropriate.
@@ -42,6 +50,19 @@ type UpdateUserRequest struct {
 	Address     models.Address `json:"address" validate:"required"`
 }
 
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	NewPassword     string `json:"newPassword" validate:"required,min=8"`
+}
+
+type SearchUsersResponse struct {
+	Users []models.UserView `json:"users"`
+}
+
+type ConfirmMFARequest struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
 func NewUserHandler(commands UserCommander, queries UserQuerier) *UserHandler {
 	return &UserHandler{commands: commands, queries: queries}
 }
@@ -57,7 +78,7 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 		return
 	}
 
-	user, err := h.commands.CreateUser(cqrs.CreateUserCommand{
+	user, err := h.commands.CreateUser(c.Request.Context(), cqrs.CreateUserCommand{
 		Name:        req.Name,
 		Email:       req.Email,
 		Password:    req.Password,
@@ -78,11 +99,10 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 
 func (h *UserHandler) GetUser(c *gin.Context) {
 	userID := c.Param("userId")
-	requestingUserID, _ := middleware.GetUserID(c)
 
-	view, err := h.queries.GetUser(cqrs.GetUserQuery{
-		UserID:           userID,
-		RequestingUserID: requestingUserID,
+	view, err := h.queries.GetUser(c.Request.Context(), cqrs.GetUserQuery{
+		UserID:    userID,
+		Principal: middleware.GetPrincipal(c),
 	})
 	if err != nil {
 		if err.Error() == "forbidden" {
@@ -96,11 +116,33 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 	c.JSON(http.StatusOK, view)
 }
 
+// SearchUsers is the back-office user lookup. It needs an email or name
+// filter; limit defaults to 20 and may be at most 100.
+func (h *UserHandler) SearchUsers(c *gin.Context) {
+	email, name := c.Query("email"), c.Query("name")
+	if email == "" && name == "" {
+		middleware.RespondWithError(c, http.StatusBadRequest, "email or name is required")
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		middleware.RespondWithError(c, http.StatusBadRequest, "limit must be between 1 and 100")
+		return
+	}
+
+	views, err := h.queries.SearchUsers(c.Request.Context(), cqrs.SearchUsersQuery{Email: email, Name: name, Limit: limit})
+	if err != nil {
+		middleware.RespondWithError(c, http.StatusInternalServerError, "Failed to search users")
+		return
+	}
+
+	c.JSON(http.StatusOK, SearchUsersResponse{Users: views})
+}
+
 func (h *UserHandler) UpdateUser(c *gin.Context) {
 	userID := c.Param("userId")
-	requestingUserID, _ := middleware.GetUserID(c)
 
-	if userID != requestingUserID {
+	if !middleware.GetPrincipal(c).Owns(userID) {
 		middleware.RespondWithError(c, http.StatusForbidden, "You can only update your own user details")
 		return
 	}
@@ -115,7 +157,7 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		return
 	}
 
-	view, err := h.commands.UpdateUser(cqrs.UpdateUserCommand{
+	view, err := h.commands.UpdateUser(c.Request.Context(), cqrs.UpdateUserCommand{
 		UserID:      userID,
 		Name:        req.Name,
 		Email:       req.Email,
@@ -136,14 +178,13 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 
 func (h *UserHandler) DeleteUser(c *gin.Context) {
 	userID := c.Param("userId")
-	requestingUserID, _ := middleware.GetUserID(c)
 
-	if userID != requestingUserID {
+	if !middleware.GetPrincipal(c).Owns(userID) {
 		middleware.RespondWithError(c, http.StatusForbidden, "You can only delete your own account")
 		return
 	}
 
-	err := h.commands.DeleteUser(cqrs.DeleteUserCommand{UserID: userID})
+	err := h.commands.DeleteUser(c.Request.Context(), cqrs.DeleteUserCommand{UserID: userID})
 	if err != nil {
 		if err.Error() == "user not found" {
 			middleware.RespondWithError(c, http.StatusNotFound, "User not found")
@@ -159,3 +200,153 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 
 	c.Status(http.StatusNoContent)
 }
+
+// EnrolMFA starts TOTP enrolment and returns the secret, its otpauth://
+// provisioning URI (for a QR code) and the recovery codes. They are shown once.
+func (h *UserHandler) EnrolMFA(c *gin.Context) {
+	userID := c.Param("userId")
+
+	if !middleware.GetPrincipal(c).Owns(userID) {
+		middleware.RespondWithError(c, http.StatusForbidden, "You can only manage MFA for your own user")
+		return
+	}
+
+	enrolment, err := h.commands.EnrolMFA(c.Request.Context(), cqrs.EnrolMFACommand{UserID: userID})
+	if err != nil {
+		switch err.Error() {
+		case "user not found":
+			middleware.RespondWithError(c, http.StatusNotFound, "User not found")
+		case "mfa already enabled":
+			middleware.RespondWithError(c, http.StatusConflict, "MFA is already enabled")
+		default:
+			middleware.RespondWithError(c, http.StatusInternalServerError, "Failed to enrol MFA")
+		}
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, enrolment)
+}
+
+// ConfirmMFA enables a pending enrolment with a code from the authenticator.
+func (h *UserHandler) ConfirmMFA(c *gin.Context) {
+	userID := c.Param("userId")
+
+	if !middleware.GetPrincipal(c).Owns(userID) {
+		middleware.RespondWithError(c, http.StatusForbidden, "You can only manage MFA for your own user")
+		return
+	}
+
+	var req ConfirmMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.RespondWithError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if validationErrors := middleware.ValidateRequest(req); validationErrors != nil {
+		middleware.RespondWithValidationError(c, validationErrors)
+		return
+	}
+
+	err := h.commands.ConfirmMFA(c.Request.Context(), cqrs.ConfirmMFACommand{UserID: userID, Code: req.Code})
+	if err != nil {
+		switch err.Error() {
+		case "mfa not enrolled":
+			middleware.RespondWithError(c, http.StatusNotFound, "MFA enrolment not found")
+		case "mfa already enabled":
+			middleware.RespondWithError(c, http.StatusConflict, "MFA is already enabled")
+		case "invalid mfa code":
+			middleware.RespondWithError(c, http.StatusBadRequest, "Invalid MFA code")
+		default:
+			middleware.RespondWithError(c, http.StatusInternalServerError, "Failed to confirm MFA")
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ChangePassword replaces the caller's password. Every session is signed
+// out, so the client must log in again.
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	userID := c.Param("userId")
+
+	if !middleware.GetPrincipal(c).Owns(userID) {
+		middleware.RespondWithError(c, http.StatusForbidden, "You can only change your own password")
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		middleware.RespondWithError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if validationErrors := middleware.ValidateRequest(req); validationErrors != nil {
+		middleware.RespondWithValidationError(c, validationErrors)
+		return
+	}
+
+	err := h.commands.ChangePassword(c.Request.Context(), cqrs.ChangePasswordCommand{
+		UserID:          userID,
+		CurrentPassword: req.CurrentPassword,
+		NewPassword:     req.NewPassword,
+	})
+	if err != nil {
+		switch err.Error() {
+		case "user not found":
+			middleware.RespondWithError(c, http.StatusNotFound, "User not found")
+		case "invalid current password":
+			middleware.RespondWithError(c, http.StatusBadRequest, "Current password is incorrect")
+		default:
+			middleware.RespondWithError(c, http.StatusInternalServerError, "Failed to change password")
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// VerifyEmail is the target of the link emailed on registration. It needs no
+// authentication: the signed token is the credential.
+func (h *UserHandler) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		middleware.RespondWithError(c, http.StatusBadRequest, "token is required")
+		return
+	}
+
+	if err := h.commands.VerifyEmail(c.Request.Context(), cqrs.VerifyEmailCommand{Token: token}); err != nil {
+		if err.Error() == "invalid verification token" {
+			middleware.RespondWithError(c, http.StatusBadRequest, "Verification link is invalid or has expired")
+		} else {
+			middleware.RespondWithError(c, http.StatusInternalServerError, "Failed to verify email address")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email address verified"})
+}
+
+// ResendVerification emails the caller a new verification link.
+func (h *UserHandler) ResendVerification(c *gin.Context) {
+	userID := c.Param("userId")
+
+	if !middleware.GetPrincipal(c).Owns(userID) {
+		middleware.RespondWithError(c, http.StatusForbidden, "You can only verify your own email address")
+		return
+	}
+
+	err := h.commands.ResendVerification(c.Request.Context(), cqrs.ResendVerificationCommand{UserID: userID})
+	if err != nil {
+		switch err.Error() {
+		case "user not found":
+			middleware.RespondWithError(c, http.StatusNotFound, "User not found")
+		case "user already verified":
+			middleware.RespondWithError(c, http.StatusConflict, "Email address is already verified")
+		default:
+			middleware.RespondWithError(c, http.StatusInternalServerError, "Failed to send verification email")
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Verification email sent"})
+}
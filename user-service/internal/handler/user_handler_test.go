@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -20,37 +21,80 @@ type mockUserCommander struct {
 	createFn func(cqrs.CreateUserCommand) (*models.User, error)
 	updateFn func(cqrs.UpdateUserCommand) (*models.UserView, error)
 	deleteFn func(cqrs.DeleteUserCommand) error
+	enrolFn  func(cqrs.EnrolMFACommand) (*models.MFAEnrolment, error)
+	confirmFn func(cqrs.ConfirmMFACommand) error
+	changePasswordFn func(cqrs.ChangePasswordCommand) error
+	verifyEmailFn func(cqrs.VerifyEmailCommand) error
+	resendFn func(cqrs.ResendVerificationCommand) error
 }
 
-func (m *mockUserCommander) CreateUser(cmd cqrs.CreateUserCommand) (*models.User, error) {
+func (m *mockUserCommander) CreateUser(_ context.Context, cmd cqrs.CreateUserCommand) (*models.User, error) {
 	if m.createFn != nil {
 		return m.createFn(cmd)
 	}
 	return nil, fmt.Errorf("not configured")
 }
-func (m *mockUserCommander) UpdateUser(cmd cqrs.UpdateUserCommand) (*models.UserView, error) {
+func (m *mockUserCommander) UpdateUser(_ context.Context, cmd cqrs.UpdateUserCommand) (*models.UserView, error) {
 	if m.updateFn != nil {
 		return m.updateFn(cmd)
 	}
 	return nil, fmt.Errorf("not configured")
 }
-func (m *mockUserCommander) DeleteUser(cmd cqrs.DeleteUserCommand) error {
+func (m *mockUserCommander) DeleteUser(_ context.Context, cmd cqrs.DeleteUserCommand) error {
 	if m.deleteFn != nil {
 		return m.deleteFn(cmd)
 	}
 	return fmt.Errorf("not configured")
 }
 
+func (m *mockUserCommander) EnrolMFA(_ context.Context, cmd cqrs.EnrolMFACommand) (*models.MFAEnrolment, error) {
+	if m.enrolFn != nil {
+		return m.enrolFn(cmd)
+	}
+	return nil, fmt.Errorf("not configured")
+}
+func (m *mockUserCommander) ConfirmMFA(_ context.Context, cmd cqrs.ConfirmMFACommand) error {
+	if m.confirmFn != nil {
+		return m.confirmFn(cmd)
+	}
+	return fmt.Errorf("not configured")
+}
+func (m *mockUserCommander) ChangePassword(_ context.Context, cmd cqrs.ChangePasswordCommand) error {
+	if m.changePasswordFn != nil {
+		return m.changePasswordFn(cmd)
+	}
+	return fmt.Errorf("not configured")
+}
+func (m *mockUserCommander) VerifyEmail(_ context.Context, cmd cqrs.VerifyEmailCommand) error {
+	if m.verifyEmailFn != nil {
+		return m.verifyEmailFn(cmd)
+	}
+	return fmt.Errorf("not configured")
+}
+func (m *mockUserCommander) ResendVerification(_ context.Context, cmd cqrs.ResendVerificationCommand) error {
+	if m.resendFn != nil {
+		return m.resendFn(cmd)
+	}
+	return fmt.Errorf("not configured")
+}
+
 type mockUserQuerier struct {
-	getFn func(cqrs.GetUserQuery) (*models.UserView, error)
+	getFn    func(cqrs.GetUserQuery) (*models.UserView, error)
+	searchFn func(cqrs.SearchUsersQuery) ([]models.UserView, error)
 }
 
-func (m *mockUserQuerier) GetUser(q cqrs.GetUserQuery) (*models.UserView, error) {
+func (m *mockUserQuerier) GetUser(_ context.Context, q cqrs.GetUserQuery) (*models.UserView, error) {
 	if m.getFn != nil {
 		return m.getFn(q)
 	}
 	return nil, fmt.Errorf("not configured")
 }
+func (m *mockUserQuerier) SearchUsers(_ context.Context, q cqrs.SearchUsersQuery) ([]models.UserView, error) {
+	if m.searchFn != nil {
+		return m.searchFn(q)
+	}
+	return nil, fmt.Errorf("not configured")
+}
 
 // ---- helpers ----
 
@@ -68,9 +112,15 @@ func newUserTestRouter(cmds UserCommander, qrys UserQuerier, authUserID string)
 	h := NewUserHandler(cmds, qrys)
 	v1 := r.Group("/v1/users")
 	v1.POST("", h.CreateUser)
+	v1.GET("/verify-email", h.VerifyEmail)
 	v1.GET("/:userId", h.GetUser)
 	v1.PATCH("/:userId", h.UpdateUser)
 	v1.DELETE("/:userId", h.DeleteUser)
+	v1.POST("/:userId/mfa", h.EnrolMFA)
+	v1.POST("/:userId/mfa/confirm", h.ConfirmMFA)
+	v1.PUT("/:userId/password", h.ChangePassword)
+	v1.POST("/:userId/verification", h.ResendVerification)
+	r.GET("/admin/v1/users", h.SearchUsers)
 	return r
 }
 
@@ -207,6 +257,46 @@ func TestGetUser(t *testing.T) {
 	}
 }
 
+func TestSearchUsers(t *testing.T) {
+	tests := []struct {
+		name           string
+		url            string
+		searchFn       func(cqrs.SearchUsersQuery) ([]models.UserView, error)
+		expectedStatus int
+	}{
+		{
+			name: "success - search by email",
+			url:  "/admin/v1/users?email=alice@example.com",
+			searchFn: func(q cqrs.SearchUsersQuery) ([]models.UserView, error) {
+				if q.Email != "alice@example.com" || q.Limit != 20 {
+					return nil, fmt.Errorf("unexpected query %+v", q)
+				}
+				return []models.UserView{*uTestUserView}, nil
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "bad request - no filter",
+			url:            "/admin/v1/users",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "bad request - limit out of range",
+			url:            "/admin/v1/users?name=alice&limit=500",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newUserTestRouter(&mockUserCommander{}, &mockUserQuerier{searchFn: tt.searchFn}, "usr-staff")
+			w := userDoRequest(router, http.MethodGet, tt.url, nil)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("[%s] expected status %d, got %d; body: %s", tt.name, tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
 func TestUpdateUser(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -301,3 +391,218 @@ func TestDeleteUser(t *testing.T) {
 		})
 	}
 }
+
+func TestEnrolMFA(t *testing.T) {
+	enrolment := &models.MFAEnrolment{
+		Secret:          "JBSWY3DPEHPK3PXP",
+		ProvisioningURI: "otpauth://totp/Eagle%20Bank:alice@example.com?secret=JBSWY3DPEHPK3PXP",
+		RecoveryCodes:   []string{"abcde-fghij"},
+	}
+	tests := []struct {
+		name           string
+		urlUserID      string
+		authUserID     string
+		enrolFn        func(cqrs.EnrolMFACommand) (*models.MFAEnrolment, error)
+		expectedStatus int
+	}{
+		{
+			name:      "success - enrol own user",
+			urlUserID: "usr-001", authUserID: "usr-001",
+			enrolFn:        func(cmd cqrs.EnrolMFACommand) (*models.MFAEnrolment, error) { return enrolment, nil },
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:      "forbidden - enrol another user",
+			urlUserID: "usr-002", authUserID: "usr-001",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:      "conflict - already enabled",
+			urlUserID: "usr-001", authUserID: "usr-001",
+			enrolFn:        func(cmd cqrs.EnrolMFACommand) (*models.MFAEnrolment, error) { return nil, fmt.Errorf("mfa already enabled") },
+			expectedStatus: http.StatusConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newUserTestRouter(&mockUserCommander{enrolFn: tt.enrolFn}, &mockUserQuerier{}, tt.authUserID)
+			w := userDoRequest(router, http.MethodPost, "/v1/users/"+tt.urlUserID+"/mfa", nil)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("[%s] expected status %d, got %d; body: %s", tt.name, tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if w.Code == http.StatusCreated && w.Header().Get("Cache-Control") != "no-store" {
+				t.Errorf("[%s] expected the secret not to be cached", tt.name)
+			}
+		})
+	}
+}
+
+func TestConfirmMFA(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           interface{}
+		confirmFn      func(cqrs.ConfirmMFACommand) error
+		expectedStatus int
+	}{
+		{
+			name:           "success - valid code",
+			body:           map[string]string{"code": "123456"},
+			confirmFn:      func(cmd cqrs.ConfirmMFACommand) error { return nil },
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "bad request - wrong code",
+			body:           map[string]string{"code": "654321"},
+			confirmFn:      func(cmd cqrs.ConfirmMFACommand) error { return fmt.Errorf("invalid mfa code") },
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "bad request - malformed code",
+			body:           map[string]string{"code": "12ab"},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "not found - not enrolled",
+			body:           map[string]string{"code": "123456"},
+			confirmFn:      func(cmd cqrs.ConfirmMFACommand) error { return fmt.Errorf("mfa not enrolled") },
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newUserTestRouter(&mockUserCommander{confirmFn: tt.confirmFn}, &mockUserQuerier{}, "usr-001")
+			w := userDoRequest(router, http.MethodPost, "/v1/users/usr-001/mfa/confirm", tt.body)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("[%s] expected status %d, got %d; body: %s", tt.name, tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	tests := []struct {
+		name             string
+		urlUserID        string
+		body             interface{}
+		changePasswordFn func(cqrs.ChangePasswordCommand) error
+		expectedStatus   int
+	}{
+		{
+			name:      "success - password changed",
+			urlUserID: "usr-001",
+			body:      map[string]string{"currentPassword": "letmein123", "newPassword": "newsecret123"},
+			changePasswordFn: func(cmd cqrs.ChangePasswordCommand) error {
+				if cmd.UserID != "usr-001" || cmd.CurrentPassword != "letmein123" || cmd.NewPassword != "newsecret123" {
+					return fmt.Errorf("unexpected command %+v", cmd)
+				}
+				return nil
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:             "bad request - wrong current password",
+			urlUserID:        "usr-001",
+			body:             map[string]string{"currentPassword": "wrongpass", "newPassword": "newsecret123"},
+			changePasswordFn: func(cmd cqrs.ChangePasswordCommand) error { return fmt.Errorf("invalid current password") },
+			expectedStatus:   http.StatusBadRequest,
+		},
+		{
+			name:           "bad request - new password too short",
+			urlUserID:      "usr-001",
+			body:           map[string]string{"currentPassword": "letmein123", "newPassword": "short"},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "forbidden - another user's password",
+			urlUserID:      "usr-002",
+			body:           map[string]string{"currentPassword": "letmein123", "newPassword": "newsecret123"},
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newUserTestRouter(&mockUserCommander{changePasswordFn: tt.changePasswordFn}, &mockUserQuerier{}, "usr-001")
+			w := userDoRequest(router, http.MethodPut, "/v1/users/"+tt.urlUserID+"/password", tt.body)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("[%s] expected status %d, got %d; body: %s", tt.name, tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestVerifyEmail(t *testing.T) {
+	tests := []struct {
+		name           string
+		url            string
+		verifyEmailFn  func(cqrs.VerifyEmailCommand) error
+		expectedStatus int
+	}{
+		{
+			name: "success - valid link",
+			url:  "/v1/users/verify-email?token=signed-token",
+			verifyEmailFn: func(cmd cqrs.VerifyEmailCommand) error {
+				if cmd.Token != "signed-token" {
+					return fmt.Errorf("unexpected command %+v", cmd)
+				}
+				return nil
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "bad request - expired or forged link",
+			url:            "/v1/users/verify-email?token=signed-token",
+			verifyEmailFn:  func(cmd cqrs.VerifyEmailCommand) error { return fmt.Errorf("invalid verification token") },
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "bad request - missing token",
+			url:            "/v1/users/verify-email",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newUserTestRouter(&mockUserCommander{verifyEmailFn: tt.verifyEmailFn}, &mockUserQuerier{}, "")
+			w := userDoRequest(router, http.MethodGet, tt.url, nil)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("[%s] expected status %d, got %d; body: %s", tt.name, tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestResendVerification(t *testing.T) {
+	tests := []struct {
+		name           string
+		urlUserID      string
+		resendFn       func(cqrs.ResendVerificationCommand) error
+		expectedStatus int
+	}{
+		{
+			name:           "accepted - link sent",
+			urlUserID:      "usr-001",
+			resendFn:       func(cmd cqrs.ResendVerificationCommand) error { return nil },
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:           "conflict - already verified",
+			urlUserID:      "usr-001",
+			resendFn:       func(cmd cqrs.ResendVerificationCommand) error { return fmt.Errorf("user already verified") },
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "forbidden - another user",
+			urlUserID:      "usr-002",
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newUserTestRouter(&mockUserCommander{resendFn: tt.resendFn}, &mockUserQuerier{}, "usr-001")
+			w := userDoRequest(router, http.MethodPost, "/v1/users/"+tt.urlUserID+"/verification", nil)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("[%s] expected status %d, got %d; body: %s", tt.name, tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
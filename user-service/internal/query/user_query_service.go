@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/eaglebank/shared/cqrs"
+	"github.com/eaglebank/shared/middleware"
 	"github.com/eaglebank/shared/models"
 	"github.com/eaglebank/user-service/internal/repository"
 )
@@ -18,10 +19,15 @@ func NewUserQueryService(readRepo *repository.UserReadRepository) *UserQueryServ
 	return &UserQueryService{readRepo: readRepo}
 }
 
-func (s *UserQueryService) GetUser(q cqrs.GetUserQuery) (*models.UserView, error) {
-	if q.UserID != q.RequestingUserID {
+func (s *UserQueryService) GetUser(ctx context.Context, q cqrs.GetUserQuery) (*models.UserView, error) {
+	if !q.Principal.CanAccess(q.UserID, middleware.ScopeUsersRead) {
 		return nil, fmt.Errorf("forbidden")
 	}
-	ctx := context.Background()
 	return s.readRepo.GetByID(ctx, q.UserID)
 }
+
+// SearchUsers finds users for back-office staff; callers must hold
+// middleware.ScopeUsersRead.
+func (s *UserQueryService) SearchUsers(ctx context.Context, q cqrs.SearchUsersQuery) ([]models.UserView, error) {
+	return s.readRepo.Search(ctx, q.Email, q.Name, q.Limit)
+}
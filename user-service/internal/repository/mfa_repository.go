@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// MFAEnrolment is a user's stored TOTP enrolment. The secret is encrypted.
+type MFAEnrolment struct {
+	UserID           string
+	SecretCiphertext string
+	Confirmed        bool
+}
+
+// SaveMFAEnrolment stores a pending TOTP enrolment and its recovery code
+// hashes, replacing any earlier enrolment that was never confirmed. It returns
+// "mfa already enabled" if the user has a confirmed enrolment.
+func (r *UserWriteRepository) SaveMFAEnrolment(userID, secretCiphertext string, recoveryCodeHashes []string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		INSERT INTO user_mfa (user_id, secret_ciphertext)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+			SET secret_ciphertext = EXCLUDED.secret_ciphertext, last_used_step = NULL, created_at = NOW()
+			WHERE user_mfa.confirmed_at IS NULL`,
+		userID, secretCiphertext,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save mfa enrolment: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("mfa already enabled")
+	}
+	if _, err := tx.Exec(`DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to replace recovery codes: %w", err)
+	}
+	for _, hash := range recoveryCodeHashes {
+		if _, err := tx.Exec(`INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash); err != nil {
+			return fmt.Errorf("failed to save recovery code: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetMFAEnrolment returns the user's enrolment or "mfa not enrolled".
+func (r *UserWriteRepository) GetMFAEnrolment(userID string) (*MFAEnrolment, error) {
+	e := MFAEnrolment{UserID: userID}
+	var confirmedAt sql.NullTime
+	err := r.db.QueryRow(
+		`SELECT secret_ciphertext, confirmed_at FROM user_mfa WHERE user_id = $1`, userID,
+	).Scan(&e.SecretCiphertext, &confirmedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("mfa not enrolled")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mfa enrolment: %w", err)
+	}
+	e.Confirmed = confirmedAt.Valid
+	return &e, nil
+}
+
+// ConfirmMFA enables a pending enrolment and records step as used, so the
+// code that confirmed it cannot then be used to log in.
+func (r *UserWriteRepository) ConfirmMFA(userID string, step int64) error {
+	result, err := r.db.Exec(`
+		UPDATE user_mfa SET confirmed_at = NOW(), last_used_step = $2
+		WHERE user_id = $1 AND confirmed_at IS NULL`,
+		userID, step,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to confirm mfa: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("mfa already enabled")
+	}
+	return nil
+}
@@ -25,7 +25,7 @@ func NewUserReadRepository(db *sql.DB, redisClient *goredis.Client) *UserReadRep
 	return &UserReadRepository{
 		db:    db,
 		redis: redisClient,
-		cache: sharedredis.NewViewCache[models.UserView](redisClient, 0),
+		cache: sharedredis.NewViewCache[models.UserView](redisClient, "user", 0),
 	}
 }
 
@@ -41,7 +41,7 @@ func (r *UserReadRepository) GetByID(ctx context.Context, id string) (*models.Us
 	query := `
 		SELECT id, name, email, phone_number,
 			   address_line1, address_line2, address_line3, address_town, address_county, address_postcode,
-			   created_at, updated_at
+			   status, created_at, updated_at
 		FROM users
 		WHERE id = $1 AND deleted_at IS NULL
 	`
@@ -51,7 +51,7 @@ func (r *UserReadRepository) GetByID(ctx context.Context, id string) (*models.Us
 	pgErr := r.db.QueryRow(query, id).Scan(
 		&view.ID, &view.Name, &view.Email, &view.PhoneNumber,
 		&view.Address.Line1, &line2, &line3, &view.Address.Town, &view.Address.County, &view.Address.Postcode,
-		&view.CreatedAt, &view.UpdatedAt,
+		&view.Status, &view.CreatedAt, &view.UpdatedAt,
 	)
 	if pgErr == sql.ErrNoRows {
 		return nil, fmt.Errorf("user not found")
@@ -72,6 +72,45 @@ func (r *UserReadRepository) GetByID(ctx context.Context, id string) (*models.Us
 	return &view, nil
 }
 
+// Search returns up to limit users, newest first, whose email equals email
+// (ignoring case) and whose name contains name. An empty filter matches
+// every user. Always read from PostgreSQL.
+func (r *UserReadRepository) Search(ctx context.Context, email, name string, limit int) ([]models.UserView, error) {
+	query := `
+		SELECT id, name, email, phone_number,
+			   address_line1, address_line2, address_line3, address_town, address_county, address_postcode,
+			   status, created_at, updated_at
+		FROM users
+		WHERE deleted_at IS NULL
+		  AND ($1 = '' OR LOWER(email) = LOWER($1))
+		  AND ($2 = '' OR POSITION(LOWER($2) IN LOWER(name)) > 0)
+		ORDER BY created_at DESC
+		LIMIT $3
+	`
+	rows, err := r.db.QueryContext(ctx, query, email, name, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search users: %w", err)
+	}
+	defer rows.Close()
+
+	views := []models.UserView{}
+	for rows.Next() {
+		var view models.UserView
+		var line2, line3 sql.NullString
+		if err := rows.Scan(
+			&view.ID, &view.Name, &view.Email, &view.PhoneNumber,
+			&view.Address.Line1, &line2, &line3, &view.Address.Town, &view.Address.County, &view.Address.Postcode,
+			&view.Status, &view.CreatedAt, &view.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		view.Address.Line2 = line2.String
+		view.Address.Line3 = line3.String
+		views = append(views, view)
+	}
+	return views, rows.Err()
+}
+
 // CacheUserView stores or refreshes the Redis read model for a user.
 // Called by the command service after every mutation.
 func (r *UserReadRepository) CacheUserView(ctx context.Context, view *models.UserView) {
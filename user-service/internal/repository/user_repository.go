@@ -3,7 +3,9 @@ package repository
 import (
 	"database/sql"
 	"fmt"
+	"time"
 
+	"github.com/eaglebank/shared/events"
 	"github.com/eaglebank/shared/models"
 	"github.com/lib/pq"
 )
@@ -18,18 +20,25 @@ func NewUserWriteRepository(db *sql.DB) *UserWriteRepository {
 	return &UserWriteRepository{db: db}
 }
 
-func (r *UserWriteRepository) Create(user *models.User) error {
+// Create inserts the user and queues its outbox events in one SQL transaction.
+func (r *UserWriteRepository) Create(user *models.User, outbox ...events.OutboxEvent) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
 		INSERT INTO users (id, name, email, password_hash, phone_number,
 			address_line1, address_line2, address_line3, address_town, address_county, address_postcode,
-			created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+			status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 	`
-	_, err := r.db.Exec(query,
+	_, err = tx.Exec(query,
 		user.ID, user.Name, user.Email, user.PasswordHash, user.PhoneNumber,
 		user.Address.Line1, nullString(user.Address.Line2), nullString(user.Address.Line3),
 		user.Address.Town, user.Address.County, user.Address.Postcode,
-		user.CreatedAt, user.UpdatedAt,
+		user.Status, user.CreatedAt, user.UpdatedAt,
 	)
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
@@ -37,7 +46,7 @@ func (r *UserWriteRepository) Create(user *models.User) error {
 		}
 		return fmt.Errorf("failed to create user: %w", err)
 	}
-	return nil
+	return commitWithOutbox(tx, outbox)
 }
 
 // GetByID fetches the full write model (including PasswordHash) for internal operations.
@@ -45,7 +54,7 @@ func (r *UserWriteRepository) GetByID(id string) (*models.User, error) {
 	query := `
 		SELECT id, name, email, password_hash, phone_number,
 			   address_line1, address_line2, address_line3, address_town, address_county, address_postcode,
-			   created_at, updated_at
+			   status, created_at, updated_at
 		FROM users
 		WHERE id = $1 AND deleted_at IS NULL
 	`
@@ -55,7 +64,7 @@ func (r *UserWriteRepository) GetByID(id string) (*models.User, error) {
 	err := r.db.QueryRow(query, id).Scan(
 		&user.ID, &user.Name, &user.Email, &user.PasswordHash, &user.PhoneNumber,
 		&user.Address.Line1, &line2, &line3, &user.Address.Town, &user.Address.County, &user.Address.Postcode,
-		&user.CreatedAt, &user.UpdatedAt,
+		&user.Status, &user.CreatedAt, &user.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("user not found")
@@ -73,40 +82,148 @@ func (r *UserWriteRepository) GetByID(id string) (*models.User, error) {
 	return &user, nil
 }
 
-func (r *UserWriteRepository) Update(user *models.User) error {
+// Update saves the user's profile and status. A changed email address
+// clears verified_at; the caller sets the status back to pending.
+func (r *UserWriteRepository) Update(user *models.User, outbox ...events.OutboxEvent) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
 		UPDATE users
 		SET name = $2, email = $3, phone_number = $4,
 			address_line1 = $5, address_line2 = $6, address_line3 = $7,
 			address_town = $8, address_county = $9, address_postcode = $10,
-			updated_at = $11
+			updated_at = $11, status = $12,
+			verified_at = CASE WHEN email = $3 THEN verified_at END
 		WHERE id = $1 AND deleted_at IS NULL
 	`
-	result, err := r.db.Exec(query,
+	result, err := tx.Exec(query,
 		user.ID, user.Name, user.Email, user.PhoneNumber,
 		user.Address.Line1, nullString(user.Address.Line2), nullString(user.Address.Line3),
 		user.Address.Town, user.Address.County, user.Address.Postcode,
-		user.UpdatedAt,
+		user.UpdatedAt, user.Status,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to update user: %w", err)
 	}
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("failed to check rows affected: %w", err)
+	if err := requireRowAffected(result); err != nil {
+		return err
 	}
-	if rows == 0 {
-		return fmt.Errorf("user not found")
-	}
-	return nil
+	return commitWithOutbox(tx, outbox)
 }
 
-func (r *UserWriteRepository) Delete(id string) error {
+func (r *UserWriteRepository) Delete(id string, outbox ...events.OutboxEvent) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `UPDATE users SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`
-	result, err := r.db.Exec(query, id)
+	result, err := tx.Exec(query, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
+	if err := requireRowAffected(result); err != nil {
+		return err
+	}
+	return commitWithOutbox(tx, outbox)
+}
+
+// Verify activates a user who is pending verification of email. It does
+// nothing, returning "user not found", if the user's email has changed since
+// or they are already active.
+func (r *UserWriteRepository) Verify(userID, email string, verifiedAt time.Time, outbox ...events.OutboxEvent) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE users SET status = $3, verified_at = $4, updated_at = $4
+		WHERE id = $1 AND email = $2 AND status = $5 AND deleted_at IS NULL`,
+		userID, email, models.UserStatusActive, verifiedAt, models.UserStatusPendingVerification,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to verify user: %w", err)
+	}
+	if err := requireRowAffected(result); err != nil {
+		return err
+	}
+	return commitWithOutbox(tx, outbox)
+}
+
+// RevokedSession is the access token of a session signed out by a password
+// change, which must be denied until it expires.
+type RevokedSession struct {
+	AccessTokenID   string
+	AccessExpiresAt time.Time
+}
+
+// ChangePassword sets the user's password hash, invalidates outstanding
+// password reset links, and revokes every refresh token in one transaction.
+// The refresh_tokens and password_reset_tokens tables are owned by
+// auth-service, which shares this database.
+func (r *UserWriteRepository) ChangePassword(userID, passwordHash string) ([]RevokedSession, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE users SET password_hash = $2, updated_at = $3
+		WHERE id = $1 AND deleted_at IS NULL`,
+		userID, passwordHash, time.Now().UTC(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update password: %w", err)
+	}
+	if err := requireRowAffected(result); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`UPDATE password_reset_tokens SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL`, userID); err != nil {
+		return nil, fmt.Errorf("failed to invalidate reset tokens: %w", err)
+	}
+
+	rows, err := tx.Query(`
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL
+		RETURNING access_token_id, access_expires_at`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	var revoked []RevokedSession
+	for rows.Next() {
+		var session RevokedSession
+		if err := rows.Scan(&session.AccessTokenID, &session.AccessExpiresAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan refresh token: %w", err)
+		}
+		revoked = append(revoked, session)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return revoked, nil
+}
+
+func (r *UserWriteRepository) HasAccounts(userID string) (bool, error) {
+	// Simplified: account ownership is coordinated via events.
+	return false, nil
+}
+
+// requireRowAffected maps an UPDATE that matched nothing to "user not found".
+func requireRowAffected(result sql.Result) error {
 	rows, err := result.RowsAffected()
 	if err != nil {
 		return fmt.Errorf("failed to check rows affected: %w", err)
@@ -117,9 +234,15 @@ func (r *UserWriteRepository) Delete(id string) error {
 	return nil
 }
 
-func (r *UserWriteRepository) HasAccounts(userID string) (bool, error) {
-	// Simplified: account ownership is coordinated via events.
-	return false, nil
+// commitWithOutbox queues the outbox events on tx and commits it.
+func commitWithOutbox(tx *sql.Tx, outbox []events.OutboxEvent) error {
+	if err := events.WriteOutbox(tx, outbox...); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
 }
 
 func nullString(s string) sql.NullString {
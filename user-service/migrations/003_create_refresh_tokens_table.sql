@@ -0,0 +1,24 @@
+CREATE TABLE
+IF NOT EXISTS refresh_tokens
+(
+    id VARCHAR
+(50) PRIMARY KEY,
+    user_id VARCHAR
+(50) NOT NULL REFERENCES users
+(id),
+    family_id VARCHAR
+(50) NOT NULL,
+    token_hash CHAR
+(64) NOT NULL UNIQUE,
+    access_token_id VARCHAR
+(50) NOT NULL,
+    access_expires_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW
+(),
+    rotated_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens(user_id);
@@ -0,0 +1,26 @@
+CREATE TABLE
+IF NOT EXISTS user_mfa
+(
+    user_id VARCHAR
+(50) PRIMARY KEY REFERENCES users
+(id),
+    secret_ciphertext TEXT NOT NULL,
+    last_used_step BIGINT,
+    confirmed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW
+()
+);
+
+CREATE TABLE
+IF NOT EXISTS mfa_recovery_codes
+(
+    id BIGSERIAL PRIMARY KEY,
+    user_id VARCHAR
+(50) NOT NULL REFERENCES users
+(id),
+    code_hash CHAR
+(64) NOT NULL,
+    used_at TIMESTAMP,
+    UNIQUE
+(user_id, code_hash)
+);
@@ -0,0 +1,6 @@
+-- locked_until is set by auth-service after repeated failed logins; the
+-- account cannot log in until then.
+ALTER TABLE users ADD COLUMN
+IF NOT EXISTS locked_until TIMESTAMP;
+
+CREATE INDEX idx_users_locked_until ON users(locked_until) WHERE locked_until IS NOT NULL;
@@ -0,0 +1,17 @@
+CREATE TABLE
+IF NOT EXISTS password_reset_tokens
+(
+    id VARCHAR
+(50) PRIMARY KEY,
+    user_id VARCHAR
+(50) NOT NULL REFERENCES users
+(id),
+    token_hash CHAR
+(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW
+(),
+    used_at TIMESTAMP
+);
+
+CREATE INDEX idx_password_reset_tokens_user_id ON password_reset_tokens(user_id);
@@ -0,0 +1,21 @@
+-- Users registered from now on start as pending_verification; existing
+-- users are treated as verified.
+ALTER TABLE users ADD COLUMN
+IF NOT EXISTS status VARCHAR
+(30) NOT NULL DEFAULT 'active';
+ALTER TABLE users ADD COLUMN
+IF NOT EXISTS verified_at TIMESTAMP;
+
+UPDATE users SET verified_at = created_at WHERE verified_at IS NULL;
+
+-- Tell account-service about the users verified above, as if each had just
+-- followed their link.
+INSERT INTO outbox
+    (stream, event_type, payload)
+SELECT 'user.events', 'user.verified', jsonb_build_object(
+    'type', 'user.verified',
+    'timestamp', to_char(NOW() AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"'),
+    'data', jsonb_build_object('userId', id, 'email', email)
+)
+FROM users
+WHERE deleted_at IS NULL;
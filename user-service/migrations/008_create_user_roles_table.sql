@@ -0,0 +1,18 @@
+-- Staff roles. Users without a row are customers; auth-service copies a
+-- user's roles and the scopes they grant into each access token, so a
+-- change takes effect at the user's next login or token refresh.
+CREATE TABLE
+IF NOT EXISTS user_roles
+(
+    user_id VARCHAR
+(50) NOT NULL REFERENCES users
+(id),
+    role VARCHAR
+(20) NOT NULL CHECK
+(role IN
+('support', 'admin')),
+    granted_at TIMESTAMP NOT NULL DEFAULT NOW
+(),
+    PRIMARY KEY
+(user_id, role)
+);
@@ -0,0 +1,19 @@
+-- API clients authenticate to auth-service with the client_credentials
+-- grant. Only the SHA-256 of the secret is kept. scopes is space-separated,
+-- as in the tokens issued to the client.
+CREATE TABLE
+IF NOT EXISTS api_clients
+(
+    id VARCHAR
+(50) PRIMARY KEY,
+    name VARCHAR
+(100) NOT NULL,
+    secret_hash CHAR
+(64) NOT NULL,
+    scopes TEXT NOT NULL,
+    created_by VARCHAR
+(50) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW
+(),
+    revoked_at TIMESTAMP
+);
@@ -0,0 +1,4 @@
+-- trace_context holds the W3C trace context of the request that queued the
+-- event, so the relay's publish joins that request's trace.
+ALTER TABLE outbox ADD COLUMN
+IF NOT EXISTS trace_context JSONB;